@@ -0,0 +1,287 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Separator separates the path of the archive and the name of the member.
+const Separator = "//"
+
+// Split splits the path into the path of the archive and the name of the member.
+func Split(p string) (string, string, bool) {
+	archive, name, ok := strings.Cut(p, Separator)
+	if !ok || archive == "" {
+		return p, "", false
+	}
+	return archive, name, true
+}
+
+// Join joins the path of the archive and the name of the member.
+func Join(archive, name string) string {
+	return archive + Separator + name
+}
+
+// Entry represents a member of the archive.
+type Entry struct {
+	Name string
+	fs.FileInfo
+	zip    *zip.File
+	offset int64
+	header *tar.Header
+}
+
+// Archive represents a zip or tar archive.
+type Archive struct {
+	r       io.ReaderAt
+	zip     *zip.Reader
+	entries []*Entry
+}
+
+// Open reads the entries of the archive.
+func Open(r io.ReaderAt, size int64) (*Archive, error) {
+	a := &Archive{r: r}
+	if zr, err := zip.NewReader(r, size); err == nil {
+		a.zip = zr
+		for _, f := range zr.File {
+			a.entries = append(a.entries, &Entry{Name: f.Name, FileInfo: f.FileInfo(), zip: f})
+		}
+		return a, nil
+	}
+	entries, err := readTar(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, errors.New("not a zip or tar archive")
+	}
+	a.entries = entries
+	return a, nil
+}
+
+// OpenStream reads the entries of the tar archive from the stream, like the
+// decompressed stream of a compressed archive. The members cannot be read.
+func OpenStream(r io.Reader) (*Archive, error) {
+	entries, err := readTar(r)
+	if err != nil {
+		return nil, errors.New("not a tar archive")
+	}
+	return &Archive{entries: entries}, nil
+}
+
+func readTar(r io.Reader) ([]*Entry, error) {
+	var entries []*Entry
+	cr := &countReader{r: r}
+	tr := tar.NewReader(cr)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if err == io.EOF && entries != nil {
+				return entries, nil
+			}
+			return nil, err
+		}
+		entries = append(entries, &Entry{
+			Name: hdr.Name, FileInfo: hdr.FileInfo(), offset: cr.n, header: hdr,
+		})
+	}
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// Entries returns the entries of the archive.
+func (a *Archive) Entries() []*Entry {
+	return a.entries
+}
+
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func (a *Archive) lookup(name string) *Entry {
+	name = cleanName(name)
+	for _, e := range a.entries {
+		if cleanName(e.Name) == name && !e.IsDir() {
+			return e
+		}
+	}
+	return nil
+}
+
+// Open returns the reader of the member. The reader is an [io.SectionReader]
+// if the member is stored without compression.
+func (a *Archive) Open(name string) (io.Reader, error) {
+	e := a.lookup(name)
+	if e == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if a.r == nil {
+		return nil, errors.New("cannot read the members of the stream")
+	}
+	if e.zip != nil {
+		if e.zip.Method != zip.Store {
+			return e.zip.Open()
+		}
+		offset, err := e.zip.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		return io.NewSectionReader(a.r, offset, int64(e.zip.UncompressedSize64)), nil
+	}
+	if e.header.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("%s: not a regular file", name)
+	}
+	return io.NewSectionReader(a.r, e.offset, e.header.Size), nil
+}
+
+// ReadDir returns the entries in the directory of the archive. The entries
+// of the intermediate directories are included even if they are omitted in
+// the archive.
+func (a *Archive) ReadDir(dir string) []fs.FileInfo {
+	dir = cleanName(dir)
+	var fis []fs.FileInfo
+	var dirs []string
+	for _, e := range a.entries {
+		name := cleanName(e.Name)
+		if dir != "" {
+			if !strings.HasPrefix(name, dir+"/") {
+				continue
+			}
+			name = name[len(dir)+1:]
+		}
+		if name == "" || name == "." {
+			continue
+		}
+		if base, _, ok := strings.Cut(name, "/"); ok || e.IsDir() {
+			if !slices.Contains(dirs, base) {
+				dirs = append(dirs, base)
+				fis = append(fis, dirInfo{base, e.ModTime()})
+			}
+			continue
+		}
+		fis = append(fis, e.FileInfo)
+	}
+	return fis
+}
+
+type dirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (fi dirInfo) Name() string       { return fi.name }
+func (fi dirInfo) Size() int64        { return 0 }
+func (fi dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o755 }
+func (fi dirInfo) ModTime() time.Time { return fi.modTime }
+func (fi dirInfo) IsDir() bool        { return true }
+func (fi dirInfo) Sys() any           { return nil }
+
+// List returns the listing of the entries.
+func (a *Archive) List() string {
+	var sb strings.Builder
+	for _, e := range a.entries {
+		fmt.Fprintf(&sb, "%s %10d %s %s\n", e.Mode(), e.Size(),
+			e.ModTime().Format("2006-01-02 15:04"), e.Name)
+	}
+	return sb.String()
+}
+
+// Write writes the archive to the writer, replacing the contents of the
+// member. The member is added to the archive if it does not exist.
+func (a *Archive) Write(w io.Writer, name string, contents []byte) error {
+	if a.r == nil {
+		return errors.New("cannot write the archive of the stream")
+	}
+	if a.zip != nil {
+		return a.writeZip(w, name, contents)
+	}
+	return a.writeTar(w, name, contents)
+}
+
+func (a *Archive) writeZip(w io.Writer, name string, contents []byte) error {
+	zw := zip.NewWriter(w)
+	target := a.lookup(name)
+	write := func(hdr zip.FileHeader) error {
+		hdr.Modified = time.Now()
+		// The sizes and the checksum are calculated by the writer.
+		hdr.CRC32, hdr.CompressedSize64, hdr.UncompressedSize64 = 0, 0, 0
+		fw, err := zw.CreateHeader(&hdr)
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(contents)
+		return err
+	}
+	for _, e := range a.entries {
+		var err error
+		if e == target {
+			err = write(e.zip.FileHeader)
+		} else {
+			err = zw.Copy(e.zip)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if target == nil {
+		if err := write(zip.FileHeader{Name: cleanName(name), Method: zip.Deflate}); err != nil {
+			return err
+		}
+	}
+	if err := zw.SetComment(a.zip.Comment); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (a *Archive) writeTar(w io.Writer, name string, contents []byte) error {
+	tw := tar.NewWriter(w)
+	target := a.lookup(name)
+	write := func(hdr tar.Header) error {
+		hdr.Size, hdr.ModTime = int64(len(contents)), time.Now()
+		if err := tw.WriteHeader(&hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(contents)
+		return err
+	}
+	for _, e := range a.entries {
+		if e == target {
+			if err := write(*e.header); err != nil {
+				return err
+			}
+			continue
+		}
+		hdr := *e.header
+		if err := tw.WriteHeader(&hdr); err != nil {
+			return err
+		}
+		if hdr.Size > 0 {
+			if _, err := io.Copy(tw, io.NewSectionReader(a.r, e.offset, hdr.Size)); err != nil {
+				return err
+			}
+		}
+	}
+	if target == nil {
+		if err := write(tar.Header{
+			Typeflag: tar.TypeReg, Name: cleanName(name), Mode: 0o644,
+		}); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
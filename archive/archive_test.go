@@ -0,0 +1,202 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func createZip(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name, contents string
+		method         uint16
+	}{
+		{"README.md", "Hello, world!", zip.Store},
+		{"src/main.go", "package main\n", zip.Deflate},
+		{"src/lib/lib.go", "package lib\n", zip.Deflate},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: f.method})
+		if err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		if _, err = w.Write([]byte(f.contents)); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func createTar(t *testing.T) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "etc/", Mode: 0o755}); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	for _, f := range []struct{ name, contents string }{
+		{"etc/hosts", "127.0.0.1 localhost\n"},
+		{"etc/passwd", "root:x:0:0:root:/root:/bin/sh\n"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg, Name: f.name, Mode: 0o644, Size: int64(len(f.contents)),
+		}); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		if _, err := tw.Write([]byte(f.contents)); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func readMember(t *testing.T, a *Archive, name string) string {
+	r, err := a.Open(name)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	return string(bs)
+}
+
+func TestSplit(t *testing.T) {
+	testCases := []struct {
+		path, archive, name string
+		ok                  bool
+	}{
+		{"/tmp/a.zip//src/main.go", "/tmp/a.zip", "src/main.go", true},
+		{"/tmp/a.tar//", "/tmp/a.tar", "", true},
+		{"/tmp/a.zip", "/tmp/a.zip", "", false},
+		{"//tmp/a.zip", "//tmp/a.zip", "", false},
+	}
+	for _, tc := range testCases {
+		archive, name, ok := Split(tc.path)
+		if archive != tc.archive || name != tc.name || ok != tc.ok {
+			t.Errorf("Split(%q) should be %q, %q, %t but got %q, %q, %t",
+				tc.path, tc.archive, tc.name, tc.ok, archive, name, ok)
+		}
+		if ok && Join(archive, name) != tc.path {
+			t.Errorf("Join(%q, %q) should be %q", archive, name, tc.path)
+		}
+	}
+}
+
+func TestArchiveZip(t *testing.T) {
+	bs := createZip(t)
+	a, err := Open(bytes.NewReader(bs), int64(len(bs)))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if r, err := a.Open("README.md"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	} else if _, ok := r.(*io.SectionReader); !ok {
+		t.Errorf("stored member should be read by *io.SectionReader but got %T", r)
+	}
+	if expected := "package main\n"; readMember(t, a, "/src/main.go") != expected {
+		t.Errorf("member should be %q", expected)
+	}
+	if _, err := a.Open("src/none.go"); err == nil {
+		t.Errorf("err should not be nil")
+	}
+	var names []string
+	for _, fi := range a.ReadDir("src") {
+		names = append(names, fi.Name())
+	}
+	if expected := "main.go,lib"; strings.Join(names, ",") != expected {
+		t.Errorf("ReadDir should return %q but got %q", expected, strings.Join(names, ","))
+	}
+
+	var buf bytes.Buffer
+	if err = a.Write(&buf, "src/main.go", []byte("package main\n\nfunc main() {}\n")); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if a, err = Open(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		// The checksum is verified on reading to the end.
+		if _, err = io.ReadAll(r); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+	}
+	if expected := "package main\n\nfunc main() {}\n"; readMember(t, a, "src/main.go") != expected {
+		t.Errorf("member should be %q", expected)
+	}
+	if expected := "Hello, world!"; readMember(t, a, "README.md") != expected {
+		t.Errorf("member should be %q", expected)
+	}
+}
+
+func TestArchiveTar(t *testing.T) {
+	bs := createTar(t)
+	a, err := Open(bytes.NewReader(bs), int64(len(bs)))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if expected := "root:x:0:0:root:/root:/bin/sh\n"; readMember(t, a, "etc/passwd") != expected {
+		t.Errorf("member should be %q", expected)
+	}
+	if _, err := a.Open("etc"); err == nil {
+		t.Errorf("err should not be nil")
+	}
+
+	var buf bytes.Buffer
+	if err = a.Write(&buf, "etc/passwd", []byte("root:x:0:0::/root:/bin/bash\nbed:x:1000:1000::/home/bed:/bin/sh\n")); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if a, err = Open(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if expected := "root:x:0:0::/root:/bin/bash\nbed:x:1000:1000::/home/bed:/bin/sh\n"; readMember(t, a, "etc/passwd") != expected {
+		t.Errorf("member should be %q", expected)
+	}
+	if expected := "127.0.0.1 localhost\n"; readMember(t, a, "etc/hosts") != expected {
+		t.Errorf("member should be %q", expected)
+	}
+	if expected := 3; len(a.Entries()) != expected {
+		t.Errorf("archive should have %d entries but got %d", expected, len(a.Entries()))
+	}
+}
+
+func TestArchiveOpenStream(t *testing.T) {
+	a, err := OpenStream(bytes.NewReader(createTar(t)))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if fis := a.ReadDir("etc"); len(fis) != 2 || fis[0].Name() != "hosts" || fis[1].Name() != "passwd" {
+		t.Errorf("entries should be hosts and passwd but got %v", fis)
+	}
+	if _, err := a.Open("etc/hosts"); err == nil {
+		t.Errorf("err should not be nil")
+	}
+	if _, err := OpenStream(bytes.NewReader([]byte("Hello, world!"))); err == nil {
+		t.Errorf("err should not be nil")
+	}
+}
+
+func TestArchiveOpenInvalid(t *testing.T) {
+	bs := []byte("Hello, world!")
+	if _, err := Open(bytes.NewReader(bs), int64(len(bs))); err == nil {
+		t.Errorf("err should not be nil")
+	}
+}
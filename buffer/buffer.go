@@ -1,6 +1,7 @@
 package buffer
 
 import (
+	"cmp"
 	"errors"
 	"io"
 	"math"
@@ -9,8 +10,12 @@ import (
 )
 
 // Buffer represents a buffer.
+// The pieces of the buffer are stored in a persistent balanced tree,
+// so that the edits take logarithmic time and the clones share the tree.
+// The tail piece reads the rest of its reader, and is not in the tree.
 type Buffer struct {
-	rrs    []readerRange
+	root   *node
+	tail   piece
 	index  int64
 	mu     *sync.Mutex
 	bytes  []byte
@@ -32,7 +37,7 @@ type readerRange struct {
 // NewBuffer creates a new buffer.
 func NewBuffer(r readAtSeeker) *Buffer {
 	return &Buffer{
-		rrs:   []readerRange{{r: r, min: 0, max: math.MaxInt64, diff: 0}},
+		tail:  piece{r: r, offset: 0},
 		index: 0,
 		mu:    new(sync.Mutex),
 	}
@@ -47,22 +52,20 @@ func (b *Buffer) Read(p []byte) (int, error) {
 
 func (b *Buffer) read(p []byte) (i int, err error) {
 	index := b.index
-	for _, rr := range b.rrs {
+	b.walk(b.index, func(rr readerRange) bool {
 		if b.index < rr.min {
-			break
-		}
-		if b.index >= rr.max {
-			continue
+			return false
 		}
 		m := int(min(int64(len(p)-i), rr.max-b.index))
 		var k int
 		if k, err = rr.r.ReadAt(p[i:i+m], b.index+rr.diff); err != nil && k == 0 {
-			break
+			return false
 		}
 		err = nil
 		b.index += int64(m)
 		i += k
-	}
+		return true
+	})
 	if len(b.bytes) > 0 {
 		j, k := max(b.offset-index, 0), max(index-b.offset, 0)
 		if j < int64(len(p)) && k < int64(len(b.bytes)) {
@@ -74,6 +77,28 @@ func (b *Buffer) read(p []byte) (i int, err error) {
 	return
 }
 
+// walk calls the function for the reader ranges from the specific position.
+// The last range is the tail, which has the maximum value of int64 as max.
+func (b *Buffer) walk(from int64, f func(readerRange) bool) {
+	if !b.root.walk(0, from, func(pos int64, p piece) bool {
+		return f(readerRange{p.r, pos, pos + p.length, p.offset - pos})
+	}) {
+		return
+	}
+	l := b.root.len()
+	f(readerRange{b.tail.r, l, math.MaxInt64, b.tail.offset - l})
+}
+
+// ranges returns all the reader ranges of the buffer.
+func (b *Buffer) ranges() []readerRange {
+	var rrs []readerRange
+	b.walk(0, func(rr readerRange) bool {
+		rrs = append(rrs, rr)
+		return true
+	})
+	return rrs
+}
+
 // Seek sets the offset.
 func (b *Buffer) Seek(offset int64, whence int) (int64, error) {
 	b.mu.Lock()
@@ -113,12 +138,11 @@ func (b *Buffer) Len() (int64, error) {
 }
 
 func (b *Buffer) len() (int64, error) {
-	rr := b.rrs[len(b.rrs)-1]
-	l, err := rr.r.Seek(0, io.SeekEnd)
+	l, err := b.tail.r.Seek(0, io.SeekEnd)
 	if err != nil {
 		return 0, err
 	}
-	return max(l-rr.diff, b.offset+int64(len(b.bytes))), nil
+	return max(b.root.len()+l-b.tail.offset, b.offset+int64(len(b.bytes))), nil
 }
 
 // ReadAt reads bytes at the specific offset.
@@ -135,18 +159,44 @@ func (b *Buffer) ReadAt(p []byte, offset int64) (int, error) {
 func (b *Buffer) EditedIndices() []int64 {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	eis := make([]int64, 0, len(b.rrs))
-	for _, rr := range b.rrs {
+	eis := []int64{}
+	b.walk(0, func(rr readerRange) bool {
 		switch rr.r.(type) {
 		case *bytesReader, constReader:
 			// constReader can be adjacent to another bytesReader or constReader.
 			if l := len(eis); l > 0 && eis[l-1] == rr.min {
 				eis[l-1] = rr.max
-				continue
+				return true
 			}
 			eis = append(eis, rr.min, rr.max)
 		}
+		return true
+	})
+	if len(b.bytes) > 0 {
+		eis = insertInterval(eis, b.offset, b.offset+int64(len(b.bytes)))
 	}
+	return eis
+}
+
+// ChangedIndices returns the indices of the regions which are not read from
+// the reader at the same offsets; the edited regions and the regions moved by
+// the insertions and the deletions. Writing these regions to the reader
+// updates the contents of the reader to the buffer.
+func (b *Buffer) ChangedIndices(r io.ReaderAt) []int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	eis := []int64{}
+	b.walk(0, func(rr readerRange) bool {
+		if rr.r == r && rr.diff == 0 {
+			return true
+		}
+		if l := len(eis); l > 0 && eis[l-1] == rr.min {
+			eis[l-1] = rr.max
+			return true
+		}
+		eis = append(eis, rr.min, rr.max)
+		return true
+	})
 	if len(b.bytes) > 0 {
 		eis = insertInterval(eis, b.offset, b.offset+int64(len(b.bytes)))
 	}
@@ -175,14 +225,13 @@ func insertInterval(xs []int64, start, end int64) []int64 {
 }
 
 // Clone the buffer.
+// The clone shares the tree of the pieces, which is never modified.
 func (b *Buffer) Clone() *Buffer {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	newBuf := new(Buffer)
-	newBuf.rrs = make([]readerRange, len(b.rrs))
-	for i, rr := range b.rrs {
-		newBuf.rrs[i] = readerRange{rr.r, rr.min, rr.max, rr.diff}
-	}
+	newBuf.root = b.root
+	newBuf.tail = b.tail
 	newBuf.index = b.index
 	newBuf.mu = new(sync.Mutex)
 	newBuf.bytes = slices.Clone(b.bytes)
@@ -195,22 +244,9 @@ func (b *Buffer) Copy(start, end int64) *Buffer {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.flush()
+	_, root := split(b.prefix(end), start)
 	newBuf := new(Buffer)
-	rrs := make([]readerRange, 0, len(b.rrs)+1)
-	index := start
-	for _, rr := range b.rrs {
-		if index < rr.min || index >= end {
-			break
-		}
-		if index >= rr.max {
-			continue
-		}
-		size := min(end-index, rr.max-index)
-		rrs = append(rrs, readerRange{rr.r, index - start, index - start + size, rr.diff + start})
-		index += size
-	}
-	newBuf.rrs = append(rrs, readerRange{newBytesReader(nil), index - start, math.MaxInt64, -index + start})
-	newBuf.cleanup()
+	newBuf.set(root, piece{r: newBytesReader(nil)})
 	newBuf.index = 0
 	newBuf.mu = new(sync.Mutex)
 	return newBuf
@@ -221,45 +257,10 @@ func (b *Buffer) Cut(start, end int64) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.flush()
-	rrs := make([]readerRange, 0, len(b.rrs)+1)
-	var index, max int64
-	for _, rr := range b.rrs {
-		if start >= rr.max {
-			rrs = append(rrs, rr)
-			index = rr.max
-			continue
-		}
-		if end <= rr.min {
-			if rr.max == math.MaxInt64 {
-				max = math.MaxInt64
-			} else {
-				max = rr.max - rr.min + index
-			}
-			rrs = append(rrs, readerRange{rr.r, index, max, rr.diff - index + rr.min})
-			index = max
-			continue
-		}
-		if start >= rr.min {
-			max = start
-			rrs = append(rrs, readerRange{rr.r, index, max, rr.diff})
-			index = max
-		}
-		if end < rr.max {
-			if rr.max == math.MaxInt64 {
-				max = math.MaxInt64
-			} else {
-				max = rr.max - end + index
-			}
-			rrs = append(rrs, readerRange{rr.r, index, max, rr.diff + end - index})
-			index = max
-		}
+	if start < end {
+		b.replace(start, end, nil)
 	}
-	if index != math.MaxInt64 {
-		rrs = append(rrs, readerRange{newBytesReader(nil), index, math.MaxInt64, -index})
-	}
-	b.rrs = rrs
 	b.index = 0
-	b.cleanup()
 }
 
 // Paste a buffer into a buffer.
@@ -269,45 +270,11 @@ func (b *Buffer) Paste(offset int64, c *Buffer) {
 	defer b.mu.Unlock()
 	defer c.mu.Unlock()
 	b.flush()
-	rrs := make([]readerRange, 0, len(b.rrs)+len(c.rrs)+1)
-	var index, max int64
-	for _, rr := range b.rrs {
-		if offset >= rr.max {
-			rrs = append(rrs, rr)
-			continue
-		}
-		if offset < rr.min {
-			if rr.max == math.MaxInt64 {
-				max = math.MaxInt64
-			} else {
-				max = rr.max - rr.min + index
-			}
-			rrs = append(rrs, readerRange{rr.r, index, max, rr.diff - index + rr.min})
-			index = max
-			continue
-		}
-		rrs = append(rrs, readerRange{rr.r, rr.min, offset, rr.diff})
-		index = offset
-		for _, rr := range c.rrs {
-			if rr.max == math.MaxInt64 {
-				l, _ := rr.r.Seek(0, io.SeekEnd)
-				max = l + index
-			} else {
-				max = rr.max - rr.min + index
-			}
-			rrs = append(rrs, readerRange{rr.r, index, max, rr.diff - index + rr.min})
-			index = max
-		}
-		if rr.max == math.MaxInt64 {
-			max = math.MaxInt64
-		} else {
-			max = rr.max - offset + index
-		}
-		rrs = append(rrs, readerRange{rr.r, index, max, rr.diff - index + offset})
-		index = max
+	root := c.root
+	if l, _ := c.tail.r.Seek(0, io.SeekEnd); l > c.tail.offset {
+		root = concat(root, newLeaf(piece{c.tail.r, c.tail.offset, l - c.tail.offset}))
 	}
-	b.rrs = rrs
-	b.cleanup()
+	b.replace(offset, offset, root)
 }
 
 // Insert inserts a byte at the specific position.
@@ -315,37 +282,7 @@ func (b *Buffer) Insert(offset int64, c byte) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.flush()
-	for i, rr := range b.rrs {
-		if offset > rr.max {
-			continue
-		}
-		var r *bytesReader
-		var ok bool
-		if rr.max != math.MaxInt64 {
-			if r, ok = rr.r.(*bytesReader); ok {
-				r = r.clone()
-				r.insert(offset+rr.diff, c)
-				b.rrs[i], i = readerRange{r, rr.min, rr.max + 1, rr.diff}, i+1
-			}
-		}
-		if !ok {
-			b.rrs = append(b.rrs, readerRange{}, readerRange{})
-			copy(b.rrs[i+2:], b.rrs[i:])
-			b.rrs[i], i = readerRange{rr.r, rr.min, offset, rr.diff}, i+1
-			b.rrs[i], i = readerRange{newBytesReader([]byte{c}), offset, offset + 1, -offset}, i+1
-			b.rrs[i].min = offset
-		}
-		for ; i < len(b.rrs); i++ {
-			b.rrs[i].min++
-			if b.rrs[i].max != math.MaxInt64 {
-				b.rrs[i].max++
-			}
-			b.rrs[i].diff--
-		}
-		b.cleanup()
-		return
-	}
-	panic("buffer.Buffer.Insert: unreachable")
+	b.replace(offset, offset, newLeaf(piece{newBytesReader([]byte{c}), 0, 1}))
 }
 
 // Replace replaces a byte at the specific position.
@@ -377,24 +314,7 @@ func (b *Buffer) UndoReplace(offset int64) {
 func (b *Buffer) ReplaceIn(start, end int64, c byte) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	rrs := make([]readerRange, 0, len(b.rrs)+1)
-	for _, rr := range b.rrs {
-		if rr.max <= start || end <= rr.min {
-			rrs = append(rrs, rr)
-			continue
-		}
-		if start > rr.min {
-			rrs = append(rrs, readerRange{rr.r, rr.min, start, rr.diff})
-		}
-		if start >= rr.min {
-			rrs = append(rrs, readerRange{constReader(c), start, end, -start})
-		}
-		if end < rr.max {
-			rrs = append(rrs, readerRange{rr.r, end, rr.max, rr.diff})
-		}
-	}
-	b.rrs = rrs
-	b.cleanup()
+	b.replace(start, end, newLeaf(piece{constReader(c), 0, end - start}))
 }
 
 // Flush temporary bytes.
@@ -408,34 +328,17 @@ func (b *Buffer) flush() {
 	if len(b.bytes) == 0 {
 		return
 	}
-	rrs := make([]readerRange, 0, len(b.rrs)+1)
 	end := b.offset + int64(len(b.bytes))
-	for _, rr := range b.rrs {
-		if b.offset >= rr.max || end <= rr.min {
-			rrs = append(rrs, rr)
-			continue
-		}
-		if b.offset >= rr.min {
-			if rr.min < b.offset {
-				rrs = append(rrs, readerRange{rr.r, rr.min, b.offset, rr.diff})
-			}
-			rrs = append(rrs, readerRange{newBytesReader(b.bytes), b.offset, end, -b.offset})
-		}
-		if rr.max == math.MaxInt64 {
-			l, _ := rr.r.Seek(0, io.SeekEnd)
-			if l-rr.diff <= end {
-				rrs = append(rrs, readerRange{newBytesReader(nil), end, math.MaxInt64, -end})
-				continue
-			}
-		}
-		if end < rr.max {
-			rrs = append(rrs, readerRange{rr.r, end, rr.max, rr.diff})
+	root, tail := b.suffix(end)
+	if l := b.root.len(); end > l {
+		if n, _ := b.tail.r.Seek(0, io.SeekEnd); l+n-b.tail.offset <= end {
+			tail = piece{r: newBytesReader(nil)}
 		}
 	}
-	b.rrs = rrs
+	root = concat(newLeaf(piece{newBytesReader(b.bytes), 0, int64(len(b.bytes))}), root)
+	b.set(concat(b.prefix(b.offset), root), tail)
 	b.offset = 0
 	b.bytes = nil
-	b.cleanup()
 }
 
 // Delete deletes a byte at the specific position.
@@ -443,60 +346,187 @@ func (b *Buffer) Delete(offset int64) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.flush()
-	for i, rr := range b.rrs {
-		if offset >= rr.max {
-			continue
+	b.replace(offset, offset+1, nil)
+}
+
+// replace the pieces in the range with the tree.
+func (b *Buffer) replace(start, end int64, n *node) {
+	if n.len() == 0 {
+		n = nil
+	}
+	root, tail := b.suffix(end)
+	b.set(concat(concat(b.prefix(start), n), root), tail)
+}
+
+// prefix returns the tree of the pieces before the position.
+func (b *Buffer) prefix(pos int64) *node {
+	if l := b.root.len(); pos > l {
+		return concat(b.root, newLeaf(piece{b.tail.r, b.tail.offset, pos - l}))
+	}
+	root, _ := split(b.root, pos)
+	return root
+}
+
+// suffix returns the tree of the pieces after the position, and the tail.
+func (b *Buffer) suffix(pos int64) (*node, piece) {
+	if l := b.root.len(); pos > l {
+		return nil, piece{r: b.tail.r, offset: b.tail.offset + pos - l}
+	}
+	_, root := split(b.root, pos)
+	return root, b.tail
+}
+
+// set the tree and the tail, and joins the last piece to the tail if possible.
+func (b *Buffer) set(root *node, tail piece) {
+	if root != nil {
+		if r, ok := tail.r.(*bytesReader); ok {
+			tail.length = int64(len(r.bs)) - tail.offset
 		}
-		if r, ok := rr.r.(*bytesReader); ok {
-			r = r.clone()
-			r.delete(offset + rr.diff)
-			b.rrs[i] = readerRange{r, rr.min, rr.max - 1, rr.diff}
-		} else {
-			b.rrs = append(b.rrs, readerRange{})
-			copy(b.rrs[i+1:], b.rrs[i:])
-			b.rrs[i] = readerRange{rr.r, rr.min, offset, rr.diff}
-			b.rrs[i+1] = readerRange{rr.r, offset + 1, rr.max, rr.diff}
+		p := root.last()
+		if q, ok := joinPieces(p, tail); ok {
+			root, _ = split(root, root.length-p.length)
+			tail = piece{r: q.r, offset: q.offset}
 		}
-		for i++; i < len(b.rrs); i++ {
-			b.rrs[i].min--
-			if b.rrs[i].max != math.MaxInt64 {
-				b.rrs[i].max--
+	}
+	b.root, b.tail = root, tail
+}
+
+// Segment represents a part of a buffer relative to a base buffer.
+// The segment refers to the base buffer if Bytes is nil.
+type Segment struct {
+	Offset int64  `json:"offset,omitempty"`
+	Length int64  `json:"length"`
+	Bytes  []byte `json:"bytes,omitempty"`
+}
+
+// Segments returns the segments of the buffer relative to the base buffer.
+// The buffer and the base buffer should share the same original reader.
+func (b *Buffer) Segments(base *Buffer) ([]Segment, error) {
+	b, base = b.Clone(), base.Clone()
+	b.flush()
+	base.flush()
+	l, err := b.len()
+	if err != nil {
+		return nil, err
+	}
+	baseLen, err := base.len()
+	if err != nil {
+		return nil, err
+	}
+	bases := baseRanges(base.ranges(), baseLen)
+	var segments []Segment
+	for _, rr := range b.ranges() {
+		index, end := rr.min, min(rr.max, l)
+		brs := bases[rr.r]
+		for index < end {
+			x := index + rr.diff
+			i, _ := slices.BinarySearchFunc(brs, x, func(br baseRange, x int64) int {
+				return cmp.Compare(br.to, x+1)
+			})
+			if i < len(brs) && brs[i].from <= x {
+				size := min(end-index, brs[i].to-x)
+				segments = appendSegment(segments, Segment{Offset: x - brs[i].diff, Length: size})
+				index += size
+				continue
+			}
+			next := end
+			if i < len(brs) {
+				next = min(next, brs[i].from-rr.diff)
 			}
-			b.rrs[i].diff++
+			bs := make([]byte, next-index)
+			if _, err := rr.r.ReadAt(bs, x); err != nil && err != io.EOF {
+				return nil, err
+			}
+			segments = appendSegment(segments, Segment{Length: next - index, Bytes: bs})
+			index = next
 		}
-		b.cleanup()
-		return
 	}
-	panic("buffer.Buffer.Delete: unreachable")
+	return segments, nil
+}
+
+// baseRange is a range of the offsets of a reader read by the base buffer,
+// where the offset of the base buffer is the reader offset minus diff.
+type baseRange struct {
+	from, to, diff int64
 }
 
-func (b *Buffer) cleanup() {
-	for i := 0; i < len(b.rrs); i++ {
-		if rr := b.rrs[i]; rr.min == rr.max {
-			b.rrs = slices.Delete(b.rrs, i, i+1)
+// baseRanges returns the disjoint ranges of the reader offsets read by the
+// base buffer for each reader, sorted by the offsets.
+func baseRanges(rrs []readerRange, length int64) map[readAtSeeker][]baseRange {
+	bases := make(map[readAtSeeker][]baseRange)
+	for _, rr := range rrs {
+		if from, to := rr.min+rr.diff, min(rr.max, length)+rr.diff; from < to {
+			bases[rr.r] = append(bases[rr.r], baseRange{from, to, rr.diff})
 		}
 	}
-	for i := len(b.rrs) - 1; i > 0; i-- {
-		rr1, rr2 := b.rrs[i-1], b.rrs[i]
-		switch r1 := rr1.r.(type) {
-		case constReader:
-			if r1 == rr2.r {
-				b.rrs[i-1].max = rr2.max
-				b.rrs = slices.Delete(b.rrs, i, i+1)
-			}
-		case *bytesReader:
-			if r2, ok := rr2.r.(*bytesReader); ok {
-				bs := make([]byte, int(rr1.max-rr1.min)+len(r2.bs)-int(rr2.min+rr2.diff))
-				copy(bs, r1.bs[rr1.min+rr1.diff:rr1.max+rr1.diff])
-				copy(bs[rr1.max-rr1.min:], r2.bs[rr2.min+rr2.diff:])
-				b.rrs[i-1] = readerRange{newBytesReader(bs), rr1.min, rr2.max, -rr1.min}
-				b.rrs = slices.Delete(b.rrs, i, i+1)
-			}
-		default:
-			if r1 == rr2.r && rr1.diff == rr2.diff && rr1.max == rr2.min {
-				b.rrs[i-1].max = rr2.max
-				b.rrs = slices.Delete(b.rrs, i, i+1)
+	for r, brs := range bases {
+		slices.SortStableFunc(brs, func(x, y baseRange) int {
+			return cmp.Compare(x.from, y.from)
+		})
+		var covered int64
+		disjoint := brs[:0]
+		for _, br := range brs {
+			if covered < br.to {
+				br.from, covered = max(br.from, covered), br.to
+				disjoint = append(disjoint, br)
 			}
 		}
+		bases[r] = disjoint
+	}
+	return bases
+}
+
+func appendSegment(segments []Segment, s Segment) []Segment {
+	if len(segments) > 0 {
+		t := &segments[len(segments)-1]
+		if t.Bytes == nil && s.Bytes == nil && t.Offset+t.Length == s.Offset {
+			t.Length += s.Length
+			return segments
+		} else if t.Bytes != nil && s.Bytes != nil {
+			t.Bytes = append(t.Bytes, s.Bytes...)
+			t.Length += s.Length
+			return segments
+		}
+	}
+	return append(segments, s)
+}
+
+// NewBufferFromSegments creates a new buffer from the segments
+// relative to the base reader.
+func NewBufferFromSegments(r readAtSeeker, segments []Segment) *Buffer {
+	var root *node
+	for _, s := range segments {
+		if s.Length <= 0 {
+			continue
+		}
+		if s.Bytes == nil {
+			root = concat(root, newLeaf(piece{r, s.Offset, s.Length}))
+		} else {
+			root = concat(root, newLeaf(piece{newBytesReader(s.Bytes), 0, s.Length}))
+		}
+	}
+	b := &Buffer{mu: new(sync.Mutex)}
+	b.set(root, piece{r: newBytesReader(nil)})
+	return b
+}
+
+// Diff returns the range where the buffer differs from another buffer;
+// the bytes in [start, end) of the buffer differ from the bytes in
+// [start, otherEnd) of the other buffer. The range is computed by comparing
+// the pieces and skipping the shared subtrees, so it may contain equal bytes.
+func (b *Buffer) Diff(c *Buffer) (start, end, otherEnd int64, err error) {
+	b, c = b.Clone(), c.Clone()
+	b.flush()
+	c.flush()
+	if end, err = b.len(); err != nil {
+		return
+	}
+	if otherEnd, err = c.len(); err != nil {
+		return
 	}
+	p, q := b.tail, c.tail
+	p.length, q.length = end-b.root.len(), otherEnd-c.root.len()
+	start = commonLength(b.root, c.root, p, q, min(end, otherEnd), false)
+	suffix := commonLength(b.root, c.root, p, q, min(end, otherEnd)-start, true)
+	return start, end - suffix, otherEnd - suffix, nil
 }
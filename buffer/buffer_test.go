@@ -1,8 +1,10 @@
 package buffer
 
 import (
+	"bytes"
 	"io"
 	"math"
+	"math/rand/v2"
 	"reflect"
 	"slices"
 	"strings"
@@ -126,17 +128,18 @@ func TestBufferClone(t *testing.T) {
 	b1 := b0.Clone()
 
 	bufferEqual := func(b0 *Buffer, b1 *Buffer) bool {
-		if b0.index != b1.index || len(b0.rrs) != len(b1.rrs) {
+		rrs0, rrs1 := b0.ranges(), b1.ranges()
+		if b0.index != b1.index || len(rrs0) != len(rrs1) {
 			return false
 		}
-		for i := range len(b0.rrs) {
-			if b0.rrs[i].min != b1.rrs[i].min || b0.rrs[i].max != b1.rrs[i].max ||
-				b0.rrs[i].diff != b1.rrs[i].diff {
+		for i := range len(rrs0) {
+			if rrs0[i].min != rrs1[i].min || rrs0[i].max != rrs1[i].max ||
+				rrs0[i].diff != rrs1[i].diff {
 				return false
 			}
-			switch r0 := b0.rrs[i].r.(type) {
+			switch r0 := rrs0[i].r.(type) {
 			case *bytesReader:
-				switch r1 := b1.rrs[i].r.(type) {
+				switch r1 := rrs1[i].r.(type) {
 				case *bytesReader:
 					if !reflect.DeepEqual(r0.bs, r1.bs) || r0.index != r1.index {
 						t.Logf("buffer differs: %+v, %+v", r0, r1)
@@ -147,7 +150,7 @@ func TestBufferClone(t *testing.T) {
 					return false
 				}
 			case *strings.Reader:
-				switch r1 := b1.rrs[i].r.(type) {
+				switch r1 := rrs1[i].r.(type) {
 				case *strings.Reader:
 					if r0 != r1 {
 						t.Logf("buffer differs: %+v, %+v", r0, r1)
@@ -158,7 +161,7 @@ func TestBufferClone(t *testing.T) {
 					return false
 				}
 			default:
-				t.Logf("buffer differs: %+v, %+v", b0.rrs[i].r, b1.rrs[i].r)
+				t.Logf("buffer differs: %+v, %+v", rrs0[i].r, rrs1[i].r)
 				return false
 			}
 		}
@@ -382,8 +385,8 @@ func TestBufferInsert(t *testing.T) {
 		t.Errorf("edited indices should be %v but got: %v", expected, eis)
 	}
 
-	if len(b.rrs) != 8 {
-		t.Errorf("len(b.rrs) should be 8 but got: %d", len(b.rrs))
+	if len(b.ranges()) != 8 {
+		t.Errorf("len(b.ranges()) should be 8 but got: %d", len(b.ranges()))
 	}
 }
 
@@ -444,8 +447,8 @@ func TestBufferReplace(t *testing.T) {
 		t.Errorf("edited indices should be %v but got: %v", expected, eis)
 	}
 
-	if len(b.rrs) != 3 {
-		t.Errorf("len(b.rrs) should be 3 but got: %d", len(b.rrs))
+	if len(b.ranges()) != 3 {
+		t.Errorf("len(b.ranges()) should be 3 but got: %d", len(b.ranges()))
 	}
 
 	{
@@ -569,8 +572,8 @@ func TestBufferReplaceIn(t *testing.T) {
 		t.Errorf("edited indices should be %v but got: %v", expected, eis)
 	}
 
-	if expected := 7; len(b.rrs) != expected {
-		t.Errorf("len(b.rrs) should be %d but got: %d", expected, len(b.rrs))
+	if expected := 7; len(b.ranges()) != expected {
+		t.Errorf("len(b.ranges()) should be %d but got: %d", expected, len(b.ranges()))
 	}
 
 	{
@@ -660,8 +663,41 @@ func TestBufferDelete(t *testing.T) {
 		t.Errorf("edited indices should be %v but got: %v", expected, eis)
 	}
 
-	if len(b.rrs) != 4 {
-		t.Errorf("len(b.rrs) should be 4 but got: %d", len(b.rrs))
+	if len(b.ranges()) != 4 {
+		t.Errorf("len(b.ranges()) should be 4 but got: %d", len(b.ranges()))
+	}
+}
+
+func TestBufferChangedIndices(t *testing.T) {
+	r := strings.NewReader("0123456789abcdef")
+	b := NewBuffer(r)
+	if eis := b.ChangedIndices(r); len(eis) != 0 {
+		t.Errorf("changed indices should be empty but got: %v", eis)
+	}
+
+	b.Replace(2, 'x')
+	b.Flush()
+	if expected := []int64{2, 3}; !reflect.DeepEqual(b.ChangedIndices(r), expected) {
+		t.Errorf("changed indices should be %v but got: %v", expected, b.ChangedIndices(r))
+	}
+
+	b.Delete(5)
+	b.Insert(10, 'y')
+	if expected := []int64{2, 3, 5, 11}; !reflect.DeepEqual(b.ChangedIndices(r), expected) {
+		t.Errorf("changed indices should be %v but got: %v", expected, b.ChangedIndices(r))
+	}
+	if expected := []int64{2, 3, 10, 11}; !reflect.DeepEqual(b.EditedIndices(), expected) {
+		t.Errorf("edited indices should be %v but got: %v", expected, b.EditedIndices())
+	}
+
+	b.Replace(0, 'z')
+	if expected := []int64{0, 1, 2, 3, 5, 11}; !reflect.DeepEqual(b.ChangedIndices(r), expected) {
+		t.Errorf("changed indices should be %v but got: %v", expected, b.ChangedIndices(r))
+	}
+
+	b.Delete(0)
+	if expected := []int64{0, math.MaxInt64}; !reflect.DeepEqual(b.ChangedIndices(r), expected) {
+		t.Errorf("changed indices should be %v but got: %v", expected, b.ChangedIndices(r))
 	}
 }
 
@@ -710,3 +746,213 @@ func TestInsertInterval(t *testing.T) {
 		}
 	}
 }
+
+func TestBufferSegments(t *testing.T) {
+	r := strings.NewReader("0123456789abcdef")
+	base := NewBuffer(r)
+	base.Replace(3, 0x41)
+	base.Delete(8)
+	b := base.Clone()
+	b.Insert(0, 0x42)
+	b.Replace(6, 0x43)
+	b.Delete(12)
+	b.Paste(14, base.Copy(2, 5))
+	segments, err := b.Segments(base)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	expected := []Segment{
+		{Length: 1, Bytes: []byte("B")},
+		{Offset: 0, Length: 5},
+		{Length: 1, Bytes: []byte("C")},
+		{Offset: 6, Length: 5},
+		{Offset: 12, Length: 2},
+		{Offset: 2, Length: 3},
+		{Offset: 14, Length: 1},
+	}
+	if !reflect.DeepEqual(segments, expected) {
+		t.Errorf("segments should be %v but got %v", expected, segments)
+	}
+	got := NewBufferFromSegments(strings.NewReader("012A45679abcdef"), segments)
+	p, q := make([]byte, 20), make([]byte, 20)
+	n, _ := b.ReadAt(p, 0)
+	m, _ := got.ReadAt(q, 0)
+	if string(p[:n]) != string(q[:m]) {
+		t.Errorf("buffer should be %q but got %q", string(p[:n]), string(q[:m]))
+	}
+
+	// The base buffer reads the same bytes of the reader twice.
+	base.Paste(2, base.Copy(4, 10))
+	segments, err = b.Segments(base)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	expected = []Segment{
+		{Length: 1, Bytes: []byte("B")},
+		{Offset: 0, Length: 2},
+		{Offset: 8, Length: 2},
+		{Offset: 2, Length: 1},
+		{Length: 1, Bytes: []byte("C")},
+		{Offset: 4, Length: 4},
+		{Offset: 16, Length: 1},
+		{Offset: 18, Length: 2},
+		{Offset: 8, Length: 2},
+		{Offset: 2, Length: 1},
+		{Offset: 20, Length: 1},
+	}
+	if !reflect.DeepEqual(segments, expected) {
+		t.Errorf("segments should be %v but got %v", expected, segments)
+	}
+	got = NewBufferFromSegments(strings.NewReader("0145679a2A45679abcdef"), segments)
+	n, _ = b.ReadAt(p, 0)
+	m, _ = got.ReadAt(q, 0)
+	if string(p[:n]) != string(q[:m]) {
+		t.Errorf("buffer should be %q but got %q", string(p[:n]), string(q[:m]))
+	}
+}
+
+func TestBufferDiff(t *testing.T) {
+	r := strings.NewReader("0123456789abcdef")
+	testCases := []struct {
+		name     string
+		edit     func(*Buffer)
+		expected [3]int64
+	}{
+		{"no change", func(*Buffer) {}, [3]int64{16, 16, 16}},
+		{"replace", func(b *Buffer) { b.Replace(3, 0x41) }, [3]int64{3, 4, 4}},
+		{"insert", func(b *Buffer) { b.Insert(5, 0x41); b.Insert(6, 0x42) }, [3]int64{5, 5, 7}},
+		{"delete", func(b *Buffer) { b.Delete(0) }, [3]int64{0, 1, 0}},
+		{"cut", func(b *Buffer) { b.Cut(4, 9) }, [3]int64{4, 9, 4}},
+		{"paste", func(b *Buffer) { b.Paste(16, b.Copy(1, 3)) }, [3]int64{16, 16, 18}},
+		{"replace in", func(b *Buffer) { b.ReplaceIn(2, 4, 0x41); b.ReplaceIn(10, 14, 0x41) }, [3]int64{2, 14, 14}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBuffer(r)
+			b.Replace(8, 0x42)
+			b.Flush()
+			c := b.Clone()
+			tc.edit(c)
+			start, end, otherEnd, err := b.Diff(c)
+			if err != nil {
+				t.Fatalf("err should be nil but got: %v", err)
+			}
+			if got := [3]int64{start, end, otherEnd}; got != tc.expected {
+				t.Errorf("Diff should return %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestBufferDiffRandom(t *testing.T) {
+	bs := make([]byte, 4096)
+	for i := range bs {
+		bs[i] = byte(i)
+	}
+	b := NewBuffer(bytes.NewReader(bs))
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for range 1000 {
+		c := b.Clone()
+		l, _ := c.Len()
+		baseLen := l
+		for range rnd.IntN(3) + 1 {
+			offset := rnd.Int64N(l)
+			switch rnd.IntN(4) {
+			case 0:
+				c.Replace(offset, byte(rnd.Uint32()))
+			case 1:
+				c.Insert(offset, byte(rnd.Uint32()))
+				l++
+			case 2:
+				c.Delete(offset)
+				l--
+			default:
+				from := rnd.Int64N(baseLen)
+				d := b.Copy(from, min(from+64, baseLen))
+				c.Paste(offset, d)
+				l += d.root.len()
+			}
+		}
+		start, end, otherEnd, err := b.Diff(c)
+		if err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		p, _ := io.ReadAll(io.NewSectionReader(b, 0, math.MaxInt32))
+		q, _ := io.ReadAll(io.NewSectionReader(c, 0, math.MaxInt32))
+		if start > end || start > otherEnd || end > int64(len(p)) || otherEnd > int64(len(q)) ||
+			!bytes.Equal(p[:start], q[:start]) || !bytes.Equal(p[end:], q[otherEnd:]) {
+			t.Fatalf("Diff returned an invalid range: %d, %d, %d", start, end, otherEnd)
+		}
+		b = c
+	}
+}
+
+func BenchmarkBufferDiff(b *testing.B) {
+	buf := NewBuffer(bytes.NewReader(make([]byte, 1<<20)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		newBuf := buf.Clone()
+		newBuf.Replace(int64(i%256)*4096, byte(i))
+		start, _, end, err := buf.Diff(newBuf)
+		if err != nil {
+			b.Fatalf("err should be nil but got: %v", err)
+		}
+		newBuf.Copy(start, end)
+		buf = newBuf
+	}
+}
+
+func newBenchmarkBuffer() *Buffer {
+	b := NewBuffer(bytes.NewReader(make([]byte, 1<<20)))
+	for i := range 10000 {
+		b.Insert(int64(i)*100, byte(i))
+	}
+	return b
+}
+
+func BenchmarkBufferInsert(b *testing.B) {
+	buf := newBenchmarkBuffer()
+	b.ResetTimer()
+	for i := range b.N {
+		buf.Insert(int64(i*7919%(1<<20)), byte(i))
+	}
+}
+
+func BenchmarkBufferDelete(b *testing.B) {
+	buf := newBenchmarkBuffer()
+	b.ResetTimer()
+	for i := range b.N {
+		buf.Delete(int64(i * 7919 % (1 << 19)))
+	}
+}
+
+func BenchmarkBufferCutPaste(b *testing.B) {
+	buf := newBenchmarkBuffer()
+	b.ResetTimer()
+	for i := range b.N {
+		offset := int64(i * 7919 % (1 << 19))
+		c := buf.Copy(offset, offset+1024)
+		buf.Cut(offset, offset+1024)
+		buf.Paste(offset+4096, c)
+	}
+}
+
+func BenchmarkBufferReadAt(b *testing.B) {
+	buf := newBenchmarkBuffer()
+	p := make([]byte, 256)
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := buf.ReadAt(p, int64(i*7919%(1<<20))); err != nil {
+			b.Fatalf("err should be nil but got: %v", err)
+		}
+	}
+}
+
+func BenchmarkBufferClone(b *testing.B) {
+	buf := newBenchmarkBuffer()
+	b.ResetTimer()
+	for range b.N {
+		buf.Clone()
+	}
+}
@@ -3,7 +3,6 @@ package buffer
 import (
 	"errors"
 	"io"
-	"slices"
 )
 
 type bytesReader struct {
@@ -53,14 +52,7 @@ func (r *bytesReader) ReadAt(b []byte, offset int64) (n int, err error) {
 	return
 }
 
-func (r *bytesReader) insert(offset int64, b byte) {
-	r.bs = slices.Insert(r.bs, int(offset), b)
-}
-
-func (r *bytesReader) delete(offset int64) {
-	r.bs = slices.Delete(r.bs, int(offset), int(offset+1))
-}
-
-func (r *bytesReader) clone() *bytesReader {
-	return newBytesReader(slices.Clone(r.bs))
+func (r *bytesReader) slice(start, end int64) []byte {
+	l := int64(len(r.bs))
+	return r.bs[min(max(start, 0), l):min(max(end, 0), l)]
 }
@@ -0,0 +1,265 @@
+package buffer
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+// piece represents a part of a reader.
+type piece struct {
+	r      readAtSeeker
+	offset int64
+	length int64
+}
+
+// node is a node of the persistent treap of pieces.
+// The nodes are immutable so that the trees can share the nodes.
+type node struct {
+	piece    piece
+	left     *node
+	right    *node
+	length   int64
+	priority uint32
+}
+
+func newNode(p piece, priority uint32, left, right *node) *node {
+	return &node{p, left, right, left.len() + p.length + right.len(), priority}
+}
+
+func newLeaf(p piece) *node {
+	return newNode(p, rand.Uint32(), nil, nil)
+}
+
+func (n *node) len() int64 {
+	if n == nil {
+		return 0
+	}
+	return n.length
+}
+
+// merge concatenates the trees.
+func merge(n, m *node) *node {
+	if n == nil {
+		return m
+	}
+	if m == nil {
+		return n
+	}
+	if n.priority > m.priority {
+		return newNode(n.piece, n.priority, n.left, merge(n.right, m))
+	}
+	return newNode(m.piece, m.priority, merge(n, m.left), m.right)
+}
+
+// split the tree at the specific position.
+func split(n *node, pos int64) (*node, *node) {
+	if n == nil {
+		return nil, nil
+	}
+	if l := n.left.len(); pos <= l {
+		left, right := split(n.left, pos)
+		return left, newNode(n.piece, n.priority, right, n.right)
+	} else if pos >= l+n.piece.length {
+		left, right := split(n.right, pos-l-n.piece.length)
+		return newNode(n.piece, n.priority, n.left, left), right
+	} else {
+		p, q := n.piece.split(pos - l)
+		return newNode(p, n.priority, n.left, nil), merge(newLeaf(q), n.right)
+	}
+}
+
+func (p piece) split(pos int64) (piece, piece) {
+	return piece{p.r, p.offset, pos}, piece{p.r, p.offset + pos, p.length - pos}
+}
+
+func (n *node) first() piece {
+	for n.left != nil {
+		n = n.left
+	}
+	return n.piece
+}
+
+func (n *node) last() piece {
+	for n.right != nil {
+		n = n.right
+	}
+	return n.piece
+}
+
+// concat concatenates the trees, and merges the adjacent pieces if possible.
+func concat(n, m *node) *node {
+	if n == nil || m == nil {
+		return merge(n, m)
+	}
+	p, q := n.last(), m.first()
+	if r, ok := joinPieces(p, q); ok {
+		n, _ = split(n, n.length-p.length)
+		_, m = split(m, q.length)
+		return merge(merge(n, newLeaf(r)), m)
+	}
+	return merge(n, m)
+}
+
+// joinPieces joins the adjacent pieces if they can be represented as one piece.
+func joinPieces(p, q piece) (piece, bool) {
+	switch r := p.r.(type) {
+	case constReader:
+		if r == q.r {
+			return piece{r, 0, p.length + q.length}, true
+		}
+	case *bytesReader:
+		if s, ok := q.r.(*bytesReader); ok {
+			bs := make([]byte, 0, p.length+q.length)
+			bs = append(bs, r.slice(p.offset, p.offset+p.length)...)
+			bs = append(bs, s.slice(q.offset, q.offset+q.length)...)
+			return piece{newBytesReader(bs), 0, p.length + q.length}, true
+		}
+	default:
+		if r == q.r && p.offset+p.length == q.offset {
+			return piece{r, p.offset, p.length + q.length}, true
+		}
+	}
+	return piece{}, false
+}
+
+// walk calls the function for the pieces from the specific position, with
+// the position of each piece. The walk stops when the function returns false.
+func (n *node) walk(pos, from int64, f func(int64, piece) bool) bool {
+	if n == nil || pos+n.length <= from {
+		return true
+	}
+	l := n.left.len()
+	if !n.left.walk(pos, from, f) {
+		return false
+	}
+	if pos += l; from < pos+n.piece.length && !f(pos, n.piece) {
+		return false
+	}
+	return n.right.walk(pos+n.piece.length, from, f)
+}
+
+// cursor iterates the pieces of a tree from the front, or from the back when
+// backward. The subtrees are expanded lazily, so that the subtrees shared with
+// another tree can be skipped without iterating the pieces.
+type cursor struct {
+	items    []cursorItem
+	backward bool
+}
+
+// cursorItem is a subtree, or a piece if the node is nil.
+type cursorItem struct {
+	node  *node
+	piece piece
+}
+
+func (i cursorItem) len() int64 {
+	if i.node != nil {
+		return i.node.length
+	}
+	return i.piece.length
+}
+
+func newCursor(n *node, tail piece, backward bool) *cursor {
+	c := &cursor{items: make([]cursorItem, 0, 64), backward: backward}
+	if backward {
+		c.push(cursorItem{node: n})
+		c.push(cursorItem{piece: tail})
+	} else {
+		c.push(cursorItem{piece: tail})
+		c.push(cursorItem{node: n})
+	}
+	return c
+}
+
+func (c *cursor) push(i cursorItem) {
+	if i.len() > 0 {
+		c.items = append(c.items, i)
+	}
+}
+
+func (c *cursor) top() cursorItem {
+	return c.items[len(c.items)-1]
+}
+
+func (c *cursor) pop() {
+	c.items = c.items[:len(c.items)-1]
+}
+
+// expand replaces the subtree at the top with its children and its piece.
+func (c *cursor) expand() {
+	n := c.top().node
+	c.pop()
+	if c.backward {
+		c.push(cursorItem{node: n.left})
+		c.push(cursorItem{piece: n.piece})
+		c.push(cursorItem{node: n.right})
+	} else {
+		c.push(cursorItem{node: n.right})
+		c.push(cursorItem{piece: n.piece})
+		c.push(cursorItem{node: n.left})
+	}
+}
+
+// consume removes the bytes of the length from the piece at the top.
+func (c *cursor) consume(length int64) {
+	p := &c.items[len(c.items)-1].piece
+	if p.length == length {
+		c.pop()
+	} else if c.backward {
+		p.length -= length
+	} else {
+		*p = piece{p.r, p.offset + length, p.length - length}
+	}
+}
+
+// commonLength returns the length of the common prefix of the trees with the
+// tail pieces, or the common suffix when backward, up to the limit.
+func commonLength(n, m *node, p, q piece, limit int64, backward bool) int64 {
+	c, d := newCursor(n, p, backward), newCursor(m, q, backward)
+	var l int64
+	for l < limit && len(c.items) > 0 && len(d.items) > 0 {
+		x, y := c.top(), d.top()
+		switch {
+		case x.node != nil && x.node == y.node:
+			l += x.node.length
+			c.pop()
+			d.pop()
+		case x.node != nil && (y.node == nil || x.node.length >= y.node.length):
+			c.expand()
+		case y.node != nil:
+			d.expand()
+		default:
+			k := min(x.piece.length, y.piece.length)
+			if !samePieces(x.piece, y.piece, k, backward) {
+				return min(l, limit)
+			}
+			c.consume(k)
+			d.consume(k)
+			l += k
+		}
+	}
+	return min(l, limit)
+}
+
+// samePieces reports whether the pieces read the same bytes in the first
+// bytes of the length, or in the last bytes when backward.
+func samePieces(p, q piece, length int64, backward bool) bool {
+	if backward {
+		_, p = p.split(p.length - length)
+		_, q = q.split(q.length - length)
+	}
+	switch r := p.r.(type) {
+	case constReader:
+		return r == q.r
+	case *bytesReader:
+		if s, ok := q.r.(*bytesReader); ok {
+			return slices.Equal(
+				r.slice(p.offset, p.offset+length),
+				s.slice(q.offset, q.offset+length),
+			)
+		}
+		return false
+	default:
+		return r == q.r && p.offset == q.offset
+	}
+}
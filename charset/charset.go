@@ -0,0 +1,193 @@
+// Package charset implements the character encodings of the text column.
+package charset
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// Character encodings of the text column.
+const (
+	ASCII    = "ascii"
+	Latin1   = "latin1"
+	CP437    = "cp437"
+	EBCDIC   = "ebcdic"
+	ShiftJIS = "shift_jis"
+	UTF8     = "utf-8"
+	UTF16LE  = "utf-16le"
+	UTF16BE  = "utf-16be"
+)
+
+// Encodings lists the available encodings.
+var Encodings = []string{ASCII, Latin1, CP437, EBCDIC, ShiftJIS, UTF8, UTF16LE, UTF16BE}
+
+// Continuation marks the continuation bytes of a multi-byte character.
+const Continuation rune = -1
+
+// The glyphs of the control bytes in CP437, except for the null byte.
+var cp437Controls = []rune(".☺☻♥♦♣♠•◘○◙♂♀♪♫☼►◄↕‼¶§▬↨↑↓→←∟↔▲▼")
+
+const cp437Delete = '⌂'
+
+// Decode the character at the head of the bytes, and returns the rune and
+// the size. An invalid byte sequence is decoded to utf8.RuneError.
+func Decode(encoding string, bs []byte) (rune, int) {
+	if len(bs) == 0 {
+		return utf8.RuneError, 0
+	}
+	switch encoding {
+	case Latin1:
+		return charmap.ISO8859_1.DecodeByte(bs[0]), 1
+	case CP437:
+		if b := bs[0]; b < 0x20 {
+			return cp437Controls[b], 1
+		} else if b == 0x7f {
+			return cp437Delete, 1
+		}
+		return charmap.CodePage437.DecodeByte(bs[0]), 1
+	case EBCDIC:
+		return charmap.CodePage037.DecodeByte(bs[0]), 1
+	case ShiftJIS:
+		return decodeShiftJIS(bs)
+	case UTF8:
+		return utf8.DecodeRune(bs)
+	case UTF16LE:
+		return decodeUTF16(bs, binary.LittleEndian)
+	case UTF16BE:
+		return decodeUTF16(bs, binary.BigEndian)
+	default:
+		if bs[0] < 0x80 {
+			return rune(bs[0]), 1
+		}
+		return utf8.RuneError, 1
+	}
+}
+
+func decodeShiftJIS(bs []byte) (rune, int) {
+	switch b := bs[0]; {
+	case b < 0x80:
+		return rune(b), 1
+	case 0xa1 <= b && b <= 0xdf:
+		// The half-width katakana.
+		return 0xff61 + rune(b-0xa1), 1
+	case len(bs) >= 2 && (0x81 <= b && b <= 0x9f || 0xe0 <= b && b <= 0xfc):
+		if t := bs[1]; 0x40 <= t && t <= 0xfc && t != 0x7f {
+			if xs, err := japanese.ShiftJIS.NewDecoder().Bytes(bs[:2]); err == nil {
+				if r, size := utf8.DecodeRune(xs); size == len(xs) {
+					return r, 2
+				}
+			}
+		}
+	}
+	return utf8.RuneError, 1
+}
+
+func decodeUTF16(bs []byte, order binary.ByteOrder) (rune, int) {
+	if len(bs) < 2 {
+		return utf8.RuneError, 1
+	}
+	r := rune(order.Uint16(bs))
+	if !utf16.IsSurrogate(r) {
+		return r, 2
+	}
+	if len(bs) >= 4 {
+		if r = utf16.DecodeRune(r, rune(order.Uint16(bs[2:]))); r != utf8.RuneError {
+			return r, 4
+		}
+	}
+	return utf8.RuneError, 2
+}
+
+// Render decodes the bytes at the offset, and returns the runes to draw in
+// the cells of the bytes. The invalid and unprintable characters are dots,
+// and the continuation bytes of the multi-byte characters are Continuation.
+func Render(encoding string, bs []byte, offset int64) []rune {
+	rs := make([]rune, len(bs))
+	var i int
+	if encoding == UTF16LE || encoding == UTF16BE {
+		// Align to the code units from the head of the file.
+		if i = int(offset % 2); i > 0 && len(rs) > 0 {
+			rs[0] = Continuation
+		}
+	}
+	for i < len(bs) {
+		r, size := Decode(encoding, bs[i:])
+		if r == utf8.RuneError || !unicode.IsPrint(r) {
+			r = '.'
+		}
+		rs[i] = r
+		for j := 1; j < size && i+j < len(rs); j++ {
+			rs[i+j] = Continuation
+		}
+		i += size
+	}
+	return rs
+}
+
+// Encode the rune typed in the text column. For compatibility, the runes
+// other than ASCII are encoded in UTF-8 when the encoding is ascii.
+func Encode(encoding string, r rune) ([]byte, error) {
+	switch encoding {
+	case Latin1:
+		return encodeCharmap(encoding, charmap.ISO8859_1, r)
+	case CP437:
+		for b, c := range cp437Controls {
+			if b > 0 && c == r {
+				return []byte{byte(b)}, nil
+			}
+		}
+		if r == cp437Delete {
+			return []byte{0x7f}, nil
+		}
+		return encodeCharmap(encoding, charmap.CodePage437, r)
+	case EBCDIC:
+		return encodeCharmap(encoding, charmap.CodePage037, r)
+	case ShiftJIS:
+		if !utf8.ValidRune(r) {
+			return nil, errEncode(encoding, r)
+		}
+		bs, err := japanese.ShiftJIS.NewEncoder().Bytes(utf8.AppendRune(nil, r))
+		if err != nil {
+			return nil, errEncode(encoding, r)
+		}
+		return bs, nil
+	case UTF16LE, UTF16BE:
+		if !utf8.ValidRune(r) {
+			return nil, errEncode(encoding, r)
+		}
+		var bs []byte
+		var order binary.AppendByteOrder = binary.LittleEndian
+		if encoding == UTF16BE {
+			order = binary.BigEndian
+		}
+		for _, u := range utf16.AppendRune(nil, r) {
+			bs = order.AppendUint16(bs, u)
+		}
+		return bs, nil
+	case ASCII, UTF8:
+		if !utf8.ValidRune(r) {
+			return nil, errEncode(encoding, r)
+		}
+		return utf8.AppendRune(nil, r), nil
+	default:
+		return nil, errors.New("unknown text encoding: " + encoding)
+	}
+}
+
+func encodeCharmap(encoding string, m *charmap.Charmap, r rune) ([]byte, error) {
+	if b, ok := m.EncodeRune(r); ok {
+		return []byte{b}, nil
+	}
+	return nil, errEncode(encoding, r)
+}
+
+func errEncode(encoding string, r rune) error {
+	return fmt.Errorf("cannot encode %U in %s", r, encoding)
+}
@@ -0,0 +1,80 @@
+package charset
+
+import (
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	testCases := []struct {
+		encoding string
+		bytes    string
+		offset   int64
+		expected string
+	}{
+		{ASCII, "ab\x00\x7f\x80\xff \t", 0, "ab.... ."},
+		{Latin1, "ab\x00\x7f\x80\xe9\xff", 0, "ab...éÿ"},
+		{CP437, "\x00\x01\x1f\x7f\xb3\xdb\xe1", 0, ".☺▼⌂│█ß"},
+		{EBCDIC, "\xc1\xc2\x81\x40\xf0\x25", 0, "ABa 0."},
+		{ShiftJIS, "a\x82\xa0\xb1\x88\x9f\x82", 0, "aあ-ｱ亜-."},
+		{UTF8, "a\xe3\x81\x82\xf0\x9f\x8d\xa3\x80\xe3", 0, "aあ--🍣---.."},
+		{UTF16LE, "a\x00B0\x3c\xd8\x63\xdf\x00", 0, "a-あ-🍣---."},
+		{UTF16LE, "\x00a\x00", 1, "-a-"},
+		{UTF16BE, "\x00a\x30\x42\xd8\x00", 0, "a-あ-.-"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.encoding, func(t *testing.T) {
+			var got []rune
+			for _, r := range Render(tc.encoding, []byte(tc.bytes), tc.offset) {
+				if r == Continuation {
+					r = '-'
+				}
+				got = append(got, r)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("Render(%q, %q) should be %q but got %q", tc.encoding, tc.bytes, tc.expected, string(got))
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	testCases := []struct {
+		encoding string
+		r        rune
+		expected string
+		err      string
+	}{
+		{ASCII, 'a', "a", ""},
+		{ASCII, 'あ', "\xe3\x81\x82", ""},
+		{Latin1, 'é', "\xe9", ""},
+		{Latin1, 'あ', "", "cannot encode U+3042 in latin1"},
+		{CP437, '☺', "\x01", ""},
+		{CP437, '⌂', "\x7f", ""},
+		{CP437, '█', "\xdb", ""},
+		{EBCDIC, 'A', "\xc1", ""},
+		{ShiftJIS, 'あ', "\x82\xa0", ""},
+		{ShiftJIS, 'ｱ', "\xb1", ""},
+		{ShiftJIS, '🍣', "", "cannot encode U+1F363 in shift_jis"},
+		{UTF8, '🍣', "\xf0\x9f\x8d\xa3", ""},
+		{UTF16LE, 'あ', "\x42\x30", ""},
+		{UTF16BE, '🍣', "\xd8\x3c\xdf\x63", ""},
+		{"foo", 'a', "", "unknown text encoding: foo"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.encoding, func(t *testing.T) {
+			got, err := Encode(tc.encoding, tc.r)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("err should be %q but got: %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err should be nil but got: %v", err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("Encode(%q, %q) should be %q but got %q", tc.encoding, tc.r, tc.expected, string(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// Encodings of the clipboard contents.
+const (
+	Hex    = "hex"
+	Base64 = "base64"
+	Raw    = "raw"
+)
+
+// Encodings lists the available encodings.
+var Encodings = []string{Hex, Base64, Raw}
+
+// Encode the bytes to copy to the clipboard.
+func Encode(encoding string, bs []byte) ([]byte, error) {
+	switch encoding {
+	case Hex:
+		return hex.AppendEncode(nil, bs), nil
+	case Base64:
+		return base64.StdEncoding.AppendEncode(nil, bs), nil
+	case Raw:
+		return bs, nil
+	default:
+		return nil, errors.New("unknown clipboard encoding: " + encoding)
+	}
+}
+
+// Decode the bytes pasted from the clipboard.
+func Decode(encoding string, bs []byte) ([]byte, error) {
+	switch encoding {
+	case Hex:
+		bs = bytes.TrimPrefix(removeSpaces(bs), []byte("0x"))
+		return hex.AppendDecode(nil, bs)
+	case Base64:
+		return base64.StdEncoding.AppendDecode(nil, removeSpaces(bs))
+	case Raw:
+		return bs, nil
+	default:
+		return nil, errors.New("unknown clipboard encoding: " + encoding)
+	}
+}
+
+func removeSpaces(bs []byte) []byte {
+	return bytes.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, bs)
+}
+
+// Copy the bytes to the clipboard using the external command.
+func Copy(command string, bs []byte) error {
+	cmd, err := newCommand(command)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = bytes.NewReader(bs)
+	return cmd.Run()
+}
+
+// Paste the bytes from the clipboard using the external command.
+func Paste(command string) ([]byte, error) {
+	cmd, err := newCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	return cmd.Output()
+}
+
+func newCommand(command string) (*exec.Cmd, error) {
+	xs := strings.Fields(command)
+	if len(xs) < 1 {
+		return nil, errors.New("no clipboard command")
+	}
+	return exec.Command(xs[0], xs[1:]...), nil
+}
+
+// CopyCommand detects the command to copy to the clipboard.
+func CopyCommand() string {
+	return detect(
+		[]string{"pbcopy"},
+		[]string{"wl-copy"},
+		[]string{"xclip -selection clipboard", "xsel --clipboard --input"},
+		[]string{"clip"},
+	)
+}
+
+// PasteCommand detects the command to paste from the clipboard.
+func PasteCommand() string {
+	return detect(
+		[]string{"pbpaste"},
+		[]string{"wl-paste --no-newline"},
+		[]string{"xclip -selection clipboard -out", "xsel --clipboard --output"},
+		[]string{"powershell -NoProfile -Command Get-Clipboard"},
+	)
+}
+
+func detect(darwin, wayland, x11, windows []string) string {
+	var commands []string
+	switch {
+	case runtime.GOOS == "darwin":
+		commands = darwin
+	case runtime.GOOS == "windows":
+		commands = windows
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		commands = wayland
+	case os.Getenv("DISPLAY") != "":
+		commands = x11
+	}
+	for _, command := range commands {
+		name, _, _ := strings.Cut(command, " ")
+		if _, err := exec.LookPath(name); err == nil {
+			return command
+		}
+	}
+	return ""
+}
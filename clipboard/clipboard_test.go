@@ -0,0 +1,57 @@
+package clipboard
+
+import (
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	for _, testCase := range []struct {
+		encoding string
+		src      string
+		encoded  string
+	}{
+		{Hex, "Hello\x00\xff", "48656c6c6f00ff"},
+		{Base64, "Hello\x00\xff", "SGVsbG8A/w=="},
+		{Raw, "Hello\x00\xff", "Hello\x00\xff"},
+	} {
+		got, err := Encode(testCase.encoding, []byte(testCase.src))
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if string(got) != testCase.encoded {
+			t.Errorf("Encode(%q, %q) should be %q but got %q",
+				testCase.encoding, testCase.src, testCase.encoded, string(got))
+		}
+		got, err = Decode(testCase.encoding, []byte(testCase.encoded))
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if string(got) != testCase.src {
+			t.Errorf("Decode(%q, %q) should be %q but got %q",
+				testCase.encoding, testCase.encoded, testCase.src, string(got))
+		}
+	}
+	for _, testCase := range []struct {
+		encoding string
+		src      string
+		decoded  string
+	}{
+		{Hex, "0x48 65 6c\n6C 6f\n", "Hello"},
+		{Base64, "SGVs\nbG8=\n", "Hello"},
+	} {
+		got, err := Decode(testCase.encoding, []byte(testCase.src))
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if string(got) != testCase.decoded {
+			t.Errorf("Decode(%q, %q) should be %q but got %q",
+				testCase.encoding, testCase.src, testCase.decoded, string(got))
+		}
+	}
+	if _, err := Decode(Hex, []byte("4")); err == nil {
+		t.Errorf("err should not be nil")
+	}
+	if _, err := Encode("foo", nil); err == nil {
+		t.Errorf("err should not be nil")
+	}
+}
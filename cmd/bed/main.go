@@ -5,7 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 
 	"golang.org/x/term"
 
@@ -41,12 +43,19 @@ Version: %s (rev: %s/%s)
 
 Synopsis:
   %% %[1]s file
+  %% %[1]s -R file
+  %% %[1]s -p pid
+  %% %[1]s -u bedrc file
 
 Options:
 `, name, version, revision, runtime.Version())
 		fs.PrintDefaults()
 	}
-	var showVersion bool
+	var readonly, showVersion bool
+	var pid, rcfile string
+	fs.BoolVar(&readonly, "R", false, "readonly mode")
+	fs.StringVar(&pid, "p", "", "edit the memory of the process")
+	fs.StringVar(&rcfile, "u", "", "use the startup file instead of bedrc (NONE to skip)")
 	fs.BoolVar(&showVersion, "version", false, "print version")
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
@@ -58,7 +67,18 @@ Options:
 		fmt.Printf("%s %s (rev: %s/%s)\n", name, version, revision, runtime.Version())
 		return exitCodeOK
 	}
-	if err := start(fs.Args()); err != nil {
+	args = fs.Args()
+	if pid != "" {
+		if n, err := strconv.Atoi(pid); err != nil || n <= 0 {
+			fmt.Fprintf(os.Stderr, "%s: invalid process id: %s\n", name, pid)
+			return exitCodeErr
+		} else if len(args) > 0 {
+			fmt.Fprintf(os.Stderr, "%s: cannot open files with -p\n", name)
+			return exitCodeErr
+		}
+		args = []string{"/proc/" + pid + "/mem"}
+	}
+	if err := start(args, readonly, rcfile); err != nil {
 		if err, ok := err.(interface{ ExitCode() int }); ok {
 			return err.ExitCode()
 		}
@@ -68,16 +88,19 @@ Options:
 	return exitCodeOK
 }
 
-func start(args []string) error {
+func start(args []string, readonly bool, rcfile string) error {
 	if len(args) > 1 {
 		return errors.New("too many files")
 	}
-	editor := editor.NewEditor(
-		tui.NewTui(), window.NewManager(), cmdline.NewCmdline(),
-	)
+	wm := window.NewManager()
+	wm.SetReadonly(readonly)
+	editor := editor.NewEditor(tui.NewTui(), wm, cmdline.NewCmdline())
 	if err := editor.Init(); err != nil {
 		return err
 	}
+	if err := source(editor, rcfile); err != nil {
+		return err
+	}
 	if len(args) > 0 && args[0] != "-" {
 		if err := editor.Open(args[0]); err != nil {
 			return err
@@ -94,3 +117,24 @@ func start(args []string) error {
 	defer editor.Close()
 	return editor.Run()
 }
+
+// source reads the startup file, $XDG_CONFIG_HOME/bed/bedrc by default.
+// The default startup file is skipped when there is no home directory.
+func source(e *editor.Editor, rcfile string) error {
+	switch rcfile {
+	case "NONE":
+		return nil
+	case "":
+		dir, err := editor.ConfigDir()
+		if err != nil {
+			return nil
+		}
+		if err := e.Source(filepath.Join(dir, "bedrc")); err != nil &&
+			!errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	default:
+		return e.Source(rcfile)
+	}
+}
@@ -1,7 +1,9 @@
 package cmdline
 
 import (
+	"errors"
 	"slices"
+	"strings"
 	"sync"
 	"unicode"
 
@@ -205,12 +207,13 @@ func (c *Cmdline) execute() (finish bool) {
 	defer c.saveHistory()
 	switch c.typ {
 	case ':':
-		cmd, r, bang, _, _, arg, err := parse(string(c.cmdline))
-		if err != nil {
+		// The empty command emits event.Nop to notify the editor
+		// waiting for the command of the mapped keys.
+		if e, err := c.Parse(string(c.cmdline)); err != nil {
 			c.eventCh <- event.Event{Type: event.Error, Error: err}
-		} else if cmd.name != "" {
-			c.eventCh <- event.Event{Type: cmd.eventType, Range: r, CmdName: cmd.name, Bang: bang, Arg: arg}
-			finish = cmd.eventType == event.QuitAll || cmd.eventType == event.QuitErr
+		} else {
+			c.eventCh <- e
+			finish = e.Type == event.QuitAll || e.Type == event.QuitErr
 		}
 	case '/':
 		c.eventCh <- event.Event{Type: event.ExecuteSearch, Arg: string(c.cmdline), Rune: '/'}
@@ -222,6 +225,30 @@ func (c *Cmdline) execute() (finish bool) {
 	return
 }
 
+// Parse parses the command line to the event of the command.
+// It returns event.Nop for the empty command.
+func (*Cmdline) Parse(src string) (event.Event, error) {
+	cmd, r, bang, _, _, arg, err := parse(src)
+	if err != nil {
+		return event.Event{}, err
+	}
+	if cmd.name == "" {
+		return event.Event{Type: event.Nop}, nil
+	}
+	if cmd.eventType == event.VerticalResize {
+		// The vertical modifier is only available for the resize command.
+		var name string
+		name, arg = cutPrefixFunc(arg, func(r rune) bool {
+			return !unicode.IsSpace(r)
+		})
+		if !matchCommand("res[ize]", name) {
+			return event.Event{}, errors.New("vertical is only available for resize")
+		}
+		cmd.name, arg = "res[ize]", strings.TrimLeftFunc(arg, unicode.IsSpace)
+	}
+	return event.Event{Type: cmd.eventType, Range: r, CmdName: cmd.name, Bang: bang, Arg: arg}, nil
+}
+
 func (c *Cmdline) saveHistory() {
 	cmdline := string(c.cmdline)
 	if cmdline == "" {
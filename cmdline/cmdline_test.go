@@ -63,15 +63,43 @@ func TestCmdlineRun(t *testing.T) {
 	if cursor != 2 {
 		t.Errorf("cursor should be 2 but got %v", cursor)
 	}
-	for range 3 {
+	for range 2 {
 		<-redrawCh
 	}
+	if e = <-eventCh; e.Type != event.Nop {
+		t.Errorf("cmdline should emit Nop event but got %v", e)
+	}
+	<-redrawCh
 	cmdline, _, _, _ = c.Get()
 	if expected := ""; string(cmdline) != expected {
 		t.Errorf("cmdline should be %q got %q", expected, string(cmdline))
 	}
 }
 
+func TestCmdlineExecuteEmpty(t *testing.T) {
+	c := NewCmdline()
+	eventCh, cmdlineCh, redrawCh := make(chan event.Event), make(chan event.Event), make(chan struct{})
+	c.Init(eventCh, cmdlineCh, redrawCh)
+	go c.Run()
+	for _, cmdline := range []string{"", "  ", ":"} {
+		go func() {
+			cmdlineCh <- event.Event{Type: event.StartCmdlineCommand}
+			for _, r := range cmdline {
+				cmdlineCh <- event.Event{Type: event.Rune, Rune: r}
+			}
+			cmdlineCh <- event.Event{Type: event.ExecuteCmdline}
+		}()
+		for range len(cmdline) + 1 {
+			<-redrawCh
+		}
+		// The editor waiting for the result of the command is notified.
+		if e := <-eventCh; e.Type != event.Nop {
+			t.Errorf("executing %q should emit Nop event but got %v", cmdline, e)
+		}
+		<-redrawCh
+	}
+}
+
 func TestCmdlineCursorMotion(t *testing.T) {
 	c := NewCmdline()
 
@@ -830,3 +858,27 @@ func TestCmdlineHistory(t *testing.T) {
 	}
 	<-redrawCh
 }
+
+func TestCmdlineParse(t *testing.T) {
+	c := NewCmdline()
+	e, err := c.Parse("  :set timeoutlen=500")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if e.Type != event.Set || e.CmdName != "se[t]" || e.Arg != "timeoutlen=500" {
+		t.Errorf("cmdline should parse set command but got %+v", e)
+	}
+	if e, err = c.Parse(""); err != nil || e.Type != event.Nop {
+		t.Errorf("cmdline should parse empty command to Nop but got %+v, %v", e, err)
+	}
+	if _, err = c.Parse("foo"); err == nil || err.Error() != "unknown command: foo" {
+		t.Errorf("cmdline should report unknown command but got %v", err)
+	}
+	if e, err = c.Parse("vert res +10"); err != nil || e.Type != event.VerticalResize ||
+		e.CmdName != "res[ize]" || e.Arg != "+10" {
+		t.Errorf("cmdline should parse vertical resize command but got %+v, %v", e, err)
+	}
+	if _, err = c.Parse("vertical new"); err == nil || err.Error() != "vertical is only available for resize" {
+		t.Errorf("cmdline should report vertical modifier error but got %v", err)
+	}
+}
@@ -30,21 +30,60 @@ func (rt rangeType) allows(r *event.Range) bool {
 
 var commands = []command{
 	{"e[dit]", "edit", event.Edit, rangeEmpty},
+	{"vie[w]", "view", event.View, rangeEmpty},
+	{"checkt[ime]", "checktime", event.CheckTime, rangeEmpty},
+	{"att[ach]", "attach", event.Attach, rangeEmpty},
+	{"maps", "maps", event.Maps, rangeEmpty},
 	{"ene[w]", "enew", event.Enew, rangeEmpty},
 	{"new", "new", event.New, rangeEmpty},
 	{"vne[w]", "vnew", event.Vnew, rangeEmpty},
 	{"on[ly]", "only", event.Only, rangeEmpty},
 	{"winc[md]", "wincmd", event.Wincmd, rangeEmpty},
+	{"res[ize]", "resize", event.Resize, rangeEmpty},
+	{"vert[ical]", "vertical", event.VerticalResize, rangeEmpty},
+	{"tabe[dit]", "tabedit", event.TabNew, rangeEmpty},
+	{"tabnew", "tabnew", event.TabNew, rangeEmpty},
+	{"tabn[ext]", "tabnext", event.TabNext, rangeEmpty},
+	{"tabp[revious]", "tabprevious", event.TabPrevious, rangeEmpty},
+	{"tabN[ext]", "tabNext", event.TabPrevious, rangeEmpty},
+	{"tabc[lose]", "tabclose", event.TabClose, rangeEmpty},
+	{"tabs", "tabs", event.Tabs, rangeEmpty},
 
 	{"go[to]", "goto", event.CursorGoto, rangeCount},
 	{"%", "%", event.CursorGoto, rangeCount},
+	{"ma[rk]", "mark", event.SetMark, rangeEmpty | rangeCount},
 
 	{"u[ndo]", "undo", event.Undo, rangeEmpty},
 	{"red[o]", "redo", event.Redo, rangeEmpty},
+	{"ea[rlier]", "earlier", event.Earlier, rangeEmpty},
+	{"lat[er]", "later", event.Later, rangeEmpty},
+	{"undol[ist]", "undolist", event.UndoList, rangeEmpty},
+
+	{"reg[isters]", "registers", event.Registers, rangeEmpty},
 
 	{"pw[d]", "pwd", event.Pwd, rangeEmpty},
 	{"cd", "cd", event.Chdir, rangeEmpty},
 	{"chd[ir]", "chdir", event.Chdir, rangeEmpty},
+	{"se[t]", "set", event.Set, rangeEmpty},
+	{"setl[ocal]", "setlocal", event.Set, rangeEmpty},
+	{"so[urce]", "source", event.Source, rangeEmpty},
+	{"hi[ghlight]", "highlight", event.Highlight, rangeEmpty},
+	{"colo[rscheme]", "colorscheme", event.Colorscheme, rangeEmpty},
+	{"map", "map", event.Map, rangeEmpty},
+	{"nm[ap]", "nmap", event.Map, rangeEmpty},
+	{"vm[ap]", "vmap", event.Map, rangeEmpty},
+	{"im[ap]", "imap", event.Map, rangeEmpty},
+	{"cm[ap]", "cmap", event.Map, rangeEmpty},
+	{"no[remap]", "noremap", event.Map, rangeEmpty},
+	{"nn[oremap]", "nnoremap", event.Map, rangeEmpty},
+	{"vn[oremap]", "vnoremap", event.Map, rangeEmpty},
+	{"ino[remap]", "inoremap", event.Map, rangeEmpty},
+	{"cno[remap]", "cnoremap", event.Map, rangeEmpty},
+	{"unm[ap]", "unmap", event.Unmap, rangeEmpty},
+	{"nun[map]", "nunmap", event.Unmap, rangeEmpty},
+	{"vu[nmap]", "vunmap", event.Unmap, rangeEmpty},
+	{"iu[nmap]", "iunmap", event.Unmap, rangeEmpty},
+	{"cu[nmap]", "cunmap", event.Unmap, rangeEmpty},
 	{"exi[t]", "exit", event.Quit, rangeEmpty},
 	{"q[uit]", "quit", event.Quit, rangeEmpty},
 	{"qa[ll]", "qall", event.QuitAll, rangeEmpty},
@@ -8,6 +8,7 @@ import (
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/itchyny/bed/archive"
 	"github.com/itchyny/bed/event"
 )
 
@@ -36,7 +37,7 @@ func (c *completor) complete(cmdline string, forward bool) string {
 		prefix = cmdline
 	}
 	switch cmd.eventType {
-	case event.Edit, event.New, event.Vnew, event.Write, event.WriteQuit:
+	case event.Edit, event.View, event.New, event.Vnew, event.Write, event.WriteQuit, event.Source:
 		return c.completeFilepath(cmdline, prefix, arg, forward, false)
 	case event.Chdir:
 		return c.completeFilepath(cmdline, prefix, arg, forward, true)
@@ -103,6 +104,9 @@ func (c *completor) completeFilepath(
 const separator = string(filepath.Separator)
 
 func (c *completor) listFileNames(arg string, dirOnly bool) (string, []string) {
+	if archivePath, member, ok := archive.Split(arg); ok {
+		return c.listMemberNames(archivePath, member, dirOnly)
+	}
 	var targets []string
 	path, simplify := c.expandPath(arg)
 	if strings.HasPrefix(arg, "$") && !strings.Contains(arg, separator) {
@@ -180,9 +184,58 @@ func (c *completor) listFileNames(arg string, dirOnly bool) (string, []string) {
 		}
 		targets = append(targets, name)
 	}
-	slices.SortFunc(targets, func(p, q string) int {
-		ps, pd := p[len(p)-1] == filepath.Separator, p[0] == '.'
-		qs, qd := q[len(q)-1] == filepath.Separator, q[0] == '.'
+	sortFileNames(targets, filepath.Separator)
+	if simplify != nil {
+		arg = simplify(dir) + separator
+	} else if !strings.HasPrefix(arg, "."+separator) && dir == "." {
+		arg = ""
+	} else if arg = dir; !strings.HasSuffix(arg, separator) {
+		arg += separator
+	}
+	return arg, targets
+}
+
+// listMemberNames lists the members of the archive, like archive.zip//path/.
+func (c *completor) listMemberNames(archivePath, member string, dirOnly bool) (string, []string) {
+	path, _ := c.expandPath(archivePath)
+	dir, base := "", member
+	if i := strings.LastIndexByte(member, '/'); i >= 0 {
+		dir, base = member[:i], member[i+1:]
+	}
+	arg := archive.Join(archivePath, member[:len(member)-len(base)])
+	f, err := c.fs.Open(archive.Join(path, dir))
+	if err != nil {
+		return arg, nil
+	}
+	defer f.Close()
+	fileInfos, err := f.Readdir(1024)
+	if err != nil {
+		return arg, nil
+	}
+	var targets []string
+	base = strings.ToLower(base)
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+		if !strings.HasPrefix(strings.ToLower(name), base) {
+			continue
+		}
+		if fileInfo.IsDir() {
+			name += "/"
+		} else if dirOnly {
+			continue
+		}
+		targets = append(targets, name)
+	}
+	sortFileNames(targets, '/')
+	return arg, targets
+}
+
+// sortFileNames sorts the file names, placing the directories
+// and the dot files after the others.
+func sortFileNames(names []string, sep byte) {
+	slices.SortFunc(names, func(p, q string) int {
+		ps, pd := p[len(p)-1] == sep, p[0] == '.'
+		qs, qd := q[len(q)-1] == sep, q[0] == '.'
 		switch {
 		case ps && !qs:
 			return 1
@@ -196,14 +249,6 @@ func (c *completor) listFileNames(arg string, dirOnly bool) (string, []string) {
 			return strings.Compare(p, q)
 		}
 	})
-	if simplify != nil {
-		arg = simplify(dir) + separator
-	} else if !strings.HasPrefix(arg, "."+separator) && dir == "." {
-		arg = ""
-	} else if arg = dir; !strings.HasSuffix(arg, separator) {
-		arg += separator
-	}
-	return arg, targets
 }
 
 func (c *completor) expandPath(path string) (string, func(string) string) {
@@ -10,7 +10,7 @@ import (
 func TestCompletorCompleteCommand(t *testing.T) {
 	c := newCompletor(nil, nil)
 	cmdline := c.complete("", true)
-	if expected := "cd"; cmdline != expected {
+	if expected := "attach"; cmdline != expected {
 		t.Errorf("cmdline should be %q but got %q", expected, cmdline)
 	}
 	if c.index != 0 {
@@ -26,14 +26,14 @@ func TestCompletorCompleteCommand(t *testing.T) {
 		t.Errorf("completion results should contain %q but got %v", expected, c.results)
 	}
 
-	for range 3 {
+	for range 10 {
 		cmdline = c.complete(cmdline, true)
 	}
 	if expected := "edit"; cmdline != expected {
 		t.Errorf("cmdline should be %q but got %q", expected, cmdline)
 	}
 
-	for range 4 {
+	for range 11 {
 		cmdline = c.complete(cmdline, false)
 	}
 	if expected := ""; cmdline != expected {
@@ -48,7 +48,7 @@ func TestCompletorCompleteCommand(t *testing.T) {
 
 	c.clear()
 	cmdline = c.complete(": :\t", true)
-	if expected := ": :\tcd"; cmdline != expected {
+	if expected := ": :\tattach"; cmdline != expected {
 		t.Errorf("cmdline should be %q but got %q", expected, cmdline)
 	}
 
@@ -68,7 +68,7 @@ func TestCompletorCompleteCommand(t *testing.T) {
 		t.Errorf("cmdline should be %q but got %q", expected, cmdline)
 	}
 	cmdline = c.complete(cmdline, true)
-	if expected := "edit"; cmdline != expected {
+	if expected := "earlier"; cmdline != expected {
 		t.Errorf("cmdline should be %q but got %q", expected, cmdline)
 	}
 	cmdline = c.complete(cmdline, false)
@@ -87,7 +87,7 @@ func TestCompletorCompleteCommand(t *testing.T) {
 
 	c.clear()
 	cmdline = "10"
-	for _, command := range []string{"%", "goto", ""} {
+	for _, command := range []string{"%", "goto", "mark", ""} {
 		cmdline = c.complete(cmdline, true)
 		if expected := "10" + command; cmdline != expected {
 			t.Errorf("cmdline should be %q but got %q", expected, cmdline)
@@ -517,6 +517,34 @@ func TestCompletorCompleteFilepathChdir(t *testing.T) {
 	}
 }
 
+func TestCompletorCompleteFilepathArchive(t *testing.T) {
+	c := newCompletor(&mockFilesystem{}, nil)
+	cmdline := c.complete("e /tmp/archive.zip//", true)
+	if expected := "e /tmp/archive.zip//README.md"; cmdline != expected {
+		t.Errorf("cmdline should be %q but got %q", expected, cmdline)
+	}
+	if expected := "e /tmp/archive.zip//"; c.target != expected {
+		t.Errorf("completion target should be %q but got %q", expected, c.target)
+	}
+
+	cmdline = c.complete(cmdline, true)
+	if expected := "e /tmp/archive.zip//src/"; cmdline != expected {
+		t.Errorf("cmdline should be %q but got %q", expected, cmdline)
+	}
+
+	c.clear()
+	cmdline = c.complete(cmdline, true)
+	if expected := "e /tmp/archive.zip//src/main.go"; cmdline != expected {
+		t.Errorf("cmdline should be %q but got %q", expected, cmdline)
+	}
+
+	c.clear()
+	cmdline = c.complete("e /tmp/archive.zip//src/l", true)
+	if expected := "e /tmp/archive.zip//src/lib/"; cmdline != expected {
+		t.Errorf("cmdline should be %q but got %q", expected, cmdline)
+	}
+}
+
 func TestCompletorCompleteWincmd(t *testing.T) {
 	c := newCompletor(&mockFilesystem{}, nil)
 	cmdline := c.complete("winc", true)
@@ -1,8 +1,18 @@
 package cmdline
 
 import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"math"
 	"os"
 	"os/user"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+
+	"github.com/itchyny/bed/archive"
 )
 
 type fs interface {
@@ -20,9 +30,68 @@ type file interface {
 type filesystem struct{}
 
 func (*filesystem) Open(path string) (file, error) {
+	if archivePath, dir, ok := archive.Split(path); ok {
+		return openArchiveDir(archivePath, dir)
+	}
 	return os.Open(path)
 }
 
+// archiveDir is a directory in the archive.
+type archiveDir struct {
+	fileInfos []os.FileInfo
+}
+
+func openArchiveDir(path, dir string) (*archiveDir, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	a, err := archive.Open(f, fi.Size())
+	if err != nil {
+		r, derr := decompress(f)
+		if derr != nil || r == nil {
+			return nil, err
+		}
+		if a, err = archive.OpenStream(r); err != nil {
+			return nil, err
+		}
+	}
+	return &archiveDir{a.ReadDir(dir)}, nil
+}
+
+// decompress returns the decompressed stream of the compressed archive,
+// or nil if the file is not compressed.
+func decompress(f *os.File) (io.Reader, error) {
+	var bs [6]byte
+	n, _ := f.ReadAt(bs[:], 0)
+	r := io.NewSectionReader(f, 0, math.MaxInt64)
+	switch b := bs[:n]; {
+	case bytes.HasPrefix(b, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(r)
+	case bytes.HasPrefix(b, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	case bytes.HasPrefix(b, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return xz.NewReader(r)
+	case bytes.HasPrefix(b, []byte("BZh")):
+		return bzip2.NewReader(r), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (*archiveDir) Close() error {
+	return nil
+}
+
+func (d *archiveDir) Readdir(int) ([]os.FileInfo, error) {
+	return d.fileInfos, nil
+}
+
 func (*filesystem) Stat(path string) (os.FileInfo, error) {
 	return os.Stat(path)
 }
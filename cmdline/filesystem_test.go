@@ -1,8 +1,12 @@
 package cmdline
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"os"
 	"os/user"
+	"path/filepath"
+	"testing"
 	"time"
 )
 
@@ -85,6 +89,18 @@ func (f *mockFile) Readdir(_ int) ([]os.FileInfo, error) {
 			{"kill", false},
 		}), nil
 	}
+	if f.path == "/tmp/archive.zip//" {
+		return createFileInfoList([]*mockFileInfo{
+			{"src", true},
+			{"README.md", false},
+		}), nil
+	}
+	if f.path == "/tmp/archive.zip//src" {
+		return createFileInfoList([]*mockFileInfo{
+			{"main.go", false},
+			{"lib", true},
+		}), nil
+	}
 	return nil, nil
 }
 
@@ -116,3 +132,35 @@ func (*mockFileInfo) ModTime() time.Time {
 func (*mockFileInfo) Sys() any {
 	return nil
 }
+
+func TestOpenArchiveDirCompressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	for _, name := range []string{"README.md", "src/main.go"} {
+		if err = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644}); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	d, err := openArchiveDir(path, "")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	fis, _ := d.Readdir(0)
+	if len(fis) != 2 || fis[0].Name() != "README.md" || fis[1].Name() != "src" || !fis[1].IsDir() {
+		t.Errorf("entries should be README.md and src/ but got %v", fis)
+	}
+}
@@ -0,0 +1,82 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/itchyny/bed/buffer"
+	"github.com/itchyny/bed/clipboard"
+	"github.com/itchyny/bed/register"
+)
+
+var errNoClipboard = errors.New("no clipboard command available")
+
+func (e *Editor) getRegister(name rune) (*buffer.Buffer, error) {
+	b, err := e.register.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == register.Clipboard {
+		c, err := e.pasteClipboard()
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			b = c
+		}
+	}
+	if b == nil && name != 0 {
+		return nil, errors.New("nothing in register " + string(name))
+	}
+	return b, nil
+}
+
+func (e *Editor) copyClipboard(name rune, b *buffer.Buffer) error {
+	if name != register.Clipboard {
+		return nil
+	}
+	l, err := b.Len()
+	if err != nil {
+		return err
+	}
+	bs, err := io.ReadAll(io.NewSectionReader(b, 0, l))
+	if err != nil {
+		return err
+	}
+	if bs, err = clipboard.Encode(e.options.clipboardEncoding, bs); err != nil {
+		return err
+	}
+	if e.options.osc52 {
+		e.ui.SetClipboard(bs)
+	}
+	command := e.options.clipboardCopy
+	if command == "" && !e.options.osc52 {
+		if command = clipboard.CopyCommand(); command == "" {
+			return errNoClipboard
+		}
+	}
+	if command != "" {
+		return clipboard.Copy(command, bs)
+	}
+	return nil
+}
+
+// pasteClipboard returns nil buffer when the paste command is not available,
+// so that the caller can fall back to the bytes copied to the register.
+func (e *Editor) pasteClipboard() (*buffer.Buffer, error) {
+	command := e.options.clipboardPaste
+	if command == "" {
+		if command = clipboard.PasteCommand(); command == "" {
+			return nil, nil
+		}
+	}
+	bs, err := clipboard.Paste(command)
+	if err != nil {
+		return nil, err
+	}
+	if bs, err = clipboard.Decode(e.options.clipboardEncoding, bs); err != nil {
+		return nil, err
+	}
+	return buffer.NewBuffer(bytes.NewReader(bs)), nil
+}
@@ -7,4 +7,5 @@ type Cmdline interface {
 	Init(chan<- event.Event, <-chan event.Event, chan<- struct{})
 	Run()
 	Get() ([]rune, int, []string, int)
+	Parse(string) (event.Event, error)
 }
@@ -0,0 +1,32 @@
+package editor
+
+import (
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/state"
+)
+
+// confirm answers the confirmation with the key in confirm mode, and shows
+// the next one. It returns the event to reload the file when the answer is
+// yes. The other events are ignored until all the confirmations are answered.
+func (e *Editor) confirm(ev event.Event) (event.Event, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.mode != mode.Confirm || ev.Type == event.Redraw {
+		return ev, true
+	}
+	if ev.Type != event.Rune {
+		return ev, false
+	}
+	confirm := e.confirms[0]
+	if e.confirms = e.confirms[1:]; len(e.confirms) > 0 {
+		e.err, e.errtyp = e.confirms[0].Error, state.MessageInfo
+	} else {
+		e.mode, e.prevMode = e.prevMode, e.mode
+		e.err = nil
+	}
+	if ev.Rune != 'y' {
+		return event.Event{Type: event.Redraw}, true
+	}
+	return event.Event{Type: event.Reload, Arg: confirm.Arg}, true
+}
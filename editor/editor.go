@@ -4,13 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 
-	"github.com/itchyny/bed/buffer"
 	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/highlight"
+	"github.com/itchyny/bed/key"
 	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/register"
 	"github.com/itchyny/bed/state"
 )
 
@@ -24,7 +27,17 @@ type Editor struct {
 	searchTarget  string
 	searchMode    rune
 	prevEventType event.Type
-	buffer        *buffer.Buffer
+	register      *register.Register
+	options       options
+	highlights    highlight.Highlights
+	colorsName    string
+	kms           map[mode.Mode]*key.Manager
+	typeahead     []typeahead
+	mapDepth      int
+	waitFor       event.Type
+	windowless    bool
+	sourceErrs    []error
+	confirms      []event.Event
 	err           error
 	errtyp        int
 	cmdEventCh    chan event.Event
@@ -32,6 +45,7 @@ type Editor struct {
 	uiEventCh     chan event.Event
 	redrawCh      chan struct{}
 	cmdlineCh     chan event.Event
+	typeaheadCh   chan struct{}
 	quitCh        chan struct{}
 	mu            *sync.Mutex
 }
@@ -39,11 +53,15 @@ type Editor struct {
 // NewEditor creates a new editor.
 func NewEditor(ui UI, wm Manager, cmdline Cmdline) *Editor {
 	return &Editor{
-		ui:       ui,
-		wm:       wm,
-		cmdline:  cmdline,
-		mode:     mode.Normal,
-		prevMode: mode.Normal,
+		ui:         ui,
+		wm:         wm,
+		cmdline:    cmdline,
+		mode:       mode.Normal,
+		prevMode:   mode.Normal,
+		register:   register.NewRegister(),
+		options:    defaultOptions(),
+		highlights: highlight.Default(),
+		colorsName: "default",
 	}
 }
 
@@ -55,7 +73,18 @@ func (e *Editor) Init() error {
 	e.redrawCh = make(chan struct{})
 	e.cmdlineCh = make(chan event.Event)
 	e.cmdline.Init(e.cmdEventCh, e.cmdlineCh, e.redrawCh)
+	e.typeaheadCh = make(chan struct{}, 1)
 	e.quitCh = make(chan struct{})
+	e.kms = defaultKeyManagers()
+	for _, km := range e.kms {
+		km.SetTimeoutHandler(func(ev event.Event) {
+			select {
+			case e.uiEventCh <- ev:
+			case <-e.quitCh:
+			}
+		})
+	}
+	e.setTimeoutlen()
 	e.wm.Init(e.wmEventCh, e.redrawCh)
 	e.mu = new(sync.Mutex)
 	return nil
@@ -82,12 +111,23 @@ func (e *Editor) listen() error {
 		for {
 			select {
 			case ev := <-e.wmEventCh:
+				if ev.Type == event.Nop {
+					// The typeahead waits for the preceding events.
+					continue
+				}
 				if redraw, finish, err := e.emit(ev); redraw {
 					e.redrawCh <- struct{}{}
 				} else if finish {
 					close(e.quitCh)
 					errCh <- err
 				}
+				if ev.Type == event.Copied || ev.Type == event.Error {
+					// Resume the typeahead waiting for the register.
+					select {
+					case e.typeaheadCh <- struct{}{}:
+					default:
+					}
+				}
 			case <-e.quitCh:
 				return
 			}
@@ -96,25 +136,36 @@ func (e *Editor) listen() error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		// Execute the commands of the startup files.
+		if e.runTypeahead(errCh) {
+			return
+		}
 		for {
 			select {
 			case ev := <-e.cmdEventCh:
-				if redraw, finish, err := e.emit(ev); redraw {
-					e.redrawCh <- struct{}{}
-				} else if finish {
-					close(e.quitCh)
-					errCh <- err
+				if e.waitFor == event.ExecuteCmdline {
+					e.waitFor = event.Nop
+				}
+				if ev.Type != event.Nop && e.dispatch(ev, errCh) {
+					return
 				}
 			case ev := <-e.uiEventCh:
-				if redraw, finish, err := e.emit(ev); redraw {
-					e.redrawCh <- struct{}{}
-				} else if finish {
-					close(e.quitCh)
-					errCh <- err
+				if len(e.typeahead) > 0 {
+					// Keep the order of the typed keys and the mapped keys.
+					e.typeahead = append(e.typeahead, typeahead{event: &ev})
+				} else if e.dispatch(ev, errCh) {
+					return
+				}
+			case <-e.typeaheadCh:
+				if e.waitFor == event.Copied {
+					e.waitFor = event.Nop
 				}
 			case <-e.quitCh:
 				return
 			}
+			if e.runTypeahead(errCh) {
+				return
+			}
 		}
 	}()
 	wg.Wait()
@@ -180,9 +231,21 @@ func (e *Editor) emit(ev event.Event) (redraw, finish bool, err error) {
 		return
 	case event.Info:
 		e.err, e.errtyp = ev.Error, state.MessageInfo
+		if e.mode == mode.Confirm {
+			e.err = errors.Join(e.err, e.confirms[0].Error)
+		}
 		redraw = true
 	case event.Error:
 		e.err, e.errtyp = ev.Error, state.MessageError
+		if e.mode == mode.Confirm {
+			e.err = errors.Join(e.err, e.confirms[0].Error)
+		}
+		redraw = true
+	case event.ConfirmReload:
+		if e.confirms = append(e.confirms, ev); len(e.confirms) == 1 {
+			e.mode, e.prevMode = mode.Confirm, e.mode
+			e.err, e.errtyp = ev.Error, state.MessageInfo
+		}
 		redraw = true
 	case event.Redraw:
 		width, height := e.ui.Size()
@@ -191,14 +254,54 @@ func (e *Editor) emit(ev event.Event) (redraw, finish bool, err error) {
 	case event.Copied:
 		e.mode, e.prevMode = mode.Normal, e.mode
 		if ev.Buffer != nil {
-			e.buffer = ev.Buffer
-			if l, err := e.buffer.Len(); err != nil {
+			store := e.register.Delete
+			if ev.Arg == "yanked" {
+				store = e.register.Yank
+			}
+			if err := store(ev.Register, ev.Buffer); err != nil {
+				e.err, e.errtyp = err, state.MessageError
+			} else if err := e.copyClipboard(ev.Register, ev.Buffer); err != nil {
+				e.err, e.errtyp = err, state.MessageError
+			} else if l, err := ev.Buffer.Len(); err != nil {
 				e.err, e.errtyp = err, state.MessageError
 			} else {
 				e.err, e.errtyp = fmt.Errorf("%[1]d (0x%[1]x) bytes %[2]s", l, ev.Arg), state.MessageInfo
 			}
 		}
 		redraw = true
+	case event.Set:
+		if str, err := e.set(ev.Arg, ev.CmdName == "setl[ocal]"); err != nil {
+			e.err, e.errtyp = err, state.MessageError
+		} else if str != "" {
+			e.err, e.errtyp = errors.New(str), state.MessageInfo
+		}
+		redraw = true
+	case event.Map:
+		if str, err := e.mapKeys(ev.CmdName, ev.Arg); err != nil {
+			e.err, e.errtyp = err, state.MessageError
+		} else if str != "" {
+			e.err, e.errtyp = errors.New(str), state.MessageInfo
+		}
+		redraw = true
+	case event.Unmap:
+		if err := e.unmapKeys(ev.CmdName, ev.Arg); err != nil {
+			e.err, e.errtyp = err, state.MessageError
+		}
+		redraw = true
+	case event.Highlight:
+		if str, err := e.highlight(ev.Arg); err != nil {
+			e.err, e.errtyp = err, state.MessageError
+		} else if str != "" {
+			e.err, e.errtyp = errors.New(str), state.MessageInfo
+		}
+		redraw = true
+	case event.Registers:
+		if str, err := e.registers(ev.Arg); err != nil {
+			e.err, e.errtyp = err, state.MessageError
+		} else {
+			e.err, e.errtyp = errors.New(str), state.MessageInfo
+		}
+		redraw = true
 	case event.Pasted:
 		e.err, e.errtyp = fmt.Errorf("%[1]d (0x%[1]x) bytes pasted", ev.Count), state.MessageInfo
 		redraw = true
@@ -214,6 +317,15 @@ func (e *Editor) emit(ev event.Event) (redraw, finish bool, err error) {
 			e.mode, e.prevMode = mode.Visual, e.mode
 		case event.ExitVisual:
 			e.mode, e.prevMode = mode.Normal, e.mode
+		case event.MousePress:
+			if e.mode == mode.Visual {
+				e.mode, e.prevMode = mode.Normal, e.mode
+			}
+		case event.MouseDrag:
+			// Dragging on the bytes starts visual mode.
+			if e.mode == mode.Normal && ev.Mouse != nil && ev.Mouse.Window >= 0 {
+				e.mode, e.prevMode = mode.Visual, e.mode
+			}
 		case event.StartCmdlineCommand:
 			if e.mode == mode.Visual {
 				ev.Arg = "'<,'>"
@@ -245,11 +357,18 @@ func (e *Editor) emit(ev event.Event) (redraw, finish bool, err error) {
 		case event.PreviousSearch:
 			ev.Arg, ev.Rune, e.err = e.searchTarget, e.searchMode, nil
 		case event.Paste, event.PastePrev:
-			if e.buffer == nil {
+			b, er := e.getRegister(ev.Register)
+			if er != nil {
+				e.err, e.errtyp = er, state.MessageError
+				e.mu.Unlock()
+				redraw = true
+				return
+			}
+			if b == nil {
 				e.mu.Unlock()
 				return
 			}
-			ev.Buffer = e.buffer
+			ev.Buffer = b
 		}
 		if e.mode == mode.Cmdline || e.mode == mode.Search ||
 			ev.Type == event.ExitCmdline || ev.Type == event.ExecuteCmdline {
@@ -271,18 +390,51 @@ func (e *Editor) emit(ev event.Event) (redraw, finish bool, err error) {
 	return
 }
 
+func (e *Editor) registers(arg string) (string, error) {
+	names := e.register.List()
+	if arg = strings.Join(strings.Fields(arg), ""); arg != "" {
+		names = slices.DeleteFunc(names, func(name rune) bool {
+			return !strings.ContainsRune(arg, name)
+		})
+	}
+	var sb strings.Builder
+	sb.WriteString("Name Size     Content")
+	for _, name := range names {
+		b, err := e.register.Get(name)
+		if err != nil {
+			return "", err
+		}
+		l, err := b.Len()
+		if err != nil {
+			return "", err
+		}
+		bs := make([]byte, min(l, 16))
+		if _, err := b.ReadAt(bs, 0); err != nil && err != io.EOF {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "\n\"%c   %-8d % x", name, l, bs)
+		if l > int64(len(bs)) {
+			sb.WriteString(" ...")
+		}
+	}
+	return sb.String(), nil
+}
+
 // Open opens a new file.
 func (e *Editor) Open(name string) error {
+	e.runStartup()
 	return e.wm.Open(name)
 }
 
 // OpenEmpty creates a new window.
 func (e *Editor) OpenEmpty() error {
+	e.runStartup()
 	return e.wm.Open("")
 }
 
 // Read [io.Reader] and creates a new window.
 func (e *Editor) Read(r io.Reader) error {
+	e.runStartup()
 	return e.wm.Read(r)
 }
 
@@ -294,7 +446,7 @@ func (e *Editor) Run() error {
 	if err := e.redraw(); err != nil {
 		return err
 	}
-	go e.ui.Run(defaultKeyManagers())
+	go e.ui.Run(e.kms)
 	go e.cmdline.Run()
 	return e.listen()
 }
@@ -312,7 +464,9 @@ func (e *Editor) redraw() (err error) {
 		return errors.New("index out of windows")
 	}
 	s.WindowStates[windowIndex].Mode = e.mode
+	s.Tabs = e.wm.Tabs()
 	s.Mode, s.PrevMode, s.Error, s.ErrorType = e.mode, e.prevMode, e.err, e.errtyp
+	s.Highlights, s.ColorBytes = e.highlights.Clone(), e.options.colorbytes
 	if s.Mode != mode.Visual && s.PrevMode != mode.Visual {
 		for _, ws := range s.WindowStates {
 			ws.VisualStart = -1
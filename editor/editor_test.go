@@ -1,15 +1,22 @@
 package editor
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/gdamore/tcell"
+
 	"github.com/itchyny/bed/cmdline"
 	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/highlight"
 	"github.com/itchyny/bed/key"
 	"github.com/itchyny/bed/mode"
 	"github.com/itchyny/bed/state"
@@ -17,9 +24,10 @@ import (
 )
 
 type testUI struct {
-	eventCh  chan<- event.Event
-	initCh   chan struct{}
-	redrawCh chan struct{}
+	eventCh   chan<- event.Event
+	initCh    chan struct{}
+	redrawCh  chan struct{}
+	clipboard []byte
 }
 
 func newTestUI() *testUI {
@@ -44,6 +52,8 @@ func (ui *testUI) Redraw(state.State) error {
 	return nil
 }
 
+func (ui *testUI) SetClipboard(bs []byte) { ui.clipboard = bs }
+
 func (*testUI) Close() error { return nil }
 
 func (ui *testUI) Emit(e event.Event) {
@@ -56,6 +66,14 @@ func (ui *testUI) Emit(e event.Event) {
 	<-ui.redrawCh
 }
 
+func (ui *testUI) EmitRedraws(e event.Event, redraws int) {
+	<-ui.initCh
+	ui.eventCh <- e
+	for range redraws {
+		<-ui.redrawCh
+	}
+}
+
 func createTemp(dir, str string) (*os.File, error) {
 	f, err := os.CreateTemp(dir, "")
 	if err != nil {
@@ -745,6 +763,159 @@ func TestEditorCopyCutPaste(t *testing.T) {
 	}
 }
 
+func TestEditorMouse(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	f, err := createTemp(t.TempDir(), "Hello, world!")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	go func() {
+		ui.Emit(event.Event{Type: event.MousePress, Mouse: &event.Mouse{Window: 0, Offset: 5}})
+		ui.Emit(event.Event{Type: event.MouseDrag, Mouse: &event.Mouse{Window: 0, Offset: 11}})
+		ui.EmitRedraws(event.Event{Type: event.MouseRelease, Mouse: &event.Mouse{Window: 0, Offset: 11}}, 0)
+		ui.Emit(event.Event{Type: event.Cut})
+		ui.Emit(event.Event{Type: event.MousePress, Mouse: &event.Mouse{Window: 0, Offset: 1}})
+		ui.Emit(event.Event{Type: event.MouseDrag, Mouse: &event.Mouse{Window: 0, Offset: 2}})
+		ui.Emit(event.Event{Type: event.MousePress, Mouse: &event.Mouse{Window: 0, Offset: 0}})
+		ui.Emit(event.Event{Type: event.DeleteByte})
+		ui.Emit(event.Event{Type: event.Write, Arg: f.Name() + ".out"})
+		ui.Emit(event.Event{Type: event.Quit, Bang: true})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if editor.mode != mode.Normal {
+		t.Errorf("mode should be %v but got %v", mode.Normal, editor.mode)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := os.ReadFile(f.Name() + ".out")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "ello!"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestEditorRegisters(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	f, err := createTemp(t.TempDir(), "Hello, world!")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	go func() {
+		ui.Emit(event.Event{Type: event.CursorNext, Count: 2})
+		ui.Emit(event.Event{Type: event.StartVisual})
+		ui.Emit(event.Event{Type: event.CursorNext, Count: 2})
+		ui.Emit(event.Event{Type: event.Copy, Register: 'a'})
+		ui.Emit(event.Event{Type: event.DeleteByte, Count: 2})
+		ui.Emit(event.Event{Type: event.Paste, Register: 'a'})
+		ui.Emit(event.Event{Type: event.PastePrev})
+		ui.Emit(event.Event{Type: event.Paste, Register: 'z'})
+		ui.Emit(event.Event{Type: event.Write, Arg: f.Name() + ".out"})
+		ui.Emit(event.Event{Type: event.Registers, Arg: "a1"})
+		ui.Emit(event.Event{Type: event.Quit, Bang: true})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "Name Size     Content\n" +
+		"\"1   2        6c 6c\n" +
+		"\"a   3        6c 6c 6f"; editor.err == nil || editor.err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, editor.err)
+	}
+	if editor.errtyp != state.MessageInfo {
+		t.Errorf("errtyp should be MessageInfo but got: %v", editor.errtyp)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := os.ReadFile(f.Name() + ".out")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "Heollllo, world!"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestEditorClipboard(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip on Windows")
+	}
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	f, err := createTemp(t.TempDir(), "Hello, world!")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	fin, err := createTemp(t.TempDir(), "41 42 43\n")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	fout := f.Name() + ".clipboard"
+	go func() {
+		ui.Emit(event.Event{Type: event.Set, Arg: "clipboardcopy=tee\\ " + fout})
+		ui.Emit(event.Event{Type: event.Set, Arg: "clipboardpaste=cat\\ " + fin.Name()})
+		ui.Emit(event.Event{Type: event.CursorNext, Count: 2})
+		ui.Emit(event.Event{Type: event.StartVisual})
+		ui.Emit(event.Event{Type: event.CursorNext, Count: 2})
+		ui.Emit(event.Event{Type: event.Copy, Register: '+'})
+		ui.Emit(event.Event{Type: event.Paste, Register: '+'})
+		ui.Emit(event.Event{Type: event.Write, Arg: f.Name() + ".out"})
+		ui.Emit(event.Event{Type: event.Set, Arg: "clipboardencoding?"})
+		ui.Emit(event.Event{Type: event.Quit, Bang: true})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "clipboardencoding=hex"; editor.err == nil || editor.err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, editor.err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "6c6c6f"; string(ui.clipboard) != expected {
+		t.Errorf("clipboard should be %q but got %q", expected, string(ui.clipboard))
+	}
+	bs, err := os.ReadFile(fout)
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "6c6c6f"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+	bs, err = os.ReadFile(f.Name() + ".out")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "HelABClo, world!"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
 func TestEditorShowBinary(t *testing.T) {
 	ui := newTestUI()
 	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
@@ -881,3 +1052,314 @@ func TestEditorChdir(t *testing.T) {
 		t.Errorf("err should be nil but got: %v", err)
 	}
 }
+
+func TestEditorConfirmReload(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skip on Windows")
+	}
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	f, err := createTemp(t.TempDir(), "Hello, world!")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	checkState := func(m mode.Mode, msg string) {
+		editor.mu.Lock()
+		defer editor.mu.Unlock()
+		if editor.mode != m {
+			t.Errorf("mode should be %d but got: %d", m, editor.mode)
+		}
+		if msg == "" && editor.err != nil || msg != "" && (editor.err == nil || editor.err.Error() != msg) {
+			t.Errorf("err should be %q but got: %v", msg, editor.err)
+		}
+	}
+	go func() {
+		question := filepath.Base(f.Name()) + " has been changed since editing started, reload? (y/n)"
+		if err := os.WriteFile(f.Name(), []byte("Hello, bed!"), 0o644); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		ui.Emit(event.Event{Type: event.CheckTime})
+		checkState(mode.Confirm, question)
+		ui.Emit(event.Event{Type: event.Rune, Rune: 'n'})
+		checkState(mode.Normal, "")
+		if err := os.WriteFile(f.Name(), []byte("Hello!"), 0o644); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		ui.Emit(event.Event{Type: event.CheckTime})
+		checkState(mode.Confirm, question)
+		ui.Emit(event.Event{Type: event.Rune, Rune: 'y'})
+		checkState(mode.Normal, filepath.Base(f.Name())+" has been reloaded")
+		ui.Emit(event.Event{Type: event.Increment})
+		ui.Emit(event.Event{Type: event.WriteQuit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "Iello!"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestEditorMapping(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	f, err := createTemp(t.TempDir(), "\x00\x00")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	checkErr := func(expected string) {
+		editor.mu.Lock()
+		defer editor.mu.Unlock()
+		if editor.err == nil || editor.err.Error() != expected {
+			t.Errorf("err should be %q but got: %v", expected, editor.err)
+		}
+	}
+	go func() {
+		ui.Emit(event.Event{Type: event.Map, CmdName: "nn[oremap]", Arg: "s 3<C-a>"})
+		ui.Emit(event.Event{Type: event.Map, CmdName: "nm[ap]", Arg: "<Leader>s s"})
+		ui.Emit(event.Event{Type: event.Map, CmdName: "map", Arg: "Q Decrement"})
+		ui.Emit(event.Event{Type: event.Keys, Arg: "\\s"})
+		ui.Emit(event.Event{Type: event.Keys, Arg: "2Q"})
+		ui.EmitRedraws(event.Event{Type: event.Keys, Arg: ":set timeoutlen=50<CR><C-a>"}, 21)
+		ui.Emit(event.Event{Type: event.Map, CmdName: "nm[ap]"})
+		checkErr("n  s            * 3<c-a>\n" +
+			"n  \\s             s\n" +
+			"n  Q              Decrement")
+		ui.Emit(event.Event{Type: event.Map, CmdName: "nm[ap]", Arg: "x x"})
+		ui.Emit(event.Event{Type: event.Keys, Arg: "x"})
+		checkErr("recursive mapping")
+		ui.Emit(event.Event{Type: event.Unmap, CmdName: "unm[ap]", Arg: "Q"})
+		ui.Emit(event.Event{Type: event.Unmap, CmdName: "unm[ap]", Arg: "Q"})
+		checkErr("no such mapping: Q")
+		ui.Emit(event.Event{Type: event.WriteQuit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if editor.options.timeoutlen != 50 {
+		t.Errorf("timeoutlen should be 50 but got: %d", editor.options.timeoutlen)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "\x02\x00"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestEditorSource(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	f, err := createTemp(t.TempDir(), "\x00")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	dir := t.TempDir()
+	rcfile, second := filepath.Join(dir, "bedrc"), filepath.Join(dir, "second")
+	if err := os.WriteFile(rcfile, []byte(`" comment
+set timeoutlen=20
+nnoremap s <C-a><C-a>
+
+bogus
+set foo=1
+wincmd z
+source `+second+"\n"), 0o644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := os.WriteFile(second, []byte("nmap t Decrement\n"), 0o644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.Source(rcfile); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.Source(filepath.Join(dir, "none")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err should be ErrNotExist but got: %v", err)
+	}
+	go func() {
+		<-ui.initCh
+		for range 6 {
+			<-ui.redrawCh
+		}
+		editor.mu.Lock()
+		if expected := rcfile + ":5: unknown command: bogus\n" +
+			rcfile + ":6: unknown option: foo\n" +
+			rcfile + ":7: Invalid argument for wincmd: z"; editor.err == nil || editor.err.Error() != expected {
+			t.Errorf("err should be %q but got: %v", expected, editor.err)
+		}
+		editor.mu.Unlock()
+		ui.EmitRedraws(event.Event{Type: event.Keys, Arg: "st"}, 3)
+		ui.Emit(event.Event{Type: event.Source, CmdName: "so[urce]"})
+		ui.Emit(event.Event{Type: event.WriteQuit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if editor.options.timeoutlen != 20 {
+		t.Errorf("timeoutlen should be 20 but got: %d", editor.options.timeoutlen)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "\x01"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestEditorSourceBeforeOpen(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	dir := t.TempDir()
+	rcfile := filepath.Join(dir, "bedrc")
+	if err := os.WriteFile(rcfile, []byte(`set readonly binary offsetbase=dec
+setlocal textencoding=utf-8
+`), 0o644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte("Hello, world!")); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	name := filepath.Join(dir, "test.gz")
+	if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.Source(rcfile); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.Open(name); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	windowState := func() *state.WindowState {
+		windowStates, _, windowIndex, _ := editor.wm.State()
+		return windowStates[windowIndex]
+	}
+	ws := windowState()
+	if !ws.Readonly {
+		t.Errorf("window should be readonly")
+	}
+	if ws.OffsetBase != "dec" {
+		t.Errorf("offsetbase should be %q but got %q", "dec", ws.OffsetBase)
+	}
+	if !bytes.HasPrefix(ws.Bytes, []byte{0x1f, 0x8b}) {
+		t.Errorf("bytes should not be decompressed but got: % x", ws.Bytes[:2])
+	}
+	go func() {
+		<-ui.initCh
+		<-ui.redrawCh // setlocal is executed after opening the window
+		if ws := windowState(); ws.TextEncoding != "utf-8" {
+			t.Errorf("textencoding should be %q but got %q", "utf-8", ws.TextEncoding)
+		}
+		ui.Emit(event.Event{Type: event.Quit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+}
+
+func TestEditorHighlight(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "bed", "colors"), 0o755); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bed", "colors", "mine"),
+		[]byte("highlight Visual fg=red attr=bold\nhi EditedByte NONE\n"), 0o644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := editor.OpenEmpty(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	checkErr := func(expected string) {
+		editor.mu.Lock()
+		defer editor.mu.Unlock()
+		if editor.err == nil || editor.err.Error() != expected {
+			t.Errorf("err should be %q but got: %v", expected, editor.err)
+		}
+	}
+	checkHighlight := func(group string, expected highlight.Highlight) {
+		editor.mu.Lock()
+		defer editor.mu.Unlock()
+		if got := editor.highlights[group]; got != expected {
+			t.Errorf("highlight %s should be %+v but got %+v", group, expected, got)
+		}
+	}
+	go func() {
+		ui.Emit(event.Event{Type: event.Highlight, Arg: "cursor fg=#FF0000 attr=bold,underline"})
+		checkHighlight(highlight.Cursor, highlight.Highlight{
+			Fg: "#ff0000", Attrs: tcell.AttrBold | tcell.AttrUnderline})
+		ui.Emit(event.Event{Type: event.Highlight, Arg: "Cursor"})
+		checkErr("Cursor          fg=#ff0000 attr=bold,underline")
+		ui.Emit(event.Event{Type: event.Highlight, Arg: "Foo fg=red"})
+		checkErr("unknown highlight group: Foo")
+		ui.Emit(event.Event{Type: event.Highlight, Arg: "Visual fg=foo"})
+		checkErr("invalid color: foo")
+		ui.EmitRedraws(event.Event{Type: event.Colorscheme, Arg: "mine"}, 3)
+		checkHighlight(highlight.Visual, highlight.Highlight{Fg: "red", Attrs: tcell.AttrBold})
+		checkHighlight(highlight.EditedByte, highlight.Highlight{})
+		checkHighlight(highlight.Cursor, highlight.Default()[highlight.Cursor])
+		ui.Emit(event.Event{Type: event.Colorscheme})
+		checkErr("mine")
+		ui.Emit(event.Event{Type: event.Colorscheme, Arg: "none"})
+		checkErr("cannot find color scheme: none")
+		ui.Emit(event.Event{Type: event.Highlight, Arg: "clear Visual"})
+		checkHighlight(highlight.Visual, highlight.Default()[highlight.Visual])
+		checkHighlight(highlight.EditedByte, highlight.Highlight{})
+		ui.Emit(event.Event{Type: event.Highlight, Arg: "clear"})
+		checkHighlight(highlight.EditedByte, highlight.Default()[highlight.EditedByte])
+		ui.Emit(event.Event{Type: event.Quit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package editor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/itchyny/bed/highlight"
+)
+
+// highlight updates the highlight group, or lists the highlight groups
+// without the colors and the attributes. The :highlight clear command
+// restores the default highlights.
+func (e *Editor) highlight(arg string) (string, error) {
+	fields := strings.Fields(arg)
+	switch {
+	case len(fields) == 0:
+		return e.highlights.Format(), nil
+	case fields[0] == "clear":
+		if len(fields) == 1 {
+			e.highlights = highlight.Default()
+			return "", nil
+		}
+		for _, name := range fields[1:] {
+			group, err := highlight.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			e.highlights[group] = highlight.Default()[group]
+		}
+		return "", nil
+	case len(fields) == 1:
+		group, err := highlight.LookupGroup(fields[0])
+		if err != nil {
+			return "", err
+		}
+		return e.highlights.Format(group), nil
+	default:
+		return "", e.highlights.Set(arg)
+	}
+}
+
+// colorscheme loads the color scheme file, $XDG_CONFIG_HOME/bed/colors/NAME,
+// which consists of :highlight commands. The highlights are restored to the
+// default before loading the file, and the default color scheme has no file.
+func (e *Editor) colorscheme(arg string) (string, error) {
+	name := strings.TrimSpace(arg)
+	if name == "" {
+		return e.colorsName, nil
+	}
+	if name == "default" {
+		e.mu.Lock()
+		e.highlights, e.colorsName = highlight.Default(), name
+		e.mu.Unlock()
+		return "", nil
+	}
+	if strings.ContainsRune(name, filepath.Separator) || name[0] == '.' {
+		return "", errors.New("invalid color scheme: " + name)
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "colors", name)
+	if _, err := os.Stat(path); err != nil {
+		return "", errors.New("cannot find color scheme: " + name)
+	}
+	e.mu.Lock()
+	e.highlights, e.colorsName = highlight.Default(), name
+	e.mu.Unlock()
+	return "", e.Source(path)
+}
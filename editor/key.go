@@ -41,6 +41,8 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 
 	km.Register(event.Undo, "u")
 	km.Register(event.Redo, "c-r")
+	km.Register(event.Earlier, "g", "-")
+	km.Register(event.Later, "g", "+")
 
 	km.Register(event.StartVisual, "v")
 
@@ -72,6 +74,13 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 	km.Register(event.MoveWindowBottom, "c-w", "J")
 	km.Register(event.MoveWindowLeft, "c-w", "H")
 	km.Register(event.MoveWindowRight, "c-w", "L")
+	km.Register(event.IncreaseWindowHeight, "c-w", "+")
+	km.Register(event.DecreaseWindowHeight, "c-w", "-")
+	km.Register(event.IncreaseWindowWidth, "c-w", ">")
+	km.Register(event.DecreaseWindowWidth, "c-w", "<")
+	km.Register(event.EqualizeWindows, "c-w", "=")
+	km.Register(event.TabNext, "g", "t")
+	km.Register(event.TabPrevious, "g", "T")
 	kms[mode.Normal] = km
 
 	km = key.NewManager(false)
@@ -139,6 +148,9 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 	km.Register(event.ExecuteCmdline, "c-m")
 	kms[mode.Cmdline] = km
 	kms[mode.Search] = km
+
+	// The keys answer the confirmation, emitted as runes.
+	kms[mode.Confirm] = key.NewManager(false)
 	return kms
 }
 
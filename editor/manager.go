@@ -5,6 +5,7 @@ import (
 
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/layout"
+	"github.com/itchyny/bed/option"
 	"github.com/itchyny/bed/state"
 )
 
@@ -16,6 +17,8 @@ type Manager interface {
 	SetSize(int, int)
 	Resize(int, int)
 	Emit(event.Event)
+	SetOption(option.Option, bool) (string, error)
 	State() (map[int]*state.WindowState, layout.Layout, int, error)
+	Tabs() []state.TabState
 	Close()
 }
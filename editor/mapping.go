@@ -0,0 +1,315 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/key"
+	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/state"
+)
+
+// maxMapDepth is the limit of the expansions of the key mappings and the
+// sourced files, to stop the recursive mappings like :nmap x x.
+const maxMapDepth = 1000
+
+type mapCommand struct {
+	modes   []mode.Mode
+	noremap bool
+}
+
+var (
+	normalAndVisual = []mode.Mode{mode.Normal, mode.Visual}
+	mapCommands     = map[string]mapCommand{
+		"map":        {normalAndVisual, false},
+		"nm[ap]":     {[]mode.Mode{mode.Normal}, false},
+		"vm[ap]":     {[]mode.Mode{mode.Visual}, false},
+		"im[ap]":     {[]mode.Mode{mode.Insert}, false},
+		"cm[ap]":     {[]mode.Mode{mode.Cmdline}, false},
+		"no[remap]":  {normalAndVisual, true},
+		"nn[oremap]": {[]mode.Mode{mode.Normal}, true},
+		"vn[oremap]": {[]mode.Mode{mode.Visual}, true},
+		"ino[remap]": {[]mode.Mode{mode.Insert}, true},
+		"cno[remap]": {[]mode.Mode{mode.Cmdline}, true},
+		"unm[ap]":    {normalAndVisual, false},
+		"nun[map]":   {[]mode.Mode{mode.Normal}, false},
+		"vu[nmap]":   {[]mode.Mode{mode.Visual}, false},
+		"iu[nmap]":   {[]mode.Mode{mode.Insert}, false},
+		"cu[nmap]":   {[]mode.Mode{mode.Cmdline}, false},
+	}
+	mapModeNames = map[mode.Mode]byte{
+		mode.Normal: 'n', mode.Visual: 'v', mode.Insert: 'i', mode.Cmdline: 'c',
+	}
+)
+
+func lookupMapCommand(name string) (mapCommand, error) {
+	cmd, ok := mapCommands[name]
+	if !ok {
+		return mapCommand{}, errors.New("unknown command: " + name)
+	}
+	return cmd, nil
+}
+
+var leaderPattern = regexp.MustCompile(`(?i)<leader>`)
+
+// parseMapKeys parses the key notation, replacing <leader> with mapleader.
+func (e *Editor) parseMapKeys(s string) ([]key.Key, error) {
+	return key.ParseKeys(leaderPattern.ReplaceAllLiteralString(s,
+		strings.ReplaceAll(e.options.mapleader, "<", "<lt>")))
+}
+
+// mapKeys adds the key mapping, or lists the key mappings without the
+// right-hand side. The right-hand side is either an event name, like
+// CursorDown, or a key sequence.
+func (e *Editor) mapKeys(name, arg string) (string, error) {
+	cmd, err := lookupMapCommand(name)
+	if err != nil {
+		return "", err
+	}
+	lhs, rhs, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	if rhs = strings.TrimLeftFunc(rhs, unicode.IsSpace); rhs == "" {
+		return e.listMappings(cmd.modes, lhs)
+	}
+	keys, err := e.parseMapKeys(lhs)
+	if err != nil {
+		return "", err
+	}
+	if typ, ok := event.ParseType(rhs); ok {
+		for _, m := range cmd.modes {
+			e.kms[m].Map(keys, typ)
+		}
+		return "", nil
+	}
+	rhsKeys, err := e.parseMapKeys(rhs)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range cmd.modes {
+		e.kms[m].MapKeys(keys, key.FormatKeys(rhsKeys), cmd.noremap)
+	}
+	return "", nil
+}
+
+// unmapKeys removes the key mapping.
+func (e *Editor) unmapKeys(name, arg string) error {
+	cmd, err := lookupMapCommand(name)
+	if err != nil {
+		return err
+	}
+	if arg = strings.TrimSpace(arg); arg == "" {
+		return errors.New("an argument is required for " + name)
+	}
+	keys, err := e.parseMapKeys(arg)
+	if err != nil {
+		return err
+	}
+	var found bool
+	for _, m := range cmd.modes {
+		if e.kms[m].Unmap(keys) {
+			found = true
+		}
+	}
+	if !found {
+		return errors.New("no such mapping: " + arg)
+	}
+	return nil
+}
+
+func (e *Editor) listMappings(modes []mode.Mode, lhs string) (string, error) {
+	var prefix string
+	if lhs != "" {
+		keys, err := e.parseMapKeys(lhs)
+		if err != nil {
+			return "", err
+		}
+		prefix = key.FormatKeys(keys)
+	}
+	var sb strings.Builder
+	for _, m := range modes {
+		for _, mapping := range e.kms[m].Mappings() {
+			lhs := key.FormatKeys(mapping.Keys)
+			if !strings.HasPrefix(lhs, prefix) {
+				continue
+			}
+			rhs, mark := mapping.RHS, ' '
+			if mapping.Event != event.Keys {
+				rhs = mapping.Event.String()
+			} else if mapping.Noremap {
+				mark = '*'
+			}
+			if sb.Len() > 0 {
+				sb.WriteByte('\n')
+			}
+			fmt.Fprintf(&sb, "%c  %-12s %c %s", mapModeNames[m], lhs, mark, rhs)
+		}
+	}
+	if sb.Len() == 0 {
+		return "No mapping found", nil
+	}
+	return sb.String(), nil
+}
+
+func (e *Editor) setTimeoutlen() {
+	for _, km := range e.kms {
+		km.SetTimeout(time.Duration(e.options.timeoutlen) * time.Millisecond)
+	}
+}
+
+// typeahead is a key of the mapped key sequences, or an event to process
+// after the mapped keys. The source is the file and line of the sourced
+// command, to report the error.
+type typeahead struct {
+	key     key.Key
+	noremap bool
+	event   *event.Event
+	source  string
+}
+
+// pushKeys inserts the keys of the key mapping to the head of the typeahead.
+func (e *Editor) pushKeys(ev event.Event) error {
+	if e.mapDepth++; e.mapDepth > maxMapDepth {
+		e.typeahead, e.mapDepth = nil, 0
+		return errors.New("recursive mapping")
+	}
+	var keys []key.Key
+	if ev.Register != 0 {
+		keys = append(keys, "\"", key.Key(ev.Register))
+	}
+	if ev.Count > 0 {
+		for _, c := range strconv.FormatInt(ev.Count, 10) {
+			keys = append(keys, key.Key(c))
+		}
+	}
+	ks, err := key.ParseKeys(ev.Arg)
+	if err != nil {
+		return err
+	}
+	keys = append(keys, ks...)
+	ts := make([]typeahead, len(keys), len(keys)+len(e.typeahead))
+	for i, k := range keys {
+		ts[i] = typeahead{key: k, noremap: ev.Bang}
+	}
+	e.typeahead = append(ts, e.typeahead...)
+	return nil
+}
+
+// runTypeahead processes the typeahead until it waits for the command line
+// or the window manager. It reports true when the editor finishes.
+func (e *Editor) runTypeahead(errCh chan<- error) bool {
+	for len(e.typeahead) > 0 && e.waitFor == event.Nop {
+		t := e.typeahead[0]
+		if e.windowless && (t.event == nil || !windowlessEvent(*t.event)) {
+			break
+		}
+		e.typeahead = e.typeahead[1:]
+		if t.event == nil {
+			e.mu.Lock()
+			km := e.kms[e.mode]
+			e.mu.Unlock()
+			var events []event.Event
+			if t.noremap {
+				events = km.PressNoremap(t.key)
+			} else {
+				events = km.Press(t.key)
+			}
+			ts := make([]typeahead, len(events), len(events)+len(e.typeahead))
+			for i := range events {
+				ts[i] = typeahead{event: &events[i]}
+			}
+			e.typeahead = append(ts, e.typeahead...)
+			continue
+		}
+		switch t.event.Type {
+		case event.ExecuteCmdline:
+			e.waitFor = event.ExecuteCmdline
+		case event.Copy, event.Cut, event.DeleteByte, event.DeletePrevByte:
+			e.mu.Lock()
+			if e.mode != mode.Cmdline && e.mode != mode.Search {
+				e.waitFor = event.Copied
+				select {
+				case <-e.typeaheadCh:
+				default:
+				}
+			}
+			e.mu.Unlock()
+		}
+		if t.source != "" {
+			e.mu.Lock()
+			e.err = nil
+			e.mu.Unlock()
+		}
+		if e.dispatch(*t.event, errCh) {
+			return true
+		}
+		if t.source != "" {
+			e.waitWindowManager()
+			e.mu.Lock()
+			if e.err != nil && e.errtyp == state.MessageError {
+				e.sourceErrs = append(e.sourceErrs, fmt.Errorf("%s: %w", t.source, e.err))
+			}
+			e.mu.Unlock()
+		}
+	}
+	if len(e.typeahead) == 0 {
+		e.waitFor, e.mapDepth = event.Nop, 0
+		e.showSourceErrors()
+	}
+	return false
+}
+
+// waitWindowManager waits until the editor processes the events which the
+// window manager has emitted, so that the errors of the window commands are
+// reported with the location of the sourced command.
+func (e *Editor) waitWindowManager() {
+	select {
+	case e.wmEventCh <- event.Event{Type: event.Nop}:
+	case <-e.quitCh:
+	}
+}
+
+// dispatch emits the event, or expands the keys of the key mapping
+// and the commands of the sourced file and the color scheme. It reports
+// true when the editor finishes.
+func (e *Editor) dispatch(ev event.Event, errCh chan<- error) bool {
+	ev, ok := e.confirm(ev)
+	if !ok {
+		return false
+	}
+	if ev.Type == event.Keys || ev.Type == event.Source || ev.Type == event.Colorscheme {
+		var str string
+		var err error
+		switch ev.Type {
+		case event.Keys:
+			err = e.pushKeys(ev)
+		case event.Source:
+			err = e.source(ev.CmdName, ev.Arg)
+		default:
+			str, err = e.colorscheme(ev.Arg)
+		}
+		if err != nil || str != "" || ev.Type == event.Colorscheme {
+			e.mu.Lock()
+			if err != nil {
+				e.err, e.errtyp = err, state.MessageError
+			} else if str != "" {
+				e.err, e.errtyp = errors.New(str), state.MessageInfo
+			}
+			e.mu.Unlock()
+			e.redrawCh <- struct{}{}
+		}
+		return false
+	}
+	redraw, finish, err := e.emit(ev)
+	if redraw {
+		e.redrawCh <- struct{}{}
+	} else if finish {
+		close(e.quitCh)
+		errCh <- err
+	}
+	return finish
+}
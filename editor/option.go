@@ -0,0 +1,76 @@
+package editor
+
+import (
+	"strings"
+
+	"github.com/itchyny/bed/clipboard"
+	"github.com/itchyny/bed/option"
+)
+
+type options struct {
+	osc52             bool
+	clipboardEncoding string
+	clipboardCopy     string
+	clipboardPaste    string
+	mapleader         string
+	timeoutlen        int64
+	colorbytes        bool
+}
+
+func defaultOptions() options {
+	return options{
+		osc52:             true,
+		clipboardEncoding: clipboard.Hex,
+		mapleader:         "\\",
+		timeoutlen:        1000,
+		colorbytes:        true,
+	}
+}
+
+const optionNames = "osc52 clipboardencoding clipboardcopy clipboardpaste mapleader timeoutlen colorbytes undofile inplace autoread binary readonly modifiable follow textencoding offsetbase baseaddress relativeoffset statusline"
+
+// set applies the option settings of :set, or :setlocal when local is set,
+// which does not change the window-local options of the new windows.
+func (e *Editor) set(arg string, local bool) (string, error) {
+	if strings.TrimSpace(arg) == "" {
+		arg = strings.ReplaceAll(optionNames, " ", "? ") + "?"
+	}
+	opts, err := option.Parse(arg)
+	if err != nil {
+		return "", err
+	}
+	var infos []string
+	for _, opt := range opts {
+		var info string
+		switch opt.Name {
+		case "osc52":
+			info, err = opt.SetBool(&e.options.osc52)
+		case "clipboardencoding":
+			info, err = opt.SetString(&e.options.clipboardEncoding, clipboard.Encodings...)
+		case "clipboardcopy":
+			info, err = opt.SetString(&e.options.clipboardCopy)
+		case "clipboardpaste":
+			info, err = opt.SetString(&e.options.clipboardPaste)
+		case "mapleader":
+			info, err = opt.SetString(&e.options.mapleader)
+		case "timeoutlen":
+			if info, err = opt.SetInt(&e.options.timeoutlen); err == nil {
+				e.setTimeoutlen()
+			}
+		case "colorbytes":
+			info, err = opt.SetBool(&e.options.colorbytes)
+		default:
+			info, err = e.wm.SetOption(opt, local)
+		}
+		if err != nil {
+			return "", err
+		}
+		if info != "" {
+			infos = append(infos, info)
+		}
+	}
+	if len(infos) == 0 {
+		return "", nil
+	}
+	return strings.Join(infos, "\n"), nil
+}
@@ -0,0 +1,123 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/state"
+)
+
+// ConfigDir returns the directory of the startup file and the color schemes,
+// $XDG_CONFIG_HOME/bed or ~/.config/bed by default.
+func ConfigDir() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(dir, "bed"), nil
+}
+
+// Source reads the commands in the file, like bedrc. The commands are
+// executed when the editor runs, or after the current command, and the
+// errors are shown after executing all the commands.
+func (e *Editor) Source(name string) error {
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	var ts []typeahead
+	for i, line := range strings.Split(string(bs), "\n") {
+		// The lines starting with a double quote are comments.
+		if line = strings.TrimSpace(line); line == "" || line[0] == '"' {
+			continue
+		}
+		source := fmt.Sprintf("%s:%d", name, i+1)
+		ev, err := e.cmdline.Parse(line)
+		if err != nil {
+			e.sourceErrs = append(e.sourceErrs, fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+		if ev.Type != event.Nop {
+			ts = append(ts, typeahead{event: &ev, source: source})
+		}
+	}
+	e.typeahead = append(ts, e.typeahead...)
+	return nil
+}
+
+// source reads the commands in the file of the :source command.
+func (e *Editor) source(name, arg string) error {
+	if arg = strings.TrimSpace(arg); arg == "" {
+		return errors.New("an argument is required for " + name)
+	}
+	if e.mapDepth++; e.mapDepth > maxMapDepth {
+		e.typeahead, e.mapDepth = nil, 0
+		return errors.New("recursive source")
+	}
+	if rest, ok := strings.CutPrefix(arg, "~"); ok && (rest == "" || rest[0] == '/') {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		arg = filepath.Join(homeDir, rest)
+	}
+	return e.Source(arg)
+}
+
+// showSourceErrors shows the errors of the sourced commands at once.
+func (e *Editor) showSourceErrors() {
+	if len(e.sourceErrs) == 0 {
+		return
+	}
+	e.mu.Lock()
+	e.err, e.errtyp = errors.Join(e.sourceErrs...), state.MessageError
+	e.mu.Unlock()
+	e.sourceErrs = nil
+	e.redrawCh <- struct{}{}
+}
+
+// runStartup executes the commands of the startup files before opening the
+// first window, so that the options apply to the window. It stops at the
+// command which requires a window, and the rest of the commands are executed
+// when the editor runs.
+func (e *Editor) runStartup() {
+	if len(e.typeahead) == 0 {
+		return
+	}
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go func() {
+		for {
+			select {
+			case <-e.redrawCh:
+			case <-e.wmEventCh:
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+	e.windowless = true
+	defer func() { e.windowless = false }()
+	e.runTypeahead(make(chan error, 1))
+}
+
+// windowlessEvent reports whether the editor processes the event without
+// emitting it to the window manager.
+func windowlessEvent(ev event.Event) bool {
+	switch ev.Type {
+	case event.Set:
+		return ev.CmdName != "setl[ocal]"
+	case event.Map, event.Unmap, event.Highlight, event.Colorscheme, event.Source:
+		return true
+	default:
+		return false
+	}
+}
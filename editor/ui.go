@@ -13,5 +13,6 @@ type UI interface {
 	Run(map[mode.Mode]*key.Manager)
 	Size() (int, int)
 	Redraw(state.State) error
+	SetClipboard([]byte)
 	Close() error
 }
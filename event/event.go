@@ -7,16 +7,26 @@ import (
 
 // Event represents the event emitted by UI.
 type Event struct {
-	Type    Type
-	Range   *Range
-	Count   int64
-	Rune    rune
-	CmdName string
-	Bang    bool
-	Arg     string
-	Error   error
-	Mode    mode.Mode
-	Buffer  *buffer.Buffer
+	Type     Type
+	Range    *Range
+	Count    int64
+	Rune     rune
+	Register rune
+	CmdName  string
+	Bang     bool
+	Arg      string
+	Error    error
+	Mode     mode.Mode
+	Buffer   *buffer.Buffer
+	Mouse    *Mouse
+}
+
+// Mouse holds the position of the mouse event.
+type Mouse struct {
+	X, Y      int   // the position on the screen
+	Window    int   // the index of the window, or -1 out of the windows
+	Offset    int64 // the offset of the byte, or -1 out of the bytes
+	FocusText bool  // the position is on the text column
 }
 
 // Type ...
@@ -36,6 +46,7 @@ const (
 	CursorHead
 	CursorEnd
 	CursorGoto
+	SetMark
 	ScrollUp
 	ScrollDown
 	ScrollTop
@@ -80,6 +91,9 @@ const (
 
 	Undo
 	Redo
+	Earlier
+	Later
+	UndoList
 
 	StartVisual
 	SwitchVisualEnd
@@ -91,6 +105,7 @@ const (
 	Paste
 	PastePrev
 	Pasted
+	Registers
 
 	StartCmdlineCommand
 	StartCmdlineSearchForward
@@ -110,6 +125,12 @@ const (
 	AbortSearch
 
 	Edit
+	View
+	CheckTime
+	Reload
+	ConfirmReload
+	Attach
+	Maps
 	Enew
 	New
 	Vnew
@@ -127,9 +148,33 @@ const (
 	MoveWindowBottom
 	MoveWindowLeft
 	MoveWindowRight
+	IncreaseWindowHeight
+	DecreaseWindowHeight
+	IncreaseWindowWidth
+	DecreaseWindowWidth
+	EqualizeWindows
+	Resize
+	VerticalResize
+	TabNew
+	TabNext
+	TabPrevious
+	TabClose
+	Tabs
+	MousePress
+	MouseDrag
+	MouseRelease
+	MouseWheelUp
+	MouseWheelDown
 
 	Pwd
 	Chdir
+	Set
+	Map
+	Unmap
+	Keys
+	Source
+	Highlight
+	Colorscheme
 	Suspend
 	Quit
 	QuitAll
@@ -0,0 +1,164 @@
+package event
+
+import (
+	"slices"
+	"strconv"
+)
+
+var typeNames = []string{
+	"Nop",
+	"Redraw",
+	"CursorUp",
+	"CursorDown",
+	"CursorLeft",
+	"CursorRight",
+	"CursorPrev",
+	"CursorNext",
+	"CursorHead",
+	"CursorEnd",
+	"CursorGoto",
+	"SetMark",
+	"ScrollUp",
+	"ScrollDown",
+	"ScrollTop",
+	"ScrollTopHead",
+	"ScrollMiddle",
+	"ScrollMiddleHead",
+	"ScrollBottom",
+	"ScrollBottomHead",
+	"PageUp",
+	"PageDown",
+	"PageUpHalf",
+	"PageDownHalf",
+	"PageTop",
+	"PageEnd",
+	"WindowTop",
+	"WindowMiddle",
+	"WindowBottom",
+	"JumpTo",
+	"JumpBack",
+	"DeleteByte",
+	"DeletePrevByte",
+	"Increment",
+	"Decrement",
+	"ShiftLeft",
+	"ShiftRight",
+	"SwitchFocus",
+	"ShowBinary",
+	"ShowDecimal",
+	"StartInsert",
+	"StartInsertHead",
+	"StartAppend",
+	"StartAppendEnd",
+	"StartReplaceByte",
+	"StartReplace",
+	"ExitInsert",
+	"Backspace",
+	"Delete",
+	"Rune",
+	"Undo",
+	"Redo",
+	"Earlier",
+	"Later",
+	"UndoList",
+	"StartVisual",
+	"SwitchVisualEnd",
+	"ExitVisual",
+	"Copy",
+	"Cut",
+	"Copied",
+	"Paste",
+	"PastePrev",
+	"Pasted",
+	"Registers",
+	"StartCmdlineCommand",
+	"StartCmdlineSearchForward",
+	"StartCmdlineSearchBackward",
+	"BackspaceCmdline",
+	"DeleteCmdline",
+	"DeleteWordCmdline",
+	"ClearToHeadCmdline",
+	"ClearCmdline",
+	"ExitCmdline",
+	"CompleteForwardCmdline",
+	"CompleteBackCmdline",
+	"ExecuteCmdline",
+	"ExecuteSearch",
+	"NextSearch",
+	"PreviousSearch",
+	"AbortSearch",
+	"Edit",
+	"View",
+	"CheckTime",
+	"Reload",
+	"ConfirmReload",
+	"Attach",
+	"Maps",
+	"Enew",
+	"New",
+	"Vnew",
+	"Only",
+	"Alternative",
+	"Wincmd",
+	"FocusWindowUp",
+	"FocusWindowDown",
+	"FocusWindowLeft",
+	"FocusWindowRight",
+	"FocusWindowTopLeft",
+	"FocusWindowBottomRight",
+	"FocusWindowPrevious",
+	"MoveWindowTop",
+	"MoveWindowBottom",
+	"MoveWindowLeft",
+	"MoveWindowRight",
+	"IncreaseWindowHeight",
+	"DecreaseWindowHeight",
+	"IncreaseWindowWidth",
+	"DecreaseWindowWidth",
+	"EqualizeWindows",
+	"Resize",
+	"VerticalResize",
+	"TabNew",
+	"TabNext",
+	"TabPrevious",
+	"TabClose",
+	"Tabs",
+	"MousePress",
+	"MouseDrag",
+	"MouseRelease",
+	"MouseWheelUp",
+	"MouseWheelDown",
+	"Pwd",
+	"Chdir",
+	"Set",
+	"Map",
+	"Unmap",
+	"Keys",
+	"Source",
+	"Highlight",
+	"Colorscheme",
+	"Suspend",
+	"Quit",
+	"QuitAll",
+	"QuitErr",
+	"Write",
+	"WriteQuit",
+	"Info",
+	"Error",
+}
+
+// String returns the name of the event type.
+func (t Type) String() string {
+	if 0 <= t && int(t) < len(typeNames) {
+		return typeNames[t]
+	}
+	return "Type(" + strconv.Itoa(int(t)) + ")"
+}
+
+// ParseType returns the event type of the name, like "CursorDown".
+func ParseType(name string) (Type, bool) {
+	if i := slices.Index(typeNames, name); i > 0 {
+		return Type(i), true
+	}
+	return Nop, false
+}
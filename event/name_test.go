@@ -0,0 +1,55 @@
+package event
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestTypeString(t *testing.T) {
+	if expected := "Error"; Error.String() != expected {
+		t.Errorf("Error.String() should be %q but got %q", expected, Error.String())
+	}
+	if expected := "Type(-1)"; Type(-1).String() != expected {
+		t.Errorf("Type(-1).String() should be %q but got %q", expected, Type(-1).String())
+	}
+	if _, ok := ParseType("Nop"); ok {
+		t.Errorf("ParseType should not parse Nop")
+	}
+	if _, ok := ParseType("Unknown"); ok {
+		t.Errorf("ParseType should not parse unknown name")
+	}
+}
+
+// TestTypeNames checks that the names are in sync with the constants
+// of the event types declared in event.go.
+func TestTypeNames(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "event.go", nil, 0)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	var names []string
+	for _, decl := range f.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.CONST {
+			for _, spec := range decl.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+	}
+	if len(names) != int(Error)+1 || len(typeNames) != len(names) {
+		t.Fatalf("number of names should be %d but got %d (constants: %d)",
+			int(Error)+1, len(typeNames), len(names))
+	}
+	for i, name := range names {
+		typ := Type(i)
+		if typ.String() != name {
+			t.Errorf("Type(%d).String() should be %q but got %q", i, name, typ.String())
+		}
+		if got, ok := ParseType(name); typ != Nop && (!ok || got != typ) {
+			t.Errorf("ParseType(%q) should be %d but got %d", name, typ, got)
+		}
+	}
+}
@@ -79,11 +79,13 @@ func parseNum(src string) (int64, string) {
 	offset, radix, ishex := int64(0), int64(10), false
 	if src, ishex = strings.CutPrefix(src, "0x"); ishex {
 		radix = 16
+	} else if rest, ok := strings.CutPrefix(src, "0o"); ok {
+		src, radix = rest, 8
 	}
 	for src != "" {
 		c := src[0]
 		switch {
-		case '0' <= c && c <= '9':
+		case '0' <= c && c <= '9' && int64(c-'0') < radix:
 			offset = offset*radix + int64(c-'0')
 		case ('A' <= c && c <= 'F' || 'a' <= c && c <= 'f') && ishex:
 			offset = offset*radix + int64(c|('a'-'A')-'a'+10)
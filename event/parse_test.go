@@ -24,6 +24,7 @@ func TestParseRange(t *testing.T) {
 		{"10d", &Range{Absolute{10}, nil}, "d"},
 		{"0x12G", &Range{Absolute{0x12}, nil}, "G"},
 		{"0x10fag", &Range{Absolute{0x10fa}, nil}, "g"},
+		{"0o17,0o1089", &Range{Absolute{0o17}, Absolute{0o10}}, "89"},
 		{".-100,.+100", &Range{Relative{-100}, Relative{100}}, ""},
 		{"'", nil, "'"},
 		{"' ", nil, "' "},
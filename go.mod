@@ -4,8 +4,11 @@ go 1.23
 
 require (
 	github.com/gdamore/tcell v1.4.0
+	github.com/klauspost/compress v1.18.0
 	github.com/mattn/go-runewidth v0.0.16
+	github.com/ulikunitz/xz v0.5.15
 	golang.org/x/term v0.26.0
+	golang.org/x/text v0.20.0
 )
 
 require (
@@ -13,5 +16,4 @@ require (
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/sys v0.27.0 // indirect
-	golang.org/x/text v0.20.0 // indirect
 )
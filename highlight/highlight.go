@@ -0,0 +1,289 @@
+// Package highlight defines the highlight groups, the styles of the parts of
+// the user interface which can be configured by :highlight and :colorscheme.
+package highlight
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// Highlight group names
+const (
+	Offset         = "Offset"
+	CursorOffset   = "CursorOffset"
+	Header         = "Header"
+	CursorHeader   = "CursorHeader"
+	EditedByte     = "EditedByte"
+	Cursor         = "Cursor"
+	CursorInactive = "CursorInactive"
+	Visual         = "Visual"
+	Search         = "Search"
+	Unmapped       = "Unmapped"
+	ScrollBar      = "ScrollBar"
+	StatusLine     = "StatusLine"
+	VertSplit      = "VertSplit"
+	InfoMsg        = "InfoMsg"
+	ErrorMsg       = "ErrorMsg"
+	Completion     = "Completion"
+	CompletionSel  = "CompletionSel"
+	TabLine        = "TabLine"
+	TabLineSel     = "TabLineSel"
+	NullByte       = "NullByte"
+	Printable      = "Printable"
+	Whitespace     = "Whitespace"
+	Control        = "Control"
+	HighByte       = "HighByte"
+	FullByte       = "FullByte"
+)
+
+// Groups is the list of the highlight group names.
+var Groups = []string{
+	Offset, CursorOffset, Header, CursorHeader, EditedByte, Cursor, CursorInactive,
+	Visual, Search, Unmapped, ScrollBar, StatusLine, VertSplit, InfoMsg, ErrorMsg,
+	Completion, CompletionSel, TabLine, TabLineSel,
+	NullByte, Printable, Whitespace, Control, HighByte, FullByte,
+}
+
+// Highlight holds the colors and the attributes of a highlight group.
+// The colors are the names like red and #ff0000, or empty for the default.
+type Highlight struct {
+	Fg    string
+	Bg    string
+	Attrs tcell.AttrMask
+}
+
+type attr struct {
+	name string
+	attr tcell.AttrMask
+	set  func(tcell.Style, bool) tcell.Style
+}
+
+var attrs = []attr{
+	{"bold", tcell.AttrBold, tcell.Style.Bold},
+	{"dim", tcell.AttrDim, tcell.Style.Dim},
+	{"italic", tcell.AttrItalic, tcell.Style.Italic},
+	{"underline", tcell.AttrUnderline, tcell.Style.Underline},
+	{"reverse", tcell.AttrReverse, tcell.Style.Reverse},
+	{"blink", tcell.AttrBlink, tcell.Style.Blink},
+}
+
+// Style returns the style of the highlight.
+func (h Highlight) Style() tcell.Style {
+	style := tcell.StyleDefault
+	if h.Fg != "" {
+		style = style.Foreground(tcell.GetColor(h.Fg))
+	}
+	if h.Bg != "" {
+		style = style.Background(tcell.GetColor(h.Bg))
+	}
+	for _, a := range attrs {
+		if h.Attrs&a.attr != 0 {
+			style = a.set(style, true)
+		}
+	}
+	return style
+}
+
+// Merge returns the highlight overlaid with another highlight. The colors
+// of the other highlight take precedence, and the attributes are combined.
+func (h Highlight) Merge(other Highlight) Highlight {
+	if other.Fg != "" {
+		h.Fg = other.Fg
+	}
+	if other.Bg != "" {
+		h.Bg = other.Bg
+	}
+	h.Attrs |= other.Attrs
+	return h
+}
+
+// String returns the highlight in the arguments of :highlight.
+func (h Highlight) String() string {
+	var args []string
+	if h.Fg != "" {
+		args = append(args, "fg="+h.Fg)
+	}
+	if h.Bg != "" {
+		args = append(args, "bg="+h.Bg)
+	}
+	if h.Attrs != tcell.AttrNone {
+		var names []string
+		for _, a := range attrs {
+			if h.Attrs&a.attr != 0 {
+				names = append(names, a.name)
+			}
+		}
+		args = append(args, "attr="+strings.Join(names, ","))
+	}
+	if len(args) == 0 {
+		return "cleared"
+	}
+	return strings.Join(args, " ")
+}
+
+// Highlights maps the highlight group names to the highlights.
+type Highlights map[string]Highlight
+
+// Default returns the default highlights.
+func Default() Highlights {
+	return Highlights{
+		CursorOffset:   {Attrs: tcell.AttrBold},
+		Header:         {Attrs: tcell.AttrUnderline},
+		CursorHeader:   {Attrs: tcell.AttrBold},
+		EditedByte:     {Fg: "lightseagreen"},
+		Cursor:         {Attrs: tcell.AttrReverse},
+		CursorInactive: {Attrs: tcell.AttrBold | tcell.AttrUnderline},
+		Visual:         {Attrs: tcell.AttrUnderline},
+		Search:         {Fg: "black", Bg: "yellow"},
+		StatusLine:     {Attrs: tcell.AttrReverse},
+		VertSplit:      {Attrs: tcell.AttrReverse},
+		InfoMsg:        {Fg: "yellow"},
+		ErrorMsg:       {Fg: "red"},
+		Completion:     {Attrs: tcell.AttrReverse},
+		CompletionSel:  {Fg: "grey", Attrs: tcell.AttrReverse},
+		TabLine:        {Attrs: tcell.AttrReverse},
+		TabLineSel:     {Attrs: tcell.AttrBold},
+		NullByte:       {Fg: "grey"},
+		Printable:      {Fg: "teal"},
+		Whitespace:     {Fg: "green"},
+		Control:        {Fg: "purple"},
+		HighByte:       {Fg: "olive"},
+		FullByte:       {Fg: "maroon"},
+	}
+}
+
+// ByteClass returns the highlight group of the byte class; NullByte for 0x00,
+// Whitespace for \t, \n, \v, \f, \r and space, Printable for the other
+// printable ASCII, Control for the other ASCII, FullByte for 0xff,
+// and HighByte for the other bytes.
+func ByteClass(b byte) string {
+	switch {
+	case b == 0x00:
+		return NullByte
+	case 0x09 <= b && b <= 0x0d || b == 0x20:
+		return Whitespace
+	case 0x20 < b && b < 0x7f:
+		return Printable
+	case b < 0x80:
+		return Control
+	case b == 0xff:
+		return FullByte
+	default:
+		return HighByte
+	}
+}
+
+// Clone returns a copy of the highlights.
+func (hs Highlights) Clone() Highlights {
+	return maps.Clone(hs)
+}
+
+// NoColor returns the highlights without the colors, for NO_COLOR.
+func (hs Highlights) NoColor() Highlights {
+	xs := make(Highlights, len(hs))
+	for name, h := range hs {
+		xs[name] = Highlight{Attrs: h.Attrs}
+	}
+	return xs
+}
+
+// Style returns the style of the highlight group.
+func (hs Highlights) Style(group string) tcell.Style {
+	return hs[group].Style()
+}
+
+// LookupGroup returns the highlight group name, which is case-insensitive.
+func LookupGroup(name string) (string, error) {
+	for _, group := range Groups {
+		if strings.EqualFold(group, name) {
+			return group, nil
+		}
+	}
+	return "", errors.New("unknown highlight group: " + name)
+}
+
+// Set parses the arguments of :highlight, like "Visual fg=black bg=#ffaf00
+// attr=bold,underline", and updates the highlight group. The colors and the
+// attributes which are not specified are kept, and "NONE" clears them.
+func (hs Highlights) Set(arg string) error {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return errors.New("highlight group is required")
+	}
+	group, err := LookupGroup(fields[0])
+	if err != nil {
+		return err
+	}
+	h := hs[group]
+	for _, field := range fields[1:] {
+		if strings.EqualFold(field, "none") {
+			h = Highlight{}
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || value == "" {
+			return errors.New("invalid highlight argument: " + field)
+		}
+		switch strings.ToLower(key) {
+		case "fg":
+			h.Fg, err = parseColor(value)
+		case "bg":
+			h.Bg, err = parseColor(value)
+		case "attr":
+			h.Attrs, err = parseAttrs(value)
+		default:
+			err = errors.New("invalid highlight argument: " + field)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	hs[group] = h
+	return nil
+}
+
+func parseColor(value string) (string, error) {
+	value = strings.ToLower(value)
+	if value == "none" {
+		return "", nil
+	}
+	if tcell.GetColor(value) == tcell.ColorDefault {
+		return "", errors.New("invalid color: " + value)
+	}
+	return value, nil
+}
+
+func parseAttrs(value string) (tcell.AttrMask, error) {
+	var mask tcell.AttrMask
+	for _, name := range strings.Split(strings.ToLower(value), ",") {
+		if name == "none" {
+			continue
+		}
+		i := slices.IndexFunc(attrs, func(a attr) bool { return a.name == name })
+		if i < 0 {
+			return 0, errors.New("invalid attribute: " + name)
+		}
+		mask |= attrs[i].attr
+	}
+	return mask, nil
+}
+
+// Format returns the list of the highlight groups for :highlight.
+func (hs Highlights) Format(groups ...string) string {
+	if len(groups) == 0 {
+		groups = Groups
+	}
+	var sb strings.Builder
+	for i, group := range groups {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		fmt.Fprintf(&sb, "%-15s %s", group, hs[group])
+	}
+	return sb.String()
+}
@@ -0,0 +1,118 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+)
+
+func TestHighlightsSet(t *testing.T) {
+	testCases := []struct {
+		arg      string
+		group    string
+		expected Highlight
+		err      string
+	}{
+		{
+			arg:      "Visual fg=Black bg=#FFAF00 attr=bold,underline",
+			group:    Visual,
+			expected: Highlight{"black", "#ffaf00", tcell.AttrBold | tcell.AttrUnderline},
+		},
+		{
+			arg:      "editedbyte bg=navy",
+			group:    EditedByte,
+			expected: Highlight{"lightseagreen", "navy", tcell.AttrNone},
+		},
+		{
+			arg:      "Cursor attr=NONE fg=red",
+			group:    Cursor,
+			expected: Highlight{Fg: "red"},
+		},
+		{
+			arg:      "StatusLine NONE attr=dim",
+			group:    StatusLine,
+			expected: Highlight{Attrs: tcell.AttrDim},
+		},
+		{
+			arg:      "InfoMsg fg=none",
+			group:    InfoMsg,
+			expected: Highlight{},
+		},
+		{
+			arg: "",
+			err: "highlight group is required",
+		},
+		{
+			arg: "Foo fg=red",
+			err: "unknown highlight group: Foo",
+		},
+		{
+			arg: "Visual fg=foo",
+			err: "invalid color: foo",
+		},
+		{
+			arg: "Visual attr=bold,strike",
+			err: "invalid attribute: strike",
+		},
+		{
+			arg: "Visual fg",
+			err: "invalid highlight argument: fg",
+		},
+		{
+			arg: "Visual font=mono",
+			err: "invalid highlight argument: font=mono",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.arg, func(t *testing.T) {
+			hs := Default()
+			err := hs.Set(tc.arg)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("err should be %q but got: %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err should be nil but got: %v", err)
+			}
+			if got := hs[tc.group]; got != tc.expected {
+				t.Errorf("highlight should be %+v but got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestHighlightStyle(t *testing.T) {
+	h := Highlight{"red", "#102030", tcell.AttrReverse}
+	fg, bg, attrs := h.Merge(Highlight{Fg: "blue", Attrs: tcell.AttrBold}).Style().Decompose()
+	if fg != tcell.ColorBlue || bg != tcell.NewHexColor(0x102030) ||
+		attrs != tcell.AttrReverse|tcell.AttrBold {
+		t.Errorf("style should be blue, #102030, reverse and bold but got %v, %v, %v", fg, bg, attrs)
+	}
+	fg, bg, attrs = Default().NoColor().Style(CompletionSel).Decompose()
+	if fg != tcell.ColorDefault || bg != tcell.ColorDefault || attrs != tcell.AttrReverse {
+		t.Errorf("style should be reverse without colors but got %v, %v, %v", fg, bg, attrs)
+	}
+}
+
+func TestHighlightsFormat(t *testing.T) {
+	hs := Default()
+	if expected := "Offset          cleared\n" +
+		"CursorOffset    attr=bold\n" +
+		"CompletionSel   fg=grey attr=reverse"; hs.Format(Offset, CursorOffset, CompletionSel) != expected {
+		t.Errorf("format should be %q but got %q", expected, hs.Format(Offset, CursorOffset, CompletionSel))
+	}
+}
+
+func TestByteClass(t *testing.T) {
+	for b, expected := range map[byte]string{
+		0x00: NullByte, 0x01: Control, 0x09: Whitespace, 0x0a: Whitespace, 0x0d: Whitespace,
+		0x1f: Control, 0x20: Whitespace, 0x21: Printable, 0x41: Printable, 0x7e: Printable,
+		0x7f: Control, 0x80: HighByte, 0xfe: HighByte, 0xff: FullByte,
+	} {
+		if got := ByteClass(b); got != expected {
+			t.Errorf("ByteClass(0x%02x) should be %s but got %s", b, expected, got)
+		}
+	}
+}
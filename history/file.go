@@ -0,0 +1,122 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/itchyny/bed/buffer"
+)
+
+type historyFile struct {
+	Index   int                `json:"index"`
+	Buffer  []buffer.Segment   `json:"buffer"`
+	Entries []historyFileEntry `json:"entries"`
+}
+
+type historyFileEntry struct {
+	Start  int64            `json:"start"`
+	Old    []buffer.Segment `json:"old"`
+	New    []buffer.Segment `json:"new"`
+	Whole  bool             `json:"whole,omitempty"`
+	Offset int64            `json:"offset"`
+	Cursor int64            `json:"cursor"`
+	Tick   uint64           `json:"tick"`
+	Time   time.Time        `json:"time"`
+	Parent int              `json:"parent"`
+	Child  int              `json:"child"`
+}
+
+// Save the history to the writer. The buffers are stored as the differences
+// from the base buffer, which should be the contents of the saved file.
+func (h *History) Save(w io.Writer, base *buffer.Buffer) error {
+	if h.index < 0 {
+		return errors.New("empty history")
+	}
+	segments, err := h.buffer.Segments(base)
+	if err != nil {
+		return err
+	}
+	f := historyFile{h.index, segments, make([]historyFileEntry, len(h.entries))}
+	for i, e := range h.entries {
+		f.Entries[i] = historyFileEntry{
+			Offset: e.offset, Cursor: e.cursor, Tick: e.tick,
+			Time: e.time, Parent: e.parent, Child: e.child,
+		}
+		if e.delta != nil {
+			f.Entries[i].Start, f.Entries[i].Whole = e.delta.start, e.delta.whole
+			if f.Entries[i].Old, err = e.delta.old.Segments(base); err != nil {
+				return err
+			}
+			if f.Entries[i].New, err = e.delta.new.Segments(base); err != nil {
+				return err
+			}
+		}
+	}
+	return json.NewEncoder(w).Encode(f)
+}
+
+// Load the history from the reader. The buffers are restored
+// from the differences to the base reader.
+func Load(r io.Reader, base interface {
+	io.ReaderAt
+	io.Seeker
+}) (*History, error) {
+	var f historyFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	if f.Index < 0 || len(f.Entries) <= f.Index {
+		return nil, errors.New("invalid history index")
+	}
+	h := &History{
+		entries: make([]*historyEntry, len(f.Entries)), index: f.Index,
+		buffer: buffer.NewBufferFromSegments(base, f.Buffer),
+	}
+	for i, e := range f.Entries {
+		if e.Parent < -1 || i <= e.Parent || (i == 0) != (e.Parent < 0) ||
+			e.Child < -1 || len(f.Entries) <= e.Child {
+			return nil, errors.New("invalid history entry")
+		}
+		var d *delta
+		if e.Parent >= 0 {
+			d = &delta{
+				e.Start,
+				buffer.NewBufferFromSegments(base, e.Old), segmentsLen(e.Old),
+				buffer.NewBufferFromSegments(base, e.New), segmentsLen(e.New),
+				e.Whole,
+			}
+		}
+		h.entries[i] = &historyEntry{
+			d, e.Offset, e.Cursor, e.Tick, e.Time, e.Parent, e.Child,
+		}
+	}
+	return h, nil
+}
+
+func segmentsLen(segments []buffer.Segment) int64 {
+	var l int64
+	for _, s := range segments {
+		l += s.Length
+	}
+	return l
+}
+
+// Current returns the current buffer and its tick.
+func (h *History) Current() (*buffer.Buffer, int64, int64, uint64) {
+	if h.index < 0 {
+		return nil, 0, 0, 0
+	}
+	e := h.entries[h.index]
+	return h.buffer.Clone(), e.offset, e.cursor, e.tick
+}
+
+// MaxTick returns the maximum tick in the history.
+func (h *History) MaxTick() uint64 {
+	var tick uint64
+	for _, e := range h.entries {
+		tick = max(tick, e.tick)
+	}
+	return tick
+}
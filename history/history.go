@@ -1,36 +1,103 @@
 package history
 
-import "github.com/itchyny/bed/buffer"
+import (
+	"time"
 
-// History manages the buffer history.
+	"github.com/itchyny/bed/buffer"
+)
+
+// History manages the buffer history as an undo tree.
+// The entries are stored in the chronological order.
+// The history holds the buffer of the current entry, and each entry
+// holds the difference from the parent entry, so that the memory
+// consumption of each entry depends only on the size of the change.
 type History struct {
 	entries []*historyEntry
 	index   int
+	buffer  *buffer.Buffer
 }
 
 type historyEntry struct {
-	buffer *buffer.Buffer
+	delta  *delta
 	offset int64
 	cursor int64
 	tick   uint64
+	time   time.Time
+	parent int
+	child  int
+}
+
+// delta represents the difference from the parent entry.
+// The bytes of the parent in [start, start+oldLen) are replaced with new.
+// When the whole buffer is replaced, old and new hold the entire buffers.
+type delta struct {
+	start  int64
+	old    *buffer.Buffer
+	oldLen int64
+	new    *buffer.Buffer
+	newLen int64
+	whole  bool
 }
 
+var now = time.Now
+
 // NewHistory creates a new history manager.
 func NewHistory() *History {
 	return &History{index: -1}
 }
 
 // Push a new buffer to the history.
+// The new entry is added as a child of the current entry.
 func (h *History) Push(buffer *buffer.Buffer, offset, cursor int64, tick uint64) {
-	newEntry := &historyEntry{buffer.Clone(), offset, cursor, tick}
-	if len(h.entries)-1 > h.index {
-		h.index++
-		h.entries[h.index] = newEntry
-		h.entries = h.entries[:h.index+1]
-	} else {
-		h.entries = append(h.entries, newEntry)
-		h.index++
+	var d *delta
+	buffer = buffer.Clone()
+	if h.index >= 0 {
+		d = newDelta(h.buffer, buffer)
+		// The ancestors already point to the current entry.
+		h.entries[h.index].child = len(h.entries)
+	}
+	h.entries = append(h.entries, &historyEntry{
+		d, offset, cursor, tick, now(), h.index, -1,
+	})
+	h.index, h.buffer = len(h.entries)-1, buffer
+}
+
+func newDelta(b, c *buffer.Buffer) *delta {
+	start, end, otherEnd, err := b.Diff(c)
+	if err != nil {
+		return &delta{old: b.Clone(), new: c.Clone(), whole: true}
+	}
+	if start == 0 {
+		if l, _ := b.Len(); l == end {
+			if l, _ := c.Len(); l == otherEnd {
+				return &delta{old: b.Clone(), new: c.Clone(), whole: true}
+			}
+		}
+	}
+	return &delta{
+		start,
+		b.Copy(start, end), end - start,
+		c.Copy(start, otherEnd), otherEnd - start,
+		false,
+	}
+}
+
+func (d *delta) apply(b *buffer.Buffer) *buffer.Buffer {
+	if d.whole {
+		return d.new.Clone()
 	}
+	b.Cut(d.start, d.start+d.oldLen)
+	b.Paste(d.start, d.new)
+	return b
+}
+
+func (d *delta) revert(b *buffer.Buffer) *buffer.Buffer {
+	if d.whole {
+		return d.old.Clone()
+	}
+	b.Cut(d.start, d.start+d.newLen)
+	b.Paste(d.start, d.old)
+	return b
 }
 
 // Undo the history.
@@ -38,19 +105,117 @@ func (h *History) Undo() (*buffer.Buffer, int, int64, int64, uint64) {
 	if h.index < 0 {
 		return nil, h.index, 0, 0, 0
 	}
-	if h.index > 0 {
-		h.index--
+	if e := h.entries[h.index]; e.parent >= 0 {
+		h.buffer = e.delta.revert(h.buffer)
+		h.index = e.parent
 	}
 	e := h.entries[h.index]
-	return e.buffer.Clone(), h.index, e.offset, e.cursor, e.tick
+	return h.buffer.Clone(), h.index, e.offset, e.cursor, e.tick
 }
 
 // Redo the history.
+// It follows the branch most recently visited.
 func (h *History) Redo() (*buffer.Buffer, int64, int64, uint64) {
-	if h.index == len(h.entries)-1 || h.index < 0 {
+	if h.index < 0 || h.entries[h.index].child < 0 {
+		return nil, 0, 0, 0
+	}
+	h.index = h.entries[h.index].child
+	e := h.entries[h.index]
+	h.buffer = e.delta.apply(h.buffer)
+	return h.buffer.Clone(), e.offset, e.cursor, e.tick
+}
+
+// Earlier goes back to the chronologically previous state.
+func (h *History) Earlier(count int64) (*buffer.Buffer, int64, int64, uint64) {
+	return h.moveTo(max(h.index-int(min(count, int64(h.index))), 0))
+}
+
+// Later goes to the chronologically next state.
+func (h *History) Later(count int64) (*buffer.Buffer, int64, int64, uint64) {
+	return h.moveTo(h.index + int(min(count, int64(len(h.entries)-1-h.index))))
+}
+
+// EarlierTime goes back to the state before the duration.
+func (h *History) EarlierTime(d time.Duration) (*buffer.Buffer, int64, int64, uint64) {
+	if h.index < 0 {
+		return nil, 0, 0, 0
+	}
+	t, index := h.entries[h.index].time.Add(-d), 0
+	for i := h.index; i >= 0; i-- {
+		if !h.entries[i].time.After(t) {
+			index = i
+			break
+		}
+	}
+	return h.moveTo(index)
+}
+
+// LaterTime goes to the state after the duration.
+func (h *History) LaterTime(d time.Duration) (*buffer.Buffer, int64, int64, uint64) {
+	if h.index < 0 {
 		return nil, 0, 0, 0
 	}
-	h.index++
+	t, index := h.entries[h.index].time.Add(d), h.index
+	for i := h.index + 1; i < len(h.entries); i++ {
+		if h.entries[i].time.After(t) {
+			break
+		}
+		index = i
+	}
+	return h.moveTo(index)
+}
+
+// moveTo moves to the entry through the common ancestor.
+func (h *History) moveTo(index int) (*buffer.Buffer, int64, int64, uint64) {
+	if index < 0 || index >= len(h.entries) || index == h.index {
+		return nil, 0, 0, 0
+	}
+	var path []int
+	for i, j := h.index, index; i != j; {
+		// The parent index is always smaller than the child index.
+		if i > j {
+			h.buffer = h.entries[i].delta.revert(h.buffer)
+			i = h.entries[i].parent
+		} else {
+			path = append(path, j)
+			j = h.entries[j].parent
+		}
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		h.buffer = h.entries[path[i]].delta.apply(h.buffer)
+	}
+	h.index = index
+	h.updateChild(index)
 	e := h.entries[h.index]
-	return e.buffer.Clone(), e.offset, e.cursor, e.tick
+	return h.buffer.Clone(), e.offset, e.cursor, e.tick
+}
+
+// updateChild updates the branches to redo.
+func (h *History) updateChild(index int) {
+	for i := index; h.entries[i].parent >= 0; i = h.entries[i].parent {
+		h.entries[h.entries[i].parent].child = i
+	}
+}
+
+// Leaf represents a leaf entry of the undo tree.
+type Leaf struct {
+	Number  int
+	Changes int
+	Time    time.Time
+}
+
+// Leaves returns the leaf entries of the undo tree.
+func (h *History) Leaves() []Leaf {
+	var leaves []Leaf
+	for i, e := range h.entries {
+		if e.child >= 0 {
+			continue
+		}
+		var changes int
+		for j := e.parent; j >= 0; j = h.entries[j].parent {
+			changes++
+		}
+		leaves = append(leaves, Leaf{i, changes, e.time})
+	}
+	return leaves
 }
@@ -1,8 +1,12 @@
 package history
 
 import (
+	"bytes"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/itchyny/bed/buffer"
 )
@@ -97,3 +101,171 @@ func TestHistoryUndo(t *testing.T) {
 		t.Errorf("history.Redo should return tick 0 but got %d", tick)
 	}
 }
+
+func TestHistorySaveLoad(t *testing.T) {
+	history := NewHistory()
+	b := buffer.NewBuffer(strings.NewReader("0123456789"))
+	history.Push(b, 0, 0, 0)
+	b.Replace(3, 0x41)
+	history.Push(b, 0, 3, 1)
+	b.Insert(5, 0x42)
+	history.Push(b, 0, 5, 2)
+	history.Undo()
+
+	var buf strings.Builder
+	current, _, _, _ := history.Current()
+	if err := history.Save(&buf, current); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	history, err := Load(strings.NewReader(buf.String()), strings.NewReader("012A456789"))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if tick := history.MaxTick(); tick != 2 {
+		t.Errorf("history.MaxTick should return 2 but got %d", tick)
+	}
+	for _, expected := range []struct {
+		str    string
+		cursor int64
+		tick   uint64
+	}{
+		{"012A456789", 3, 1},
+		{"0123456789", 0, 0},
+		{"012A4B56789", 5, 2},
+	} {
+		b, _, cursor, tick := history.Current()
+		p := make([]byte, 16)
+		n, _ := b.ReadAt(p, 0)
+		if string(p[:n]) != expected.str {
+			t.Errorf("buffer should be %q but got %q", expected.str, string(p[:n]))
+		}
+		if cursor != expected.cursor {
+			t.Errorf("cursor should be %d but got %d", expected.cursor, cursor)
+		}
+		if tick != expected.tick {
+			t.Errorf("tick should be %d but got %d", expected.tick, tick)
+		}
+		if expected.tick == 1 {
+			history.Undo()
+		} else {
+			history.Redo()
+			history.Redo()
+		}
+	}
+}
+
+func TestHistoryTree(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	history := NewHistory()
+	read := func(b *buffer.Buffer) string {
+		p := make([]byte, 8)
+		n, _ := b.ReadAt(p, 0)
+		return string(p[:n])
+	}
+	for i, str := range []string{"", "a", "ab", "abc"} {
+		now = func() time.Time { return base.Add(time.Duration(i) * time.Minute) }
+		history.Push(buffer.NewBuffer(strings.NewReader(str)), 0, 0, uint64(i))
+	}
+	history.Undo()
+	history.Undo()
+	now = func() time.Time { return base.Add(10 * time.Minute) }
+	history.Push(buffer.NewBuffer(strings.NewReader("ax")), 0, 0, 4)
+
+	if b, _, _, _ := history.Redo(); b != nil {
+		t.Errorf("history.Redo should return nil buffer but got %q", read(b))
+	}
+	for _, expected := range []string{"abc", "ab", "a", ""} {
+		b, _, _, _ := history.Earlier(1)
+		if b == nil || read(b) != expected {
+			t.Fatalf("history.Earlier should return %q but got %v", expected, b)
+		}
+	}
+	if b, _, _, _ := history.Earlier(1); b != nil {
+		t.Errorf("history.Earlier should return nil buffer but got %q", read(b))
+	}
+	if b, _, _, _ := history.Later(2); b == nil || read(b) != "ab" {
+		t.Errorf("history.Later should return %q but got %v", "ab", b)
+	}
+	if b, _, _, _ := history.Redo(); b == nil || read(b) != "abc" {
+		t.Errorf("history.Redo should return %q but got %v", "abc", b)
+	}
+	if b, _, _, _ := history.LaterTime(5 * time.Minute); b != nil {
+		t.Errorf("history.LaterTime should return nil buffer but got %q", read(b))
+	}
+	if b, _, _, _ := history.EarlierTime(90 * time.Second); b == nil || read(b) != "a" {
+		t.Errorf("history.EarlierTime should return %q but got %v", "a", b)
+	}
+	if b, _, _, _ := history.LaterTime(time.Hour); b == nil || read(b) != "ax" {
+		t.Errorf("history.LaterTime should return %q but got %v", "ax", b)
+	}
+	if b, _, _, _, _ := history.Undo(); b == nil || read(b) != "a" {
+		t.Errorf("history.Undo should return %q but got %v", "a", b)
+	}
+	if b, _, _, _ := history.Redo(); b == nil || read(b) != "ax" {
+		t.Errorf("history.Redo should return %q but got %v", "ax", b)
+	}
+
+	expected := []Leaf{
+		{Number: 3, Changes: 3, Time: base.Add(3 * time.Minute)},
+		{Number: 4, Changes: 2, Time: base.Add(10 * time.Minute)},
+	}
+	if leaves := history.Leaves(); !reflect.DeepEqual(leaves, expected) {
+		t.Errorf("history.Leaves should return %v but got %v", expected, leaves)
+	}
+}
+
+func BenchmarkHistoryPush(b *testing.B) {
+	buf := buffer.NewBuffer(bytes.NewReader(make([]byte, 1<<20)))
+	history := NewHistory()
+	history.Push(buf, 0, 0, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		offset := int64(i%256) * 4096
+		buf.Replace(offset, byte(i))
+		history.Push(buf, 0, offset, uint64(i+1))
+	}
+}
+
+// BenchmarkHistoryEdits reports the memory retained by the history of
+// a fixed number of edits, which does not depend on b.N.
+func BenchmarkHistoryEdits(b *testing.B) {
+	const edits = 100000
+	var heap int64
+	for range b.N {
+		buf := buffer.NewBuffer(bytes.NewReader(make([]byte, 1<<20)))
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		history := NewHistory()
+		history.Push(buf, 0, 0, 0)
+		for i := range edits {
+			offset := int64(i%256) * 4096
+			buf.Replace(offset, byte(i))
+			history.Push(buf, 0, offset, uint64(i+1))
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		heap += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+		runtime.KeepAlive(history)
+	}
+	b.ReportMetric(float64(heap)/float64(b.N)/edits, "heap-B/edit")
+}
+
+func BenchmarkHistoryUndoRedo(b *testing.B) {
+	buf := buffer.NewBuffer(bytes.NewReader(make([]byte, 1<<20)))
+	history := NewHistory()
+	history.Push(buf, 0, 0, 0)
+	for i := range 1000 {
+		offset := int64(i) * 1024
+		buf.Replace(offset, byte(i))
+		history.Push(buf, 0, offset, uint64(i+1))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		history.Undo()
+		history.Redo()
+	}
+}
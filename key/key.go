@@ -1,7 +1,10 @@
 package key
 
 import (
+	"slices"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/itchyny/bed/event"
 )
@@ -10,14 +13,17 @@ import (
 type Key string
 
 type keyEvent struct {
-	keys  []Key
-	event event.Type
-	bang  bool
+	keys    []Key
+	event   event.Type
+	bang    bool
+	rhs     string
+	noremap bool
 }
 
 const (
 	keysEq = iota
 	keysPending
+	keysMapPending
 	keysNeq
 )
 
@@ -36,56 +42,265 @@ func (ke keyEvent) cmp(ks []Key) int {
 	return keysEq
 }
 
+// Mapping represents a user-defined key mapping.
+type Mapping struct {
+	Keys    []Key
+	Event   event.Type
+	RHS     string
+	Noremap bool
+}
+
 // Manager holds the key mappings and current key sequence.
 type Manager struct {
-	keys   []Key
-	events []keyEvent
-	count  bool
+	keys     []Key
+	events   []keyEvent
+	mappings []keyEvent
+	count    bool
+	remap    bool
+	timeout  time.Duration
+	handler  func(event.Event)
+	timer    *time.Timer
+	mu       *sync.Mutex
 }
 
 // NewManager creates a new Manager.
 func NewManager(count bool) *Manager {
-	return &Manager{count: count}
+	return &Manager{count: count, timeout: time.Second, mu: new(sync.Mutex)}
 }
 
 // Register adds a new key mapping.
 func (km *Manager) Register(eventType event.Type, keys ...Key) {
-	km.events = append(km.events, keyEvent{keys, eventType, false})
+	km.events = append(km.events, keyEvent{keys: keys, event: eventType})
 }
 
 // RegisterBang adds a new key mapping with bang.
 func (km *Manager) RegisterBang(eventType event.Type, keys ...Key) {
-	km.events = append(km.events, keyEvent{keys, eventType, true})
+	km.events = append(km.events, keyEvent{keys: keys, event: eventType, bang: true})
+}
+
+// Map adds a user key mapping to the event, which takes precedence over the
+// default key mappings.
+func (km *Manager) Map(keys []Key, eventType event.Type) {
+	km.addMapping(keyEvent{keys: keys, event: eventType})
+}
+
+// MapKeys adds a user key mapping to the key sequence. The key sequence is
+// emitted by [event.Keys], and the user key mappings are applied to the key
+// sequence again unless noremap is set.
+func (km *Manager) MapKeys(keys []Key, rhs string, noremap bool) {
+	km.addMapping(keyEvent{keys: keys, event: event.Keys, rhs: rhs, noremap: noremap})
+}
+
+func (km *Manager) addMapping(ke keyEvent) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	if i := km.indexMapping(ke.keys); i >= 0 {
+		km.mappings[i] = ke
+	} else {
+		km.mappings = append(km.mappings, ke)
+	}
+}
+
+func (km *Manager) indexMapping(keys []Key) int {
+	return slices.IndexFunc(km.mappings, func(ke keyEvent) bool {
+		return slices.Equal(ke.keys, keys)
+	})
+}
+
+// Unmap removes the user key mapping. It reports false if not mapped.
+func (km *Manager) Unmap(keys []Key) bool {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	i := km.indexMapping(keys)
+	if i < 0 {
+		return false
+	}
+	km.mappings = slices.Delete(km.mappings, i, i+1)
+	return true
 }
 
-// Press checks the new key down event.
-func (km *Manager) Press(k Key) event.Event {
-	km.keys = append(km.keys, k)
+// Mappings returns the user key mappings.
+func (km *Manager) Mappings() []Mapping {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	mappings := make([]Mapping, len(km.mappings))
+	for i, ke := range km.mappings {
+		mappings[i] = Mapping{ke.keys, ke.event, ke.rhs, ke.noremap}
+	}
+	return mappings
+}
+
+// SetTimeout sets the time to wait for the next key
+// when the key sequence is a prefix of a user key mapping.
+func (km *Manager) SetTimeout(timeout time.Duration) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.timeout = timeout
+}
+
+// SetTimeoutHandler sets the handler of the events emitted on timeout.
+func (km *Manager) SetTimeoutHandler(handler func(event.Event)) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.handler = handler
+}
+
+// Press checks the new key down event, applying the user key mappings.
+// The key sequence can be prefixed with a count and a register ("x).
+// The keys which do not match any mapping are emitted as [event.Rune].
+// It emits nothing while the key sequence is pending. When the key sequence
+// is a prefix of a user key mapping, or a mapping is a prefix of another,
+// the pending keys are resolved on timeout.
+func (km *Manager) Press(k Key) []event.Event {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	return km.press(k, true)
+}
+
+// PressNoremap checks the new key down event like Press,
+// ignoring the user key mappings.
+func (km *Manager) PressNoremap(k Key) []event.Event {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	return km.press(k, false)
+}
+
+func (km *Manager) press(k Key, remap bool) []event.Event {
+	if km.timer != nil {
+		km.timer.Stop()
+		km.timer = nil
+	}
+	km.keys, km.remap = append(km.keys, k), remap
 	for i := range len(km.keys) {
-		keys := km.keys[i:]
-		var count int64
-		if km.count {
-			numStr := ""
-			for j, k := range keys {
-				if len(k) == 1 && ('1' <= k[0] && k[0] <= '9' || k[0] == '0' && j > 0) {
-					numStr += string(k)
-				} else {
-					break
-				}
+		e, state := km.match(km.keys[i:], remap)
+		switch state {
+		case keysPending, keysMapPending:
+			events := km.resolve(km.keys[:i], remap)
+			km.keys = km.keys[i:]
+			if state == keysMapPending {
+				km.startTimer()
+			}
+			return events
+		case keysEq:
+			events := append(km.resolve(km.keys[:i], remap), e)
+			km.keys = nil
+			return events
+		}
+	}
+	events := km.resolve(km.keys, remap)
+	km.keys = nil
+	return events
+}
+
+// match the key sequence against the mappings. The event is returned along
+// with keysMapPending when the key sequence is ambiguous.
+func (km *Manager) match(keys []Key, remap bool) (event.Event, int) {
+	var count int64
+	var register rune
+	if km.count {
+		keys, count = parseCount(keys)
+		if len(keys) > 0 && keys[0] == "\"" {
+			if len(keys) == 1 {
+				return event.Event{}, keysPending
+			}
+			rs := []rune(string(keys[1]))
+			if len(rs) != 1 {
+				return event.Event{}, keysNeq
+			}
+			register = rs[0]
+			var cnt int64
+			if keys, cnt = parseCount(keys[2:]); cnt > 0 {
+				count = max(count, 1) * cnt
 			}
-			keys = keys[len(numStr):]
-			count, _ = strconv.ParseInt(numStr, 10, 64)
 		}
-		for _, ke := range km.events {
+	}
+	if len(keys) == 0 {
+		return event.Event{}, keysPending
+	}
+	var e *event.Event
+	var pending, mapPending bool
+	for i, kes := range [][]keyEvent{km.mappings, km.events} {
+		if i == 0 && !remap {
+			continue
+		}
+		for _, ke := range kes {
 			switch ke.cmp(keys) {
 			case keysPending:
-				return event.Event{Type: event.Nop}
+				pending, mapPending = true, mapPending || i == 0
 			case keysEq:
-				km.keys = nil
-				return event.Event{Type: ke.event, Count: count, Bang: ke.bang}
+				if e == nil {
+					e = &event.Event{Type: ke.event, Count: count, Register: register,
+						Bang: ke.bang || ke.noremap, Arg: ke.rhs}
+				}
 			}
 		}
 	}
-	km.keys = nil
-	return event.Event{Type: event.Nop}
+	switch {
+	case pending && e != nil:
+		return *e, keysMapPending
+	case mapPending:
+		return event.Event{}, keysMapPending
+	case pending:
+		return event.Event{}, keysPending
+	case e != nil:
+		return *e, keysEq
+	default:
+		return event.Event{}, keysNeq
+	}
+}
+
+// resolve the key sequence which cannot be extended anymore,
+// matching the longest mappings from the head.
+func (km *Manager) resolve(keys []Key, remap bool) []event.Event {
+	var events []event.Event
+	for len(keys) > 0 {
+		n := len(keys)
+		for ; n > 0; n-- {
+			if e, _ := km.match(keys[:n], remap); e.Type != event.Nop {
+				events = append(events, e)
+				break
+			}
+		}
+		if n == 0 {
+			events = append(events, event.Event{Type: event.Rune, Rune: keys[0].Rune()})
+			n = 1
+		}
+		keys = keys[n:]
+	}
+	return events
+}
+
+func (km *Manager) startTimer() {
+	if km.timeout <= 0 || km.handler == nil {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(km.timeout, func() {
+		km.mu.Lock()
+		if km.timer != timer {
+			km.mu.Unlock()
+			return
+		}
+		events := km.resolve(km.keys, km.remap)
+		km.keys, km.timer = nil, nil
+		handler := km.handler
+		km.mu.Unlock()
+		for _, e := range events {
+			handler(e)
+		}
+	})
+	km.timer = timer
+}
+
+func parseCount(keys []Key) ([]Key, int64) {
+	numStr := ""
+	for j, k := range keys {
+		if len(k) == 1 && ('1' <= k[0] && k[0] <= '9' || k[0] == '0' && j > 0) {
+			numStr += string(k)
+		} else {
+			break
+		}
+	}
+	count, _ := strconv.ParseInt(numStr, 10, 64)
+	return keys[len(numStr):], count
 }
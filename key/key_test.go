@@ -1,21 +1,31 @@
 package key
 
 import (
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/itchyny/bed/event"
 )
 
+func eventTypes(events []event.Event) []event.Type {
+	types := make([]event.Type, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+	return types
+}
+
 func TestKeyManagerPress(t *testing.T) {
 	km := NewManager(true)
 	km.Register(event.CursorUp, "k")
-	e := km.Press("k")
-	if e.Type != event.CursorUp {
-		t.Errorf("pressing k should emit event.CursorUp but got: %d", e.Type)
+	es := km.Press("k")
+	if len(es) != 1 || es[0].Type != event.CursorUp {
+		t.Errorf("pressing k should emit event.CursorUp but got: %v", eventTypes(es))
 	}
-	e = km.Press("j")
-	if e.Type != event.Nop {
-		t.Errorf("pressing j should be nop but got: %d", e.Type)
+	es = km.Press("j")
+	if len(es) != 1 || es[0].Type != event.Rune || es[0].Rune != 'j' {
+		t.Errorf("pressing j should emit event.Rune but got: %v", eventTypes(es))
 	}
 }
 
@@ -24,48 +34,209 @@ func TestKeyManagerPressMulti(t *testing.T) {
 	km.Register(event.CursorUp, "k", "k", "j")
 	km.Register(event.CursorDown, "k", "j", "j")
 	km.Register(event.CursorDown, "j", "k", "k")
-	e := km.Press("k")
-	if e.Type != event.Nop {
-		t.Errorf("pressing k should be nop but got: %d", e.Type)
+	es := km.Press("k")
+	if len(es) != 0 {
+		t.Errorf("pressing k should be nop but got: %v", eventTypes(es))
 	}
-	e = km.Press("k")
-	if e.Type != event.Nop {
-		t.Errorf("pressing k twice should be nop but got: %d", e.Type)
+	es = km.Press("k")
+	if len(es) != 0 {
+		t.Errorf("pressing k twice should be nop but got: %v", eventTypes(es))
 	}
-	e = km.Press("k")
-	if e.Type != event.Nop {
-		t.Errorf("pressing k three times should be nop but got: %d", e.Type)
+	es = km.Press("k")
+	if expected := []event.Type{event.Rune}; !slices.Equal(eventTypes(es), expected) {
+		t.Errorf("pressing k three times should emit %v but got: %v", expected, eventTypes(es))
 	}
-	e = km.Press("j")
-	if e.Type != event.CursorUp {
-		t.Errorf("pressing kkj should emit event.CursorUp but got: %d", e.Type)
+	es = km.Press("j")
+	if len(es) != 1 || es[0].Type != event.CursorUp {
+		t.Errorf("pressing kkj should emit event.CursorUp but got: %v", eventTypes(es))
 	}
 }
 
 func TestKeyManagerPressCount(t *testing.T) {
 	km := NewManager(true)
 	km.Register(event.CursorUp, "k", "j")
-	e := km.Press("k")
-	if e.Type != event.Nop {
-		t.Errorf("pressing k should be nop but got: %d", e.Type)
+	es := km.Press("k")
+	if len(es) != 0 {
+		t.Errorf("pressing k should be nop but got: %v", eventTypes(es))
+	}
+	es = km.Press("3")
+	if expected := []event.Type{event.Rune}; !slices.Equal(eventTypes(es), expected) {
+		t.Errorf("pressing 3 should emit %v but got: %v", expected, eventTypes(es))
+	}
+	es = km.Press("7")
+	if len(es) != 0 {
+		t.Errorf("pressing 7 should be nop but got: %v", eventTypes(es))
+	}
+	es = km.Press("k")
+	if len(es) != 0 {
+		t.Errorf("pressing k should be nop but got: %v", eventTypes(es))
+	}
+	es = km.Press("j")
+	if len(es) != 1 || es[0].Type != event.CursorUp {
+		t.Fatalf("pressing 37kj should emit event.CursorUp but got: %v", eventTypes(es))
+	}
+	if es[0].Count != 37 {
+		t.Errorf("pressing 37kj should emit event.CursorUp with count 37 but got: %d", es[0].Count)
+	}
+}
+
+func TestKeyManagerPressRegister(t *testing.T) {
+	km := NewManager(true)
+	km.Register(event.Paste, "p")
+	es := km.Press("\"")
+	if len(es) != 0 {
+		t.Errorf("pressing \" should be nop but got: %v", eventTypes(es))
+	}
+	es = km.Press("a")
+	if len(es) != 0 {
+		t.Errorf("pressing \"a should be nop but got: %v", eventTypes(es))
+	}
+	es = km.Press("p")
+	if len(es) != 1 || es[0].Type != event.Paste {
+		t.Fatalf("pressing \"ap should emit event.Paste but got: %v", eventTypes(es))
+	}
+	if es[0].Register != 'a' {
+		t.Errorf("pressing \"ap should emit event.Paste with register %q but got: %q", 'a', es[0].Register)
+	}
+	for _, k := range []Key{"2", "\"", "B", "3"} {
+		if es = km.Press(k); len(es) != 0 {
+			t.Errorf("pressing %s should be nop but got: %v", k, eventTypes(es))
+		}
+	}
+	es = km.Press("p")
+	if len(es) != 1 || es[0].Type != event.Paste {
+		t.Fatalf("pressing 2\"B3p should emit event.Paste but got: %v", eventTypes(es))
+	}
+	if es[0].Register != 'B' {
+		t.Errorf("pressing 2\"B3p should emit event.Paste with register %q but got: %q", 'B', es[0].Register)
+	}
+	if es[0].Count != 6 {
+		t.Errorf("pressing 2\"B3p should emit event.Paste with count 6 but got: %d", es[0].Count)
+	}
+	es = km.Press("p")
+	if len(es) != 1 || es[0].Register != 0 {
+		t.Errorf("pressing p should emit event.Paste without register but got: %v", es)
+	}
+}
+
+func TestKeyManagerMap(t *testing.T) {
+	km := NewManager(true)
+	km.Register(event.CursorDown, "j")
+	km.Register(event.CursorUp, "k")
+	km.Map([]Key{"j"}, event.CursorUp)
+	km.MapKeys([]Key{"g", "j"}, "3j", true)
+	es := km.Press("j")
+	if len(es) != 1 || es[0].Type != event.CursorUp {
+		t.Errorf("pressing j should emit event.CursorUp but got: %v", eventTypes(es))
+	}
+	es = km.PressNoremap("j")
+	if len(es) != 1 || es[0].Type != event.CursorDown {
+		t.Errorf("pressing j without remap should emit event.CursorDown but got: %v", eventTypes(es))
+	}
+	es = km.Press("g")
+	if len(es) != 0 {
+		t.Errorf("pressing g should be nop but got: %v", eventTypes(es))
+	}
+	es = km.Press("j")
+	if len(es) != 1 || es[0].Type != event.Keys || es[0].Arg != "3j" || !es[0].Bang {
+		t.Errorf("pressing gj should emit event.Keys but got: %v", es)
+	}
+	if expected := []Mapping{
+		{[]Key{"j"}, event.CursorUp, "", false},
+		{[]Key{"g", "j"}, event.Keys, "3j", true},
+	}; !slices.EqualFunc(km.Mappings(), expected, func(m1, m2 Mapping) bool {
+		return slices.Equal(m1.Keys, m2.Keys) && m1.Event == m2.Event &&
+			m1.RHS == m2.RHS && m1.Noremap == m2.Noremap
+	}) {
+		t.Errorf("mappings should be %v but got: %v", expected, km.Mappings())
+	}
+	if !km.Unmap([]Key{"j"}) {
+		t.Errorf("unmap should report true")
+	}
+	if km.Unmap([]Key{"j"}) {
+		t.Errorf("unmap should report false")
+	}
+	es = km.Press("j")
+	if len(es) != 1 || es[0].Type != event.CursorDown {
+		t.Errorf("pressing j should emit event.CursorDown but got: %v", eventTypes(es))
+	}
+}
+
+func TestKeyManagerMapAmbiguous(t *testing.T) {
+	km := NewManager(true)
+	km.Register(event.CursorDown, "j")
+	km.Register(event.CursorUp, "k")
+	km.Map([]Key{"j", "j"}, event.PageDown)
+	km.SetTimeout(0)
+	es := km.Press("j")
+	if len(es) != 0 {
+		t.Errorf("pressing j should be nop but got: %v", eventTypes(es))
 	}
-	e = km.Press("3")
-	if e.Type != event.Nop {
-		t.Errorf("pressing 3 should be nop but got: %d", e.Type)
+	es = km.Press("k")
+	if expected := []event.Type{event.CursorDown, event.CursorUp}; !slices.Equal(eventTypes(es), expected) {
+		t.Errorf("pressing jk should emit %v but got: %v", expected, eventTypes(es))
 	}
-	e = km.Press("7")
-	if e.Type != event.Nop {
-		t.Errorf("pressing 7 should be nop but got: %d", e.Type)
+	km.Press("j")
+	es = km.Press("j")
+	if expected := []event.Type{event.PageDown}; !slices.Equal(eventTypes(es), expected) {
+		t.Errorf("pressing jj should emit %v but got: %v", expected, eventTypes(es))
 	}
-	e = km.Press("k")
-	if e.Type != event.Nop {
-		t.Errorf("pressing k should be nop but got: %d", e.Type)
+}
+
+func TestKeyManagerMapTimeout(t *testing.T) {
+	km := NewManager(false)
+	km.MapKeys([]Key{"j", "k"}, "<esc>", false)
+	km.SetTimeout(10 * time.Millisecond)
+	ch := make(chan event.Event, 1)
+	km.SetTimeoutHandler(func(e event.Event) { ch <- e })
+	if es := km.Press("j"); len(es) != 0 {
+		t.Errorf("pressing j should be nop but got: %v", eventTypes(es))
+	}
+	select {
+	case e := <-ch:
+		if e.Type != event.Rune || e.Rune != 'j' {
+			t.Errorf("timeout should emit event.Rune but got: %v", e)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("timeout should emit event.Rune")
+	}
+	km.Press("j")
+	es := km.Press("k")
+	if len(es) != 1 || es[0].Type != event.Keys || es[0].Arg != "<esc>" {
+		t.Errorf("pressing jk should emit event.Keys but got: %v", es)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	testCases := []struct {
+		src      string
+		expected []Key
+		str      string
+	}{
+		{"j", []Key{"j"}, "j"},
+		{"<C-w>j", []Key{"c-w", "j"}, "<c-w>j"},
+		{"<Esc>:w<CR>", []Key{"escape", ":", "w", "enter"}, "<esc>:w<cr>"},
+		{"<lt>a<Space><Bar>", []Key{"<", "a", " ", "|"}, "<lt>a<space>|"},
+		{"<a>", []Key{"<", "a", ">"}, "<lt>a>"},
+		{"<F1><PageDown><S-Tab><BS>", []Key{"f1", "pgdn", "backtab", "backspace2"},
+			"<f1><pagedown><s-tab><bs>"},
+		{"ä<c-]>", []Key{"ä", "\x1d"}, "ä<c-]>"},
 	}
-	e = km.Press("j")
-	if e.Type != event.CursorUp {
-		t.Errorf("pressing 37kj should emit event.CursorUp but got: %d", e.Type)
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			keys, err := ParseKeys(tc.src)
+			if err != nil {
+				t.Fatalf("err should be nil but got: %v", err)
+			}
+			if !slices.Equal(keys, tc.expected) {
+				t.Errorf("keys should be %q but got: %q", tc.expected, keys)
+			}
+			if str := FormatKeys(keys); str != tc.str {
+				t.Errorf("key notation should be %q but got: %q", tc.str, str)
+			}
+		})
 	}
-	if e.Count != 37 {
-		t.Errorf("pressing 37kj should emit event.CursorUp with count 37 but got: %d", e.Count)
+	if _, err := ParseKeys(""); err == nil {
+		t.Errorf("err should not be nil")
 	}
 }
@@ -0,0 +1,154 @@
+package key
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var keyNames = map[string]Key{
+	"esc":      "escape",
+	"escape":   "escape",
+	"cr":       "enter",
+	"enter":    "enter",
+	"return":   "enter",
+	"tab":      "tab",
+	"s-tab":    "backtab",
+	"bs":       "backspace2",
+	"del":      "delete",
+	"delete":   "delete",
+	"space":    " ",
+	"lt":       "<",
+	"bar":      "|",
+	"bslash":   "\\",
+	"up":       "up",
+	"down":     "down",
+	"left":     "left",
+	"right":    "right",
+	"home":     "home",
+	"end":      "end",
+	"pageup":   "pgup",
+	"pagedown": "pgdn",
+	"insert":   "insert",
+	"f1":       "f1",
+	"f2":       "f2",
+	"f3":       "f3",
+	"f4":       "f4",
+	"f5":       "f5",
+	"f6":       "f6",
+	"f7":       "f7",
+	"f8":       "f8",
+	"f9":       "f9",
+	"f10":      "f10",
+	"f11":      "f11",
+	"f12":      "f12",
+	"c-h":      "backspace",
+	"c-i":      "tab",
+	"c-m":      "enter",
+	"c-[":      "escape",
+	"c-]":      "\x1d",
+	"c-^":      "\x1e",
+}
+
+// ParseKeys parses the key notation, like "<c-w>j" and "<leader>x".
+// The names in angle brackets are case-insensitive, and the angle bracket
+// which does not start a key name is treated literally.
+func ParseKeys(s string) ([]Key, error) {
+	var keys []Key
+	for s != "" {
+		if s[0] == '<' {
+			if name, rest, ok := strings.Cut(s[1:], ">"); ok {
+				if k, ok := parseKeyName(strings.ToLower(name)); ok {
+					keys, s = append(keys, k), rest
+					continue
+				}
+			}
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		if r == utf8.RuneError && size == 1 {
+			return nil, errors.New("invalid key notation: " + s)
+		}
+		keys, s = append(keys, Key(s[:size])), s[size:]
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("empty key sequence")
+	}
+	return keys, nil
+}
+
+func parseKeyName(name string) (Key, bool) {
+	if k, ok := keyNames[name]; ok {
+		return k, true
+	}
+	if c, ok := strings.CutPrefix(name, "c-"); ok && len(c) == 1 && 'a' <= c[0] && c[0] <= 'z' {
+		return Key(name), true
+	}
+	return "", false
+}
+
+// String returns the key in the key notation.
+func (k Key) String() string {
+	switch k {
+	case "<":
+		return "<lt>"
+	case " ":
+		return "<space>"
+	case "escape":
+		return "<esc>"
+	case "enter":
+		return "<cr>"
+	case "backspace":
+		return "<c-h>"
+	case "backspace2":
+		return "<bs>"
+	case "backtab":
+		return "<s-tab>"
+	case "delete":
+		return "<del>"
+	case "pgup":
+		return "<pageup>"
+	case "pgdn":
+		return "<pagedown>"
+	case "\x1d":
+		return "<c-]>"
+	case "\x1e":
+		return "<c-^>"
+	}
+	if utf8.RuneCountInString(string(k)) == 1 {
+		return string(k)
+	}
+	return "<" + string(k) + ">"
+}
+
+// FormatKeys returns the key sequence in the key notation.
+func FormatKeys(keys []Key) string {
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k.String())
+	}
+	return sb.String()
+}
+
+// Rune returns the character of the key, or zero for a special key
+// without a character.
+func (k Key) Rune() rune {
+	switch k {
+	case "enter":
+		return '\r'
+	case "tab":
+		return '\t'
+	case "escape":
+		return 0x1b
+	case "backspace":
+		return 0x08
+	case "backspace2":
+		return 0x7f
+	}
+	if c, ok := strings.CutPrefix(string(k), "c-"); ok && len(c) == 1 && 'a' <= c[0] && c[0] <= 'z' {
+		return rune(c[0]-'a') + 1
+	}
+	if r, size := utf8.DecodeRuneInString(string(k)); size == len(k) && r != utf8.RuneError {
+		return r
+	}
+	return 0
+}
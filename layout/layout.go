@@ -20,6 +20,9 @@ type Layout interface {
 	ActiveWindow() Window
 	Lookup(func(Window) bool) Window
 	Close() Layout
+	MoveBorder(int, int, int, int) (Layout, bool)
+	ResizeActive(int, bool) Layout
+	Equalize() Layout
 }
 
 // Window holds the window index and it is active or not.
@@ -167,10 +170,75 @@ func (l Window) Close() Layout {
 	return l
 }
 
+// MoveBorder moves the split border at the position.
+func (l Window) MoveBorder(int, int, int, int) (Layout, bool) {
+	return l, false
+}
+
+// ResizeActive changes the size of the active window.
+func (l Window) ResizeActive(int, bool) Layout {
+	return l
+}
+
+// Equalize the sizes of the windows.
+func (l Window) Equalize() Layout {
+	return l
+}
+
+// The targets of grow, which takes the delta of the size of the layout.
+const (
+	growActive = iota // the active window
+	growFirst         // the top (or left) window
+	growLast          // the bottom (or right) window
+)
+
+// resizeActive changes the height, or the width if vertical, of the active
+// window by delta. The size is taken from the window below (or right), or
+// from the window above (or left) if the active window is at the bottom.
+func resizeActive(l Layout, delta int, vertical bool) Layout {
+	if delta == 0 {
+		return l
+	}
+	layout, ok := resize(l, delta, vertical, true)
+	if !ok {
+		if layout, ok = resize(l, delta, vertical, false); !ok {
+			return l
+		}
+	}
+	return layout.Resize(l.LeftMargin(), l.TopMargin(), l.Width(), l.Height())
+}
+
+// resize changes the height, or the width if vertical, of the active window
+// by moving the nearest border after (or before if not forward) the window.
+func resize(l Layout, delta int, vertical, forward bool) (Layout, bool) {
+	switch l := l.(type) {
+	case Horizontal:
+		return resizeHorizontal(l, delta, vertical, forward)
+	case Vertical:
+		return resizeVertical(l, delta, vertical, forward)
+	default:
+		return l, false
+	}
+}
+
+// grow updates the ratios so that the target window takes the delta of the
+// height (or the width if vertical) of the layout as far as possible.
+func grow(l Layout, delta int, vertical bool, target int) Layout {
+	switch l := l.(type) {
+	case Horizontal:
+		return growHorizontal(l, delta, vertical, target)
+	case Vertical:
+		return growVertical(l, delta, vertical, target)
+	default:
+		return l
+	}
+}
+
 // Horizontal holds two layout horizontally.
 type Horizontal struct {
 	Top    Layout
 	Bottom Layout
+	ratio  float64 // the ratio of the top height, or 0 to split evenly
 	left   int
 	top    int
 	width  int
@@ -193,6 +261,7 @@ func (l Horizontal) Replace(index int) Layout {
 	return Horizontal{
 		Top:    l.Top.Replace(index),
 		Bottom: l.Bottom.Replace(index),
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -205,9 +274,13 @@ func (l Horizontal) Resize(left, top, width, height int) Layout {
 	_, h1 := l.Top.Count()
 	_, h2 := l.Bottom.Count()
 	topHeight := height * h1 / (h1 + h2)
+	if l.ratio > 0 && height > 1 {
+		topHeight = min(max(int(float64(height)*l.ratio+0.5), 1), height-1)
+	}
 	return Horizontal{
 		Top:    l.Top.Resize(left, top, width, topHeight),
 		Bottom: l.Bottom.Resize(left, top+topHeight, width, height-topHeight),
+		ratio:  l.ratio,
 		left:   left,
 		top:    top,
 		width:  width,
@@ -240,6 +313,7 @@ func (l Horizontal) SplitTop(index int) Layout {
 	return Horizontal{
 		Top:    l.Top.SplitTop(index),
 		Bottom: l.Bottom.SplitTop(index),
+		ratio:  l.ratio,
 	}
 }
 
@@ -248,6 +322,7 @@ func (l Horizontal) SplitBottom(index int) Layout {
 	return Horizontal{
 		Top:    l.Top.SplitBottom(index),
 		Bottom: l.Bottom.SplitBottom(index),
+		ratio:  l.ratio,
 	}
 }
 
@@ -256,6 +331,7 @@ func (l Horizontal) SplitLeft(index int) Layout {
 	return Horizontal{
 		Top:    l.Top.SplitLeft(index),
 		Bottom: l.Bottom.SplitLeft(index),
+		ratio:  l.ratio,
 	}
 }
 
@@ -264,6 +340,7 @@ func (l Horizontal) SplitRight(index int) Layout {
 	return Horizontal{
 		Top:    l.Top.SplitRight(index),
 		Bottom: l.Bottom.SplitRight(index),
+		ratio:  l.ratio,
 	}
 }
 
@@ -279,6 +356,7 @@ func (l Horizontal) Activate(i int) Layout {
 	return Horizontal{
 		Top:    l.Top.Activate(i),
 		Bottom: l.Bottom.Activate(i),
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -291,6 +369,7 @@ func (l Horizontal) ActivateFirst() Layout {
 	return Horizontal{
 		Top:    l.Top.ActivateFirst(),
 		Bottom: l.Bottom,
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -329,13 +408,134 @@ func (l Horizontal) Close() Layout {
 	return Horizontal{
 		Top:    l.Top.Close(),
 		Bottom: l.Bottom.Close(),
+		ratio:  l.ratio,
+	}
+}
+
+// MoveBorder moves the split border at the position (x, y), which is the
+// status line of the top layout, to the row of the position (toX, toY).
+func (l Horizontal) MoveBorder(x, y, toX, toY int) (Layout, bool) {
+	if x < l.left || l.left+l.width <= x || y < l.top || l.top+l.height <= y {
+		return l, false
+	}
+	if y == l.top+l.Top.Height()-1 {
+		if l.height < 2 {
+			return l, false
+		}
+		topHeight := min(max(toY-l.top+1, 1), l.height-1)
+		return l.with(l.Top, l.Bottom, float64(topHeight)/float64(l.height)).
+			Resize(l.left, l.top, l.width, l.height), true
+	}
+	if top, ok := l.Top.MoveBorder(x, y, toX, toY); ok {
+		return l.with(top, l.Bottom, l.ratio), true
+	}
+	if bottom, ok := l.Bottom.MoveBorder(x, y, toX, toY); ok {
+		return l.with(l.Top, bottom, l.ratio), true
+	}
+	return l, false
+}
+
+// ResizeActive changes the height, or the width if vertical, of the active
+// window by delta.
+func (l Horizontal) ResizeActive(delta int, vertical bool) Layout {
+	return resizeActive(l, delta, vertical)
+}
+
+// Equalize the sizes of the windows.
+func (l Horizontal) Equalize() Layout {
+	return Horizontal{
+		Top:    l.Top.Equalize(),
+		Bottom: l.Bottom.Equalize(),
+	}.Resize(l.left, l.top, l.width, l.height)
+}
+
+// with returns the layout with the new children and the ratio of the top
+// height, keeping the position.
+func (l Horizontal) with(top, bottom Layout, ratio float64) Horizontal {
+	return Horizontal{
+		Top:    top,
+		Bottom: bottom,
+		ratio:  ratio,
+		left:   l.left,
+		top:    l.top,
+		width:  l.width,
+		height: l.height,
+	}
+}
+
+// resizeHorizontal changes the height of the active window by moving the
+// nearest border below (or above if not forward) the active window.
+func resizeHorizontal(l Horizontal, delta int, vertical, forward bool) (Layout, bool) {
+	inTop := l.Top.ActiveWindow().Index >= 0
+	if inTop {
+		if top, ok := resize(l.Top, delta, vertical, forward); ok {
+			return l.with(top, l.Bottom, l.ratio), true
+		}
+	} else if bottom, ok := resize(l.Bottom, delta, vertical, forward); ok {
+		return l.with(l.Top, bottom, l.ratio), true
 	}
+	if vertical || inTop != forward {
+		return l, false
+	}
+	_, h1 := l.Top.Count()
+	_, h2 := l.Bottom.Count()
+	if l.height < h1+h2 {
+		return l, false
+	}
+	height := l.Top.Height()
+	if inTop {
+		topHeight := min(max(height+delta, h1), l.height-h2)
+		return l.with(
+			grow(l.Top, topHeight-height, false, growActive),
+			grow(l.Bottom, height-topHeight, false, growFirst),
+			float64(topHeight)/float64(l.height),
+		), true
+	}
+	topHeight := min(max(height-delta, h1), l.height-h2)
+	return l.with(
+		grow(l.Top, topHeight-height, false, growLast),
+		grow(l.Bottom, height-topHeight, false, growActive),
+		float64(topHeight)/float64(l.height),
+	), true
+}
+
+// growHorizontal updates the ratios so that the target window takes the delta
+// of the height (or the width if vertical) of the layout as far as possible.
+func growHorizontal(l Horizontal, delta int, vertical bool, target int) Layout {
+	inTop := l.Top.ActiveWindow().Index >= 0
+	if vertical {
+		top, bottom := l.Top, l.Bottom
+		if target != growActive || inTop {
+			top = grow(top, delta, vertical, target)
+		}
+		if target != growActive || !inTop {
+			bottom = grow(bottom, delta, vertical, target)
+		}
+		return l.with(top, bottom, l.ratio)
+	}
+	_, h1 := l.Top.Count()
+	_, h2 := l.Bottom.Count()
+	height, topHeight := l.height+delta, l.Top.Height()
+	if height < h1+h2 {
+		return l
+	}
+	if target == growFirst || target == growActive && inTop {
+		topHeight = min(max(topHeight+delta, h1), height-h2)
+	} else {
+		topHeight = min(max(topHeight, h1), height-h2)
+	}
+	return l.with(
+		grow(l.Top, topHeight-l.Top.Height(), false, target),
+		grow(l.Bottom, height-topHeight-l.Bottom.Height(), false, target),
+		float64(topHeight)/float64(height),
+	)
 }
 
 // Vertical holds two layout vertically.
 type Vertical struct {
 	Left   Layout
 	Right  Layout
+	ratio  float64 // the ratio of the left width, or 0 to split evenly
 	left   int
 	top    int
 	width  int
@@ -358,6 +558,7 @@ func (l Vertical) Replace(index int) Layout {
 	return Vertical{
 		Left:   l.Left.Replace(index),
 		Right:  l.Right.Replace(index),
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -370,11 +571,15 @@ func (l Vertical) Resize(left, top, width, height int) Layout {
 	w1, _ := l.Left.Count()
 	w2, _ := l.Right.Count()
 	leftWidth := width * w1 / (w1 + w2)
+	if l.ratio > 0 && width > 2 {
+		leftWidth = min(max(int(float64(width)*l.ratio+0.5), 1), width-2)
+	}
 	return Vertical{
 		Left: l.Left.Resize(left, top, leftWidth, height),
 		Right: l.Right.Resize(
 			min(left+leftWidth+1, left+width), top,
 			max(width-leftWidth-1, 0), height),
+		ratio:  l.ratio,
 		left:   left,
 		top:    top,
 		width:  width,
@@ -407,6 +612,7 @@ func (l Vertical) SplitTop(index int) Layout {
 	return Vertical{
 		Left:  l.Left.SplitTop(index),
 		Right: l.Right.SplitTop(index),
+		ratio: l.ratio,
 	}
 }
 
@@ -415,6 +621,7 @@ func (l Vertical) SplitBottom(index int) Layout {
 	return Vertical{
 		Left:  l.Left.SplitBottom(index),
 		Right: l.Right.SplitBottom(index),
+		ratio: l.ratio,
 	}
 }
 
@@ -423,6 +630,7 @@ func (l Vertical) SplitLeft(index int) Layout {
 	return Vertical{
 		Left:  l.Left.SplitLeft(index),
 		Right: l.Right.SplitLeft(index),
+		ratio: l.ratio,
 	}
 }
 
@@ -431,6 +639,7 @@ func (l Vertical) SplitRight(index int) Layout {
 	return Vertical{
 		Left:  l.Left.SplitRight(index),
 		Right: l.Right.SplitRight(index),
+		ratio: l.ratio,
 	}
 }
 
@@ -446,6 +655,7 @@ func (l Vertical) Activate(i int) Layout {
 	return Vertical{
 		Left:   l.Left.Activate(i),
 		Right:  l.Right.Activate(i),
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -458,6 +668,7 @@ func (l Vertical) ActivateFirst() Layout {
 	return Vertical{
 		Left:   l.Left.ActivateFirst(),
 		Right:  l.Right,
+		ratio:  l.ratio,
 		left:   l.left,
 		top:    l.top,
 		width:  l.width,
@@ -496,5 +707,125 @@ func (l Vertical) Close() Layout {
 	return Vertical{
 		Left:  l.Left.Close(),
 		Right: l.Right.Close(),
+		ratio: l.ratio,
+	}
+}
+
+// MoveBorder moves the split border at the position (x, y), which is the
+// vertical separator, to the column of the position (toX, toY).
+func (l Vertical) MoveBorder(x, y, toX, toY int) (Layout, bool) {
+	if x < l.left || l.left+l.width <= x || y < l.top || l.top+l.height <= y {
+		return l, false
+	}
+	if x == l.left+l.Left.Width() {
+		if l.width < 3 {
+			return l, false
+		}
+		leftWidth := min(max(toX-l.left, 1), l.width-2)
+		return l.with(l.Left, l.Right, float64(leftWidth)/float64(l.width)).
+			Resize(l.left, l.top, l.width, l.height), true
+	}
+	if left, ok := l.Left.MoveBorder(x, y, toX, toY); ok {
+		return l.with(left, l.Right, l.ratio), true
+	}
+	if right, ok := l.Right.MoveBorder(x, y, toX, toY); ok {
+		return l.with(l.Left, right, l.ratio), true
+	}
+	return l, false
+}
+
+// ResizeActive changes the height, or the width if vertical, of the active
+// window by delta.
+func (l Vertical) ResizeActive(delta int, vertical bool) Layout {
+	return resizeActive(l, delta, vertical)
+}
+
+// Equalize the sizes of the windows.
+func (l Vertical) Equalize() Layout {
+	return Vertical{
+		Left:  l.Left.Equalize(),
+		Right: l.Right.Equalize(),
+	}.Resize(l.left, l.top, l.width, l.height)
+}
+
+// with returns the layout with the new children and the ratio of the left
+// width, keeping the position.
+func (l Vertical) with(left, right Layout, ratio float64) Vertical {
+	return Vertical{
+		Left:   left,
+		Right:  right,
+		ratio:  ratio,
+		left:   l.left,
+		top:    l.top,
+		width:  l.width,
+		height: l.height,
+	}
+}
+
+// resizeVertical changes the width of the active window by moving the nearest
+// border on the right (or left if not forward) of the active window.
+func resizeVertical(l Vertical, delta int, vertical, forward bool) (Layout, bool) {
+	inLeft := l.Left.ActiveWindow().Index >= 0
+	if inLeft {
+		if left, ok := resize(l.Left, delta, vertical, forward); ok {
+			return l.with(left, l.Right, l.ratio), true
+		}
+	} else if right, ok := resize(l.Right, delta, vertical, forward); ok {
+		return l.with(l.Left, right, l.ratio), true
+	}
+	if !vertical || inLeft != forward {
+		return l, false
+	}
+	w1, _ := l.Left.Count()
+	w2, _ := l.Right.Count()
+	if l.width < 2*(w1+w2)-1 {
+		return l, false
+	}
+	width := l.Left.Width()
+	if inLeft {
+		leftWidth := min(max(width+delta, 2*w1-1), l.width-2*w2)
+		return l.with(
+			grow(l.Left, leftWidth-width, true, growActive),
+			grow(l.Right, width-leftWidth, true, growFirst),
+			float64(leftWidth)/float64(l.width),
+		), true
+	}
+	leftWidth := min(max(width-delta, 2*w1-1), l.width-2*w2)
+	return l.with(
+		grow(l.Left, leftWidth-width, true, growLast),
+		grow(l.Right, width-leftWidth, true, growActive),
+		float64(leftWidth)/float64(l.width),
+	), true
+}
+
+// growVertical updates the ratios so that the target window takes the delta
+// of the width (or the height if not vertical) of the layout as far as possible.
+func growVertical(l Vertical, delta int, vertical bool, target int) Layout {
+	inLeft := l.Left.ActiveWindow().Index >= 0
+	if !vertical {
+		left, right := l.Left, l.Right
+		if target != growActive || inLeft {
+			left = grow(left, delta, vertical, target)
+		}
+		if target != growActive || !inLeft {
+			right = grow(right, delta, vertical, target)
+		}
+		return l.with(left, right, l.ratio)
+	}
+	w1, _ := l.Left.Count()
+	w2, _ := l.Right.Count()
+	width, leftWidth := l.width+delta, l.Left.Width()
+	if width < 2*(w1+w2)-1 {
+		return l
+	}
+	if target == growFirst || target == growActive && inLeft {
+		leftWidth = min(max(leftWidth+delta, 2*w1-1), width-2*w2)
+	} else {
+		leftWidth = min(max(leftWidth, 2*w1-1), width-2*w2)
 	}
+	return l.with(
+		grow(l.Left, leftWidth-l.Left.Width(), true, target),
+		grow(l.Right, width-leftWidth-1-l.Right.Width(), true, target),
+		float64(leftWidth)/float64(width),
+	)
 }
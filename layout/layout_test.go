@@ -305,3 +305,109 @@ func TestLayout(t *testing.T) {
 		t.Errorf("Height() should be %d but layout %d", 10, layout.Height())
 	}
 }
+
+func TestLayoutMoveBorder(t *testing.T) {
+	layout := NewLayout(0).SplitBottom(1).SplitRight(2).Resize(0, 0, 20, 10)
+
+	var ok bool
+	if layout, ok = layout.MoveBorder(3, 4, 8, 6); !ok {
+		t.Errorf("MoveBorder should move the border of the status line")
+	}
+	if layout, ok = layout.MoveBorder(10, 8, 5, 0); !ok {
+		t.Errorf("MoveBorder should move the vertical border")
+	}
+	if _, ok = layout.MoveBorder(3, 2, 3, 8); ok {
+		t.Errorf("MoveBorder should not move the border out of the borders")
+	}
+
+	expectedMap := map[int]Window{
+		0: {Index: 0, Active: false, left: 0, top: 0, width: 20, height: 7},
+		1: {Index: 1, Active: false, left: 0, top: 7, width: 5, height: 3},
+		2: {Index: 2, Active: true, left: 6, top: 7, width: 14, height: 3},
+	}
+	if !reflect.DeepEqual(layout.Collect(), expectedMap) {
+		t.Errorf("Collect should be %+v but got %+v", expectedMap, layout.Collect())
+	}
+
+	layout, _ = layout.MoveBorder(5, 8, 30, 0)
+	layout, _ = layout.MoveBorder(0, 6, 0, -5)
+	expectedMap = map[int]Window{
+		0: {Index: 0, Active: false, left: 0, top: 0, width: 20, height: 1},
+		1: {Index: 1, Active: false, left: 0, top: 1, width: 18, height: 9},
+		2: {Index: 2, Active: true, left: 19, top: 1, width: 1, height: 9},
+	}
+	if !reflect.DeepEqual(layout.Collect(), expectedMap) {
+		t.Errorf("Collect should be %+v but got %+v", expectedMap, layout.Collect())
+	}
+
+	layout, _ = layout.MoveBorder(0, 0, 0, 6)
+	layout, _ = layout.MoveBorder(18, 8, 5, 0)
+	layout = layout.SplitTop(3).Resize(0, 0, 40, 20)
+	expectedMap = map[int]Window{
+		0: {Index: 0, Active: false, left: 0, top: 0, width: 40, height: 14},
+		1: {Index: 1, Active: false, left: 0, top: 14, width: 10, height: 6},
+		2: {Index: 2, Active: false, left: 11, top: 17, width: 29, height: 3},
+		3: {Index: 3, Active: true, left: 11, top: 14, width: 29, height: 3},
+	}
+	if !reflect.DeepEqual(layout.Collect(), expectedMap) {
+		t.Errorf("Collect should be %+v but got %+v", expectedMap, layout.Collect())
+	}
+}
+
+func TestLayoutResizeActive(t *testing.T) {
+	layout := NewLayout(0).SplitBottom(2).Activate(0).SplitBottom(1).Resize(0, 0, 20, 12)
+
+	layout = layout.ResizeActive(2, false)
+	expectedMap := map[int]Window{
+		0: {Index: 0, Active: false, left: 0, top: 0, width: 20, height: 4},
+		1: {Index: 1, Active: true, left: 0, top: 4, width: 20, height: 6},
+		2: {Index: 2, Active: false, left: 0, top: 10, width: 20, height: 2},
+	}
+	if !reflect.DeepEqual(layout.Collect(), expectedMap) {
+		t.Errorf("Collect should be %+v but got %+v", expectedMap, layout.Collect())
+	}
+
+	layout = layout.Activate(2).ResizeActive(3, false)
+	expectedMap = map[int]Window{
+		0: {Index: 0, Active: false, left: 0, top: 0, width: 20, height: 4},
+		1: {Index: 1, Active: false, left: 0, top: 4, width: 20, height: 3},
+		2: {Index: 2, Active: true, left: 0, top: 7, width: 20, height: 5},
+	}
+	if !reflect.DeepEqual(layout.Collect(), expectedMap) {
+		t.Errorf("Collect should be %+v but got %+v", expectedMap, layout.Collect())
+	}
+
+	layout = layout.Activate(1).SplitRight(3).Resize(0, 0, 20, 12).ResizeActive(-5, true)
+	layout = layout.ResizeActive(-2, false)
+	expectedMap = map[int]Window{
+		0: {Index: 0, Active: false, left: 0, top: 0, width: 20, height: 4},
+		1: {Index: 1, Active: false, left: 0, top: 4, width: 15, height: 1},
+		2: {Index: 2, Active: false, left: 0, top: 5, width: 20, height: 7},
+		3: {Index: 3, Active: true, left: 16, top: 4, width: 4, height: 1},
+	}
+	if !reflect.DeepEqual(layout.Collect(), expectedMap) {
+		t.Errorf("Collect should be %+v but got %+v", expectedMap, layout.Collect())
+	}
+
+	layout = layout.Resize(0, 0, 40, 24)
+	expectedMap = map[int]Window{
+		0: {Index: 0, Active: false, left: 0, top: 0, width: 40, height: 8},
+		1: {Index: 1, Active: false, left: 0, top: 8, width: 30, height: 2},
+		2: {Index: 2, Active: false, left: 0, top: 10, width: 40, height: 14},
+		3: {Index: 3, Active: true, left: 31, top: 8, width: 9, height: 2},
+	}
+	if !reflect.DeepEqual(layout.Collect(), expectedMap) {
+		t.Errorf("Collect should be %+v but got %+v", expectedMap, layout.Collect())
+	}
+
+	layout = layout.Equalize()
+	expectedMap = map[int]Window{
+		0: {Index: 0, Active: false, left: 0, top: 0, width: 40, height: 8},
+		1: {Index: 1, Active: false, left: 0, top: 8, width: 20, height: 8},
+		2: {Index: 2, Active: false, left: 0, top: 16, width: 40, height: 8},
+		3: {Index: 3, Active: true, left: 21, top: 8, width: 19, height: 8},
+	}
+	if !reflect.DeepEqual(layout.Collect(), expectedMap) {
+		t.Errorf("Collect should be %+v but got %+v", expectedMap, layout.Collect())
+	}
+}
@@ -11,4 +11,5 @@ const (
 	Visual
 	Cmdline
 	Search
+	Confirm
 )
@@ -0,0 +1,160 @@
+package option
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Option represents an option setting of :set command.
+type Option struct {
+	Name  string
+	Value string
+	Type  Type
+	arg   string
+}
+
+// Type of the option setting.
+type Type int
+
+// Option setting types
+const (
+	Set    Type = iota // name
+	Unset              // noname
+	Toggle             // invname, name!
+	Query              // name?
+	Assign             // name=value
+)
+
+// Parse the arguments of :set command.
+func Parse(src string) ([]Option, error) {
+	var opts []Option
+	for _, arg := range splitArgs(src) {
+		var opt Option
+		if name, value, ok := strings.Cut(arg, "="); ok {
+			opt = Option{Name: name, Value: value, Type: Assign}
+		} else if name, ok := strings.CutSuffix(arg, "?"); ok {
+			opt = Option{Name: name, Type: Query}
+		} else if name, ok := strings.CutSuffix(arg, "!"); ok {
+			opt = Option{Name: name, Type: Toggle}
+		} else if name, ok := strings.CutPrefix(arg, "inv"); ok {
+			opt = Option{Name: name, Type: Toggle}
+		} else if name, ok := strings.CutPrefix(arg, "no"); ok {
+			opt = Option{Name: name, Type: Unset}
+		} else {
+			opt = Option{Name: arg, Type: Set}
+		}
+		if opt.Name == "" || strings.ContainsFunc(opt.Name, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			return nil, errors.New("invalid option: " + arg)
+		}
+		opt.arg = arg
+		opts = append(opts, opt)
+	}
+	return opts, nil
+}
+
+// splitArgs splits the arguments by spaces, which can be escaped by backslashes.
+func splitArgs(src string) []string {
+	var args []string
+	var sb strings.Builder
+	var escape, arg bool
+	for _, r := range src {
+		switch {
+		case escape:
+			if r != ' ' && r != '\\' {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(r)
+			escape = false
+		case r == '\\':
+			escape, arg = true, true
+		case unicode.IsSpace(r):
+			if arg {
+				args = append(args, sb.String())
+				sb.Reset()
+				arg = false
+			}
+		default:
+			sb.WriteRune(r)
+			arg = true
+		}
+	}
+	if escape {
+		sb.WriteRune('\\')
+	}
+	if arg {
+		args = append(args, sb.String())
+	}
+	return args
+}
+
+// SetBool applies the setting to the boolean option.
+// It returns the current value for Query setting.
+func (opt Option) SetBool(p *bool) (string, error) {
+	switch opt.Type {
+	case Set:
+		*p = true
+	case Unset:
+		*p = false
+	case Toggle:
+		*p = !*p
+	case Query:
+		if *p {
+			return opt.Name, nil
+		}
+		return "no" + opt.Name, nil
+	default:
+		return "", opt.invalidArgument()
+	}
+	return "", nil
+}
+
+// SetString applies the setting to the string option.
+// If values are specified, the value is restricted to them.
+// It returns the current value for Set and Query setting.
+func (opt Option) SetString(p *string, values ...string) (string, error) {
+	switch opt.Type {
+	case Set, Query:
+		return opt.Name + "=" + *p, nil
+	case Assign:
+		if len(values) > 0 && !slices.Contains(values, opt.Value) {
+			return "", opt.invalidArgument()
+		}
+		*p = opt.Value
+	default:
+		return "", opt.invalidArgument()
+	}
+	return "", nil
+}
+
+// SetInt applies the setting to the integer option.
+// It returns the current value for Set and Query setting.
+func (opt Option) SetInt(p *int64) (string, error) {
+	switch opt.Type {
+	case Set, Query:
+		return opt.Name + "=" + strconv.FormatInt(*p, 10), nil
+	case Assign:
+		n, err := strconv.ParseInt(opt.Value, 0, 64)
+		if err != nil {
+			return "", opt.invalidArgument()
+		}
+		*p = n
+	default:
+		return "", opt.invalidArgument()
+	}
+	return "", nil
+}
+
+func (opt Option) invalidArgument() error {
+	return errors.New("invalid argument: " + opt.arg)
+}
+
+// Unknown returns the error for unknown option.
+func (opt Option) Unknown() error {
+	name, _, _ := strings.Cut(strings.TrimRight(opt.arg, "?!"), "=")
+	return errors.New("unknown option: " + name)
+}
@@ -0,0 +1,93 @@
+package option
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, testCase := range []struct {
+		src      string
+		expected []Option
+		err      string
+	}{
+		{"", nil, ""},
+		{"osc52", []Option{{Name: "osc52", Type: Set}}, ""},
+		{" noosc52  invosc52 osc52! osc52? ", []Option{
+			{Name: "osc52", Type: Unset},
+			{Name: "osc52", Type: Toggle},
+			{Name: "osc52", Type: Toggle},
+			{Name: "osc52", Type: Query},
+		}, ""},
+		{`foo=bar\ baz\\ qux=\x`, []Option{
+			{Name: "foo", Value: `bar baz\`, Type: Assign},
+			{Name: "qux", Value: `\x`, Type: Assign},
+		}, ""},
+		{"foo=", []Option{{Name: "foo", Type: Assign}}, ""},
+		{"=foo", nil, "invalid option: =foo"},
+		{"foo-bar", nil, "invalid option: foo-bar"},
+	} {
+		got, err := Parse(testCase.src)
+		for i := range got {
+			got[i].arg = ""
+		}
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("Parse(%q) should be %+v but got %+v", testCase.src, testCase.expected, got)
+		}
+		if testCase.err == "" && err != nil || testCase.err != "" && (err == nil || err.Error() != testCase.err) {
+			t.Errorf("Parse(%q) should return error %q but got %v", testCase.src, testCase.err, err)
+		}
+	}
+}
+
+func TestOptionSet(t *testing.T) {
+	opts, err := Parse("foo nofoo foo! foo? bar=baz bar? baz=0x10 baz foo=1 bar=qux")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	var b bool
+	var s string
+	var n int64
+	for i, expected := range []struct {
+		b    bool
+		s    string
+		n    int64
+		info string
+		err  string
+	}{
+		{b: true},
+		{b: false},
+		{b: true},
+		{b: true, info: "foo"},
+		{b: true, s: "baz"},
+		{b: true, s: "baz", info: "bar=baz"},
+		{b: true, s: "baz", n: 16},
+		{b: true, s: "baz", n: 16, info: "baz=16"},
+		{b: true, s: "baz", n: 16, err: "invalid argument: foo=1"},
+		{b: true, s: "baz", n: 16, err: "invalid argument: bar=qux"},
+	} {
+		var info string
+		var err error
+		switch opts[i].Name {
+		case "foo":
+			info, err = opts[i].SetBool(&b)
+		case "bar":
+			info, err = opts[i].SetString(&s, "baz", "qux1")
+		case "baz":
+			info, err = opts[i].SetInt(&n)
+		}
+		if b != expected.b || s != expected.s || n != expected.n || info != expected.info {
+			t.Errorf("%d: expected %v, %q, %d, %q but got %v, %q, %d, %q",
+				i, expected.b, expected.s, expected.n, expected.info, b, s, n, info)
+		}
+		if expected.err == "" && err != nil || expected.err != "" && (err == nil || err.Error() != expected.err) {
+			t.Errorf("%d: error should be %q but got %v", i, expected.err, err)
+		}
+	}
+	if err, expected := opts[1].Unknown(), "unknown option: nofoo"; err.Error() != expected {
+		t.Errorf("error should be %q but got %v", expected, err)
+	}
+	if err, expected := opts[4].Unknown(), "unknown option: bar"; err.Error() != expected {
+		t.Errorf("error should be %q but got %v", expected, err)
+	}
+}
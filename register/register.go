@@ -0,0 +1,116 @@
+package register
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/itchyny/bed/buffer"
+)
+
+// Register manages the buffers of yanked and deleted bytes.
+type Register struct {
+	buffers map[rune]*buffer.Buffer
+}
+
+// NewRegister creates a new Register.
+func NewRegister() *Register {
+	return &Register{buffers: make(map[rune]*buffer.Buffer)}
+}
+
+// Unnamed is the register used when no register is specified.
+const Unnamed = '"'
+
+// BlackHole is the register which discards everything.
+const BlackHole = '_'
+
+// Clipboard is the register for the system clipboard.
+const Clipboard = '+'
+
+// Names returns the register names in the listing order.
+func Names() []rune {
+	names := []rune{Unnamed}
+	for c := '0'; c <= '9'; c++ {
+		names = append(names, c)
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		names = append(names, c)
+	}
+	return append(names, Clipboard)
+}
+
+// Valid reports whether the name is a valid register name.
+func Valid(name rune) bool {
+	return name == 0 || name == Unnamed || name == BlackHole || name == Clipboard ||
+		'0' <= name && name <= '9' ||
+		'a' <= name && name <= 'z' || 'A' <= name && name <= 'Z'
+}
+
+// Yank stores the yanked bytes to the register.
+// Without register name, the bytes are stored to the register 0.
+func (r *Register) Yank(name rune, b *buffer.Buffer) error {
+	if name == 0 || name == Unnamed {
+		name = '0'
+	}
+	return r.store(name, b)
+}
+
+// Delete stores the deleted bytes to the register.
+// Without register name, the numbered registers are shifted
+// and the bytes are stored to the register 1.
+func (r *Register) Delete(name rune, b *buffer.Buffer) error {
+	if name == 0 || name == Unnamed {
+		for c := '9'; c > '1'; c-- {
+			if b, ok := r.buffers[c-1]; ok {
+				r.buffers[c] = b
+			}
+		}
+		name = '1'
+	}
+	return r.store(name, b)
+}
+
+func (r *Register) store(name rune, b *buffer.Buffer) error {
+	if !Valid(name) {
+		return errors.New("invalid register: " + string(name))
+	}
+	switch {
+	case name == BlackHole:
+		return nil
+	case 'A' <= name && name <= 'Z':
+		name += 'a' - 'A'
+		if c, ok := r.buffers[name]; ok {
+			c = c.Clone()
+			l, err := c.Len()
+			if err != nil {
+				return err
+			}
+			c.Paste(l, b)
+			b = c
+		}
+	}
+	r.buffers[name] = b
+	r.buffers[Unnamed] = b
+	return nil
+}
+
+// Get returns the buffer of the register.
+func (r *Register) Get(name rune) (*buffer.Buffer, error) {
+	if !Valid(name) {
+		return nil, errors.New("invalid register: " + string(name))
+	}
+	switch {
+	case name == 0:
+		name = Unnamed
+	case 'A' <= name && name <= 'Z':
+		name += 'a' - 'A'
+	}
+	return r.buffers[name], nil
+}
+
+// List returns the names of the non-empty registers.
+func (r *Register) List() []rune {
+	return slices.DeleteFunc(Names(), func(name rune) bool {
+		_, ok := r.buffers[name]
+		return !ok
+	})
+}
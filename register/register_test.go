@@ -0,0 +1,77 @@
+package register
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/bed/buffer"
+)
+
+func readAll(t *testing.T, r *Register, name rune) string {
+	t.Helper()
+	b, err := r.Get(name)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if b == nil {
+		return ""
+	}
+	bs, err := io.ReadAll(io.NewSectionReader(b, 0, 1024))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	return string(bs)
+}
+
+func TestRegisterYankDelete(t *testing.T) {
+	r := NewRegister()
+	if err := r.Yank(0, buffer.NewBuffer(strings.NewReader("foo"))); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := r.Delete(0, buffer.NewBuffer(strings.NewReader("bar"))); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := r.Delete(0, buffer.NewBuffer(strings.NewReader("baz"))); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := r.Delete(BlackHole, buffer.NewBuffer(strings.NewReader("qux"))); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	for name, expected := range map[rune]string{
+		0: "baz", Unnamed: "baz", '0': "foo", '1': "baz", '2': "bar", '3': "", BlackHole: "",
+	} {
+		if got := readAll(t, r, name); got != expected {
+			t.Errorf("register %q should be %q but got %q", name, expected, got)
+		}
+	}
+	if expected, got := `"012`, string(r.List()); got != expected {
+		t.Errorf("registers should be %q but got %q", expected, got)
+	}
+}
+
+func TestRegisterNamed(t *testing.T) {
+	r := NewRegister()
+	if err := r.Yank('a', buffer.NewBuffer(strings.NewReader("foo"))); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := r.Delete('A', buffer.NewBuffer(strings.NewReader("bar"))); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := r.Yank('B', buffer.NewBuffer(strings.NewReader("baz"))); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	for name, expected := range map[rune]string{
+		0: "baz", 'a': "foobar", 'A': "foobar", 'b': "baz", '0': "", '1': "",
+	} {
+		if got := readAll(t, r, name); got != expected {
+			t.Errorf("register %q should be %q but got %q", name, expected, got)
+		}
+	}
+	if err := r.Yank('!', buffer.NewBuffer(strings.NewReader("foo"))); err == nil {
+		t.Errorf("err should not be nil")
+	}
+	if _, err := r.Get('!'); err == nil {
+		t.Errorf("err should not be nil")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"math"
 	"sync"
 	"time"
 )
@@ -20,6 +21,14 @@ type Searcher struct {
 	mu      *sync.Mutex
 }
 
+// Ranger is implemented by the reader which is searched only in the ranges,
+// like the mapped regions of the memory of a process.
+type Ranger interface {
+	// Range returns the range to search after the offset if forward, or
+	// before the offset if backward. The range is empty if there is none.
+	Range(offset int64, forward bool) (start, end int64)
+}
+
 // NewSearcher creates a new searcher.
 func NewSearcher(r io.ReaderAt) *Searcher {
 	return &Searcher{r: r, mu: new(sync.Mutex)}
@@ -48,6 +57,15 @@ func (s *Searcher) Search(cursor int64, pattern string, forward bool) <-chan any
 	return ch
 }
 
+// MatchLength returns the length of the bytes matched by the pattern.
+func MatchLength(pattern string) int {
+	target, err := patternToTarget(pattern)
+	if err != nil {
+		return 0
+	}
+	return len(target)
+}
+
 func (s *Searcher) forward() (int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -55,18 +73,25 @@ func (s *Searcher) forward() (int64, error) {
 	if err != nil {
 		return -1, err
 	}
-	base := s.cursor + 1
-	n, err := s.r.ReadAt(s.bytes, base)
+	base, end := s.cursor+1, int64(math.MaxInt64)
+	if r, ok := s.r.(Ranger); ok {
+		var start int64
+		if start, end = r.Range(base, true); start >= end {
+			return -1, errNotFound(s.pattern)
+		}
+		base = max(base, start)
+	}
+	n, err := s.r.ReadAt(s.bytes[:min(int64(loadSize), end-base)], base)
 	if err != nil && err != io.EOF {
 		return -1, err
 	}
 	if n == 0 {
 		return -1, errNotFound(s.pattern)
 	}
-	if err == io.EOF {
-		s.cursor += int64(n)
+	if err == io.EOF || base+int64(n) == end {
+		s.cursor = base + int64(n) - 1
 	} else {
-		s.cursor += int64(n - len(target) + 1)
+		s.cursor = base + int64(n-len(target))
 	}
 	i := bytes.Index(s.bytes[:n], target)
 	if i >= 0 {
@@ -82,17 +107,24 @@ func (s *Searcher) backward() (int64, error) {
 	if err != nil {
 		return -1, err
 	}
-	base := max(0, s.cursor-int64(loadSize))
-	size := int(min(int64(loadSize), s.cursor))
-	n, err := s.r.ReadAt(s.bytes[:size], base)
+	start, end := int64(0), s.cursor
+	if r, ok := s.r.(Ranger); ok {
+		var to int64
+		if start, to = r.Range(s.cursor, false); start >= to {
+			return -1, errNotFound(s.pattern)
+		}
+		end = min(end, to)
+	}
+	base := max(start, end-int64(loadSize))
+	n, err := s.r.ReadAt(s.bytes[:end-base], base)
 	if err != nil && err != io.EOF {
 		return -1, err
 	}
 	if n == 0 {
 		return -1, errNotFound(s.pattern)
 	}
-	if s.cursor == int64(n) {
-		s.cursor = 0
+	if base == start {
+		s.cursor = start
 	} else {
 		s.cursor = base + int64(len(target)-1)
 	}
@@ -1,6 +1,7 @@
 package searcher
 
 import (
+	"slices"
 	"strings"
 	"testing"
 )
@@ -179,3 +180,80 @@ func TestSearcher(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchLength(t *testing.T) {
+	for pattern, expected := range map[string]int{
+		"abc": 3, `a\x00あ`: 5, "0x1234ab": 3, "0b0101": 1, "0xzz": 0,
+	} {
+		if got := MatchLength(pattern); got != expected {
+			t.Errorf("MatchLength(%q) should be %d but got %d", pattern, expected, got)
+		}
+	}
+}
+
+// rangedReader reads the strings at the offsets, and zeros between them.
+type rangedReader []struct {
+	offset int64
+	str    string
+}
+
+func (r rangedReader) ReadAt(b []byte, offset int64) (int, error) {
+	clear(b)
+	for _, x := range r {
+		if from, to := max(x.offset, offset), min(x.offset+int64(len(x.str)), offset+int64(len(b))); from < to {
+			copy(b[from-offset:], x.str[from-x.offset:to-x.offset])
+		}
+	}
+	return len(b), nil
+}
+
+func (r rangedReader) Range(offset int64, forward bool) (int64, int64) {
+	if forward {
+		for _, x := range r {
+			if offset < x.offset+int64(len(x.str)) {
+				return x.offset, x.offset + int64(len(x.str))
+			}
+		}
+	} else {
+		for _, x := range slices.Backward(r) {
+			if x.offset < offset {
+				return x.offset, x.offset + int64(len(x.str))
+			}
+		}
+	}
+	return offset, offset
+}
+
+func TestSearcherRanger(t *testing.T) {
+	r := rangedReader{{0, "xabx"}, {1 << 40, "abcd"}, {1 << 50, "cdef"}}
+	testCases := []struct {
+		cursor   int64
+		pattern  string
+		forward  bool
+		expected int64
+		err      error
+	}{
+		{1, "ab", true, 1 << 40, nil},
+		{0, "cd", true, 1<<40 + 2, nil},
+		{1<<40 + 2, "cd", true, 1 << 50, nil},
+		{0, `\0`, true, 0, errNotFound(`\0`)},
+		{0, "bc", true, 1<<40 + 1, nil},
+		{1 << 50, "ab", false, 1 << 40, nil},
+		{1 << 40, "ab", false, 1, nil},
+		{1<<50 + 3, `\0`, false, 0, errNotFound(`\0`)},
+		{1 << 40, "xa", false, 0, nil},
+	}
+	for _, tc := range testCases {
+		s := NewSearcher(r)
+		switch x := (<-s.Search(tc.cursor, tc.pattern, tc.forward)).(type) {
+		case error:
+			if x != tc.err {
+				t.Errorf("Search(%#x, %q, %t) should return %#x but got %v", tc.cursor, tc.pattern, tc.forward, tc.expected, x)
+			}
+		case int64:
+			if tc.err != nil || x != tc.expected {
+				t.Errorf("Search(%#x, %q, %t) should return %#x but got %#x", tc.cursor, tc.pattern, tc.forward, tc.expected, x)
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"github.com/itchyny/bed/highlight"
 	"github.com/itchyny/bed/layout"
 	"github.com/itchyny/bed/mode"
 )
@@ -11,11 +12,14 @@ type State struct {
 	PrevMode          mode.Mode
 	WindowStates      map[int]*WindowState
 	Layout            layout.Layout
+	Tabs              []TabState
 	Cmdline           []rune
 	CmdlineCursor     int
 	CompletionResults []string
 	CompletionIndex   int
 	SearchMode        rune
+	Highlights        highlight.Highlights
+	ColorBytes        bool
 	Error             error
 	ErrorType         int
 }
@@ -24,6 +28,10 @@ type State struct {
 type WindowState struct {
 	Name          string
 	Modified      bool
+	Readonly      bool
+	Reading       bool
+	ReadSize      int64
+	Codec         string
 	Width         int
 	Offset        int64
 	Cursor        int64
@@ -35,9 +43,26 @@ type WindowState struct {
 	PendingByte   byte
 	VisualStart   int64
 	EditedIndices []int64
+	Unmapped      []int64
+	MatchStart    int64
+	MatchEnd      int64
+	TextEncoding  string
+	OffsetBase    string
+	AddressDelta  int64
+	StatusLine    string
+	FileType      string
+	CursorBytes   []byte
 	FocusText     bool
 }
 
+// TabState holds the state of one tab page.
+type TabState struct {
+	Name     string // the name of the active window
+	Windows  int
+	Modified bool
+	Active   bool
+}
+
 // Message types
 const (
 	MessageInfo = iota
@@ -0,0 +1,238 @@
+// Package statusline implements the format language of the statusline option.
+package statusline
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/state"
+)
+
+// Format is the parsed format of the status line.
+type Format []item
+
+type item struct {
+	kind byte   // the item character, or 0 for the literal text
+	text string // the literal text, or the value type of %{...}
+}
+
+// Parse the format of the status line. The items are
+//
+//	%f  the file name
+//	%m  [+] if the buffer is modified
+//	%r  [RO] if the buffer is readonly
+//	%M  the mode, like [VISUAL]
+//	%o  the cursor offset in decimal
+//	%O  the cursor address in hexadecimal
+//	%L  the length in decimal
+//	%b  the byte at the cursor in decimal
+//	%B  the byte at the cursor in hexadecimal
+//	%p  the percentage of the cursor in the file
+//	%s  the size of the selection in visual mode
+//	%y  the file type detected by the magic bytes, like [png]
+//	%{} the value at the cursor, like %{u32le}, %{i16be} and %{f64le}
+//	%=  the separation point of the left and right aligned items
+//	%%  the percent sign
+func Parse(src string) (Format, error) {
+	var f Format
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			f = append(f, item{text: sb.String()})
+			sb.Reset()
+		}
+	}
+	for i := 0; i < len(src); i++ {
+		if src[i] != '%' {
+			sb.WriteByte(src[i])
+			continue
+		}
+		if i++; i == len(src) {
+			return nil, errors.New("unterminated item in statusline")
+		}
+		switch c := src[i]; c {
+		case '%':
+			sb.WriteByte('%')
+		case 'f', 'm', 'r', 'M', 'o', 'O', 'L', 'b', 'B', 'p', 's', 'y', '=':
+			flush()
+			f = append(f, item{kind: c})
+		case '{':
+			j := strings.IndexByte(src[i:], '}')
+			if j < 0 {
+				return nil, errors.New("unterminated item in statusline")
+			}
+			typ := src[i+1 : i+j]
+			if _, ok := valueSize(typ); !ok {
+				return nil, errors.New("invalid value type in statusline: " + typ)
+			}
+			flush()
+			f = append(f, item{kind: c, text: typ})
+			i += j
+		default:
+			return nil, fmt.Errorf("invalid item in statusline: %%%c", c)
+		}
+	}
+	flush()
+	return f, nil
+}
+
+// valueSize returns the byte size of the value type, like u8, i16le and f32be.
+func valueSize(typ string) (int, bool) {
+	if typ == "u8" || typ == "i8" {
+		return 1, true
+	}
+	typ, ok := strings.CutSuffix(typ, "le")
+	if !ok {
+		if typ, ok = strings.CutSuffix(typ, "be"); !ok {
+			return 0, false
+		}
+	}
+	switch typ {
+	case "u16", "i16":
+		return 2, true
+	case "u32", "i32", "f32":
+		return 4, true
+	case "u64", "i64", "f64":
+		return 8, true
+	default:
+		return 0, false
+	}
+}
+
+// Render the status line of the window in the width. The spaces are
+// distributed to the separation points so that the line fills the width.
+func (f Format) Render(s *state.WindowState, width int) string {
+	var sections []string
+	var sb strings.Builder
+	for _, it := range f {
+		if it.kind == '=' {
+			sections = append(sections, sb.String())
+			sb.Reset()
+			continue
+		}
+		sb.WriteString(it.render(s))
+	}
+	sections = append(sections, sb.String())
+	var total int
+	for _, section := range sections {
+		total += runewidth.StringWidth(section)
+	}
+	pad := max(width-total, 0)
+	for i := range sections[:len(sections)-1] {
+		n := pad / (len(sections) - 1)
+		if i < pad%(len(sections)-1) {
+			n++
+		}
+		sections[i] += strings.Repeat(" ", n)
+	}
+	return strings.Join(sections, "")
+}
+
+func (it item) render(s *state.WindowState) string {
+	switch it.kind {
+	case 0:
+		return it.text
+	case 'f':
+		if s.Name == "" {
+			return "[No name]"
+		}
+		return s.Name
+	case 'm':
+		if s.Modified {
+			return "[+]"
+		}
+	case 'r':
+		if s.Readonly {
+			return "[RO]"
+		}
+	case 'M':
+		switch s.Mode {
+		case mode.Insert:
+			return "[INSERT]"
+		case mode.Replace:
+			return "[REPLACE]"
+		case mode.Visual:
+			return "[VISUAL]"
+		}
+	case 'o':
+		return strconv.FormatInt(s.Cursor, 10)
+	case 'O':
+		if address := s.Cursor + s.AddressDelta; address < 0 {
+			return "-0x" + strconv.FormatInt(-address, 16)
+		}
+		return "0x" + strconv.FormatInt(s.Cursor+s.AddressDelta, 16)
+	case 'L':
+		return strconv.FormatInt(s.Length, 10)
+	case 'b', 'B':
+		if i := int(s.Cursor - s.Offset); 0 <= i && i < s.Size {
+			if it.kind == 'b' {
+				return strconv.Itoa(int(s.Bytes[i]))
+			}
+			return fmt.Sprintf("0x%02x", s.Bytes[i])
+		}
+	case 'p':
+		return fmt.Sprintf("%.2f%%", float64(s.Cursor*100)/float64(max(s.Length, 1)))
+	case 's':
+		if size := SelectionSize(s); size > 0 {
+			return strconv.FormatInt(size, 10)
+		}
+	case 'y':
+		if s.FileType != "" {
+			return "[" + s.FileType + "]"
+		}
+	case '{':
+		return renderValue(it.text, s.CursorBytes)
+	}
+	return ""
+}
+
+// SelectionSize returns the size of the selection in visual mode, or 0.
+func SelectionSize(s *state.WindowState) int64 {
+	if s.Mode != mode.Visual || s.VisualStart < 0 {
+		return 0
+	}
+	from, to := min(s.VisualStart, s.Cursor), max(s.VisualStart, s.Cursor)
+	return min(to, max(s.Length-1, 0)) - from + 1
+}
+
+func renderValue(typ string, bs []byte) string {
+	size, _ := valueSize(typ)
+	if len(bs) < size {
+		return ""
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if strings.HasSuffix(typ, "be") {
+		order = binary.BigEndian
+	}
+	var u uint64
+	switch size {
+	case 1:
+		u = uint64(bs[0])
+	case 2:
+		u = uint64(order.Uint16(bs))
+	case 4:
+		u = uint64(order.Uint32(bs))
+	default:
+		u = order.Uint64(bs)
+	}
+	switch typ[0] {
+	case 'i':
+		// Extend the sign bit of the value.
+		shift := 64 - size*8
+		return strconv.FormatInt(int64(u<<shift)>>shift, 10)
+	case 'f':
+		if size == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(uint32(u))), 'g', -1, 32)
+		}
+		return strconv.FormatFloat(math.Float64frombits(u), 'g', -1, 64)
+	default:
+		return strconv.FormatUint(u, 10)
+	}
+}
@@ -0,0 +1,81 @@
+package statusline
+
+import (
+	"testing"
+
+	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/state"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		src string
+		err string
+	}{
+		{"", ""},
+		{"%f%m %r%=%o/%L 100%%", ""},
+		{"%{u8} %{i16be} %{u32le} %{f64be}", ""},
+		{"%", "unterminated item in statusline"},
+		{"%{u32le", "unterminated item in statusline"},
+		{"%{u32}", "invalid value type in statusline: u32"},
+		{"%{u8le}", "invalid value type in statusline: u8le"},
+		{"%{f16le}", "invalid value type in statusline: f16le"},
+		{"%x", "invalid item in statusline: %x"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			_, err := Parse(tc.src)
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("err should be nil but got: %v", err)
+				}
+			} else if err == nil || err.Error() != tc.err {
+				t.Errorf("err should be %q but got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	s := &state.WindowState{
+		Name:         "test.bin",
+		Modified:     true,
+		Offset:       16,
+		Cursor:       18,
+		Bytes:        []byte("\x00\x01\xfe\xff\x00\x00\x80\x3f\x01"),
+		Size:         9,
+		Length:       40,
+		Mode:         mode.Visual,
+		VisualStart:  21,
+		AddressDelta: 0x1000,
+		FileType:     "elf",
+		CursorBytes:  []byte("\xfe\xff\x00\x00\x80\x3f\x01"),
+	}
+	testCases := []struct {
+		src      string
+		width    int
+		expected string
+	}{
+		{"%f%m%r %M", 0, "test.bin[+] [VISUAL]"},
+		{"%o %O %L %p", 0, "18 0x1012 40 45.00%"},
+		{"%b %B %s %y", 0, "254 0xfe 4 [elf]"},
+		{"%{u8} %{i8} %{u16le} %{i16le} %{u16be}", 0, "254 -2 65534 -2 65279"},
+		{"%{i32le} %{u32be} %{f32be}", 0, "65534 4278124544 -1.6947657e+38"},
+		{"%{f32le}", 0, "9.1833e-41"},
+		{"%{u64le}", 0, ""},
+		{"%f%=%o", 15, "test.bin     18"},
+		{"%=%o%=%L", 9, "   18  40"},
+		{"100%%", 0, "100%"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.src, func(t *testing.T) {
+			f, err := Parse(tc.src)
+			if err != nil {
+				t.Fatalf("err should be nil but got: %v", err)
+			}
+			if got := f.Render(s, tc.width); got != tc.expected {
+				t.Errorf("Render(%q) should be %q but got %q", tc.src, tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"github.com/gdamore/tcell"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/layout"
+	"github.com/itchyny/bed/mode"
+)
+
+// mouseEvent converts the mouse event to the event of the editor. The mouse
+// is available in normal and visual mode, and the drag is reported with the
+// position in the window on the press, to select the bytes out of the window.
+func (ui *Tui) mouseEvent(ev *tcell.EventMouse) (event.Event, bool) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	if ui.layout == nil {
+		return event.Event{}, false
+	}
+	x, y := ev.Position()
+	buttons := ev.Buttons()
+	available := ui.mode == mode.Normal || ui.mode == mode.Visual
+	mouse := &event.Mouse{X: x, Y: y, Window: -1, Offset: -1}
+	var typ event.Type
+	switch {
+	case buttons&tcell.Button1 != 0 && !ui.mousePressed:
+		if !available {
+			return event.Event{}, false
+		}
+		typ, ui.mousePressed = event.MousePress, true
+		// The status line of the window is the border to drag.
+		if l := ui.windowAt(x, y); l.Index >= 0 && y < l.TopMargin()+l.Height()-1 {
+			mouse.Window = l.Index
+			mouse.Offset, mouse.FocusText = ui.offsetAt(l, x, y, false)
+		}
+		ui.mouseWindow = mouse.Window
+	case buttons&tcell.Button1 != 0, ui.mousePressed:
+		if typ = event.MouseDrag; buttons&tcell.Button1 == 0 {
+			typ, ui.mousePressed = event.MouseRelease, false
+		}
+		if l := ui.layout.Lookup(func(l layout.Window) bool {
+			return l.Index == ui.mouseWindow
+		}); l.Index >= 0 {
+			mouse.Window = l.Index
+			mouse.Offset, mouse.FocusText = ui.offsetAt(l, x, y, true)
+		}
+	case buttons&(tcell.WheelUp|tcell.WheelDown) != 0:
+		if !available {
+			return event.Event{}, false
+		}
+		if typ = event.MouseWheelUp; buttons&tcell.WheelDown != 0 {
+			typ = event.MouseWheelDown
+		}
+		mouse.Window = ui.windowAt(x, y).Index
+	default:
+		return event.Event{}, false
+	}
+	return event.Event{Type: typ, Mouse: mouse}, true
+}
+
+func (ui *Tui) windowAt(x, y int) layout.Window {
+	return ui.layout.Lookup(func(l layout.Window) bool {
+		return l.LeftMargin() <= x && x < l.LeftMargin()+l.Width() &&
+			l.TopMargin() <= y && y < l.TopMargin()+l.Height()
+	})
+}
+
+// offsetAt returns the offset of the byte at the position in the window, and
+// whether the position is on the text column. The position out of the bytes
+// is clamped to the bytes, or the offset is -1 without clamp.
+func (ui *Tui) offsetAt(l layout.Window, x, y int, clamp bool) (int64, bool) {
+	s, ok := ui.windowStates[l.Index]
+	if !ok || s.Width <= 0 {
+		return -1, false
+	}
+	x, y = x-l.LeftMargin(), y-l.TopMargin()-1
+	width, height := s.Width, l.Height()-2
+	offsetStyleWidth := offsetStyleWidth(s)
+	focusText := x >= 3*width+offsetStyleWidth+4
+	column := -1
+	if focusText {
+		column = x - (3*width + offsetStyleWidth + 6)
+	} else if x >= offsetStyleWidth+3 {
+		column = (x - offsetStyleWidth - 3) / 3
+	}
+	if !clamp && (column < 0 || width <= column || y < 0 || height <= y) {
+		return -1, focusText
+	}
+	// The rows out of the window scroll the window while dragging.
+	column, y = min(max(column, 0), width-1), min(max(y, -1), height)
+	return max(s.Offset+int64(y*width+column), 0), focusText
+}
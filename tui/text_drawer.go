@@ -37,6 +37,12 @@ func (d *textDrawer) setByte(b byte, style tcell.Style) {
 	d.screen.SetContent(left, top, rune(b), nil, style)
 }
 
+func (d *textDrawer) setRune(r rune, style tcell.Style) {
+	top := d.region.top + d.top
+	left := d.region.left + d.left + d.offset
+	d.screen.SetContent(left, top, r, nil, style)
+}
+
 func (d *textDrawer) setTop(top int) *textDrawer {
 	d.top = top
 	return d
@@ -2,6 +2,9 @@ package tui
 
 import (
 	"bytes"
+	"encoding/base64"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -9,6 +12,7 @@ import (
 	"github.com/mattn/go-runewidth"
 
 	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/highlight"
 	"github.com/itchyny/bed/key"
 	"github.com/itchyny/bed/layout"
 	"github.com/itchyny/bed/mode"
@@ -17,11 +21,19 @@ import (
 
 // Tui implements UI
 type Tui struct {
-	eventCh chan<- event.Event
-	mode    mode.Mode
-	screen  tcell.Screen
-	waitCh  chan struct{}
-	mu      *sync.Mutex
+	eventCh      chan<- event.Event
+	mode         mode.Mode
+	screen       tcell.Screen
+	layout       layout.Layout
+	windowStates map[int]*state.WindowState
+	mousePressed bool
+	mouseWindow  int
+	highlights   highlight.Highlights
+	colorBytes   bool
+	noColor      bool
+	clipboard    []byte
+	waitCh       chan struct{}
+	mu           *sync.Mutex
 }
 
 // NewTui creates a new Tui.
@@ -35,11 +47,17 @@ func (ui *Tui) Init(eventCh chan<- event.Event) (err error) {
 	defer ui.mu.Unlock()
 	ui.eventCh = eventCh
 	ui.mode = mode.Normal
+	// Disable the colors when NO_COLOR is set, see https://no-color.org.
+	ui.noColor = os.Getenv("NO_COLOR") != ""
 	if ui.screen, err = tcell.NewScreen(); err != nil {
 		return
 	}
 	ui.waitCh = make(chan struct{})
-	return ui.screen.Init()
+	if err = ui.screen.Init(); err != nil {
+		return
+	}
+	ui.screen.EnableMouse()
+	return nil
 }
 
 // Run the Tui.
@@ -48,15 +66,17 @@ func (ui *Tui) Run(kms map[mode.Mode]*key.Manager) {
 		e := ui.screen.PollEvent()
 		switch ev := e.(type) {
 		case *tcell.EventKey:
-			var e event.Event
 			if km, ok := kms[ui.getMode()]; ok {
-				e = km.Press(eventToKey(ev))
-			}
-			if e.Type != event.Nop {
-				ui.eventCh <- e
+				for _, e := range km.Press(eventToKey(ev)) {
+					ui.eventCh <- e
+				}
 			} else {
 				ui.eventCh <- event.Event{Type: event.Rune, Rune: ev.Rune()}
 			}
+		case *tcell.EventMouse:
+			if e, ok := ui.mouseEvent(ev); ok {
+				ui.eventCh <- e
+			}
 		case *tcell.EventResize:
 			if ui.eventCh != nil {
 				ui.eventCh <- event.Event{Type: event.Redraw}
@@ -84,10 +104,25 @@ func (ui *Tui) Redraw(s state.State) error {
 	ui.mu.Lock()
 	defer ui.mu.Unlock()
 	ui.mode = s.Mode
+	ui.layout, ui.windowStates = s.Layout, s.WindowStates
+	if ui.highlights = s.Highlights; ui.highlights == nil {
+		ui.highlights = highlight.Default()
+	}
+	if ui.noColor {
+		ui.highlights = ui.highlights.NoColor()
+	}
+	ui.colorBytes = s.ColorBytes
 	ui.screen.Clear()
 	ui.drawWindows(s.WindowStates, s.Layout)
+	if len(s.Tabs) > 1 {
+		ui.drawTabline(s.Tabs)
+	}
 	ui.drawCmdline(s)
 	ui.screen.Show()
+	if ui.clipboard != nil {
+		ui.writeClipboard(ui.clipboard)
+		ui.clipboard = nil
+	}
 	return nil
 }
 
@@ -104,7 +139,8 @@ func (ui *Tui) drawWindows(windowStates map[int]*state.WindowState, l layout.Lay
 		r := fromLayout(l)
 		if ws, ok := windowStates[l.Index]; ok && r.valid() {
 			ui.newTuiWindow(r).drawWindow(ws,
-				l.Active && ui.mode != mode.Cmdline && ui.mode != mode.Search)
+				l.Active && ui.mode != mode.Cmdline && ui.mode != mode.Search &&
+					ui.mode != mode.Confirm)
 		}
 	case layout.Horizontal:
 		ui.drawWindows(windowStates, l.Top)
@@ -117,12 +153,41 @@ func (ui *Tui) drawWindows(windowStates map[int]*state.WindowState, l layout.Lay
 }
 
 func (ui *Tui) newTuiWindow(region region) *tuiWindow {
-	return &tuiWindow{region: region, screen: ui.screen}
+	return &tuiWindow{region: region, screen: ui.screen,
+		highlights: ui.highlights, colorBytes: ui.colorBytes}
 }
 
 func (ui *Tui) drawVerticalSplit(region region) {
 	for i := range region.height {
-		ui.setLine(region.top+i, region.left+region.width, "|", tcell.StyleDefault.Reverse(true))
+		ui.setLine(region.top+i, region.left+region.width, "|", ui.highlights.Style(highlight.VertSplit))
+	}
+}
+
+// drawTabline draws the labels of the tab pages on the top line. The label
+// shows the number of the windows, + for unsaved changes, and the name.
+func (ui *Tui) drawTabline(tabs []state.TabState) {
+	width, _ := ui.Size()
+	ui.setLine(0, 0, strings.Repeat(" ", width), ui.highlights.Style(highlight.TabLine))
+	var left int
+	for _, t := range tabs {
+		var sb strings.Builder
+		sb.WriteByte(' ')
+		if t.Windows > 1 {
+			sb.WriteString(strconv.Itoa(t.Windows))
+		}
+		if t.Modified {
+			sb.WriteByte('+')
+		}
+		if sb.Len() > 1 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(t.Name + " ")
+		style := ui.highlights.Style(highlight.TabLine)
+		if t.Active {
+			style = ui.highlights.Style(highlight.TabLineSel)
+		}
+		ui.setLine(0, left, sb.String(), style)
+		left += runewidth.StringWidth(sb.String())
 	}
 }
 
@@ -134,9 +199,9 @@ func (ui *Tui) drawCmdline(s state.State) {
 	case s.Error != nil:
 		cmdline = s.Error.Error()
 		if s.ErrorType == state.MessageInfo {
-			style = style.Foreground(tcell.ColorYellow)
+			style = ui.highlights.Style(highlight.InfoMsg)
 		} else {
-			style = style.Foreground(tcell.ColorRed)
+			style = ui.highlights.Style(highlight.ErrorMsg)
 		}
 	case s.Mode == mode.Cmdline:
 		if len(s.CompletionResults) > 0 {
@@ -154,7 +219,16 @@ func (ui *Tui) drawCmdline(s state.State) {
 	default:
 		return
 	}
-	ui.setLine(height-1, 0, cmdline, style)
+	lines := strings.Split(cmdline, "\n")
+	for i, line := range lines {
+		if i < len(lines)-1 {
+			line += strings.Repeat(" ", max(width-runewidth.StringWidth(line), 0))
+		}
+		ui.setLine(height-len(lines)+i, 0, line, style)
+	}
+	if s.Mode == mode.Confirm {
+		ui.screen.ShowCursor(runewidth.StringWidth(lines[len(lines)-1]), height-1)
+	}
 }
 
 func (ui *Tui) drawCompletionResults(results []string, index, width, height int) {
@@ -178,10 +252,33 @@ func (ui *Tui) drawCompletionResults(results []string, index, width, height int)
 		line.WriteString(" ")
 	}
 	line.WriteString(strings.Repeat(" ", max(width-right, 0)))
-	ui.setLine(height-2, 0, line.String(), tcell.StyleDefault.Reverse(true))
+	ui.setLine(height-2, 0, line.String(), ui.highlights.Style(highlight.Completion))
 	if index >= 0 {
 		ui.setLine(height-2, left, " "+results[index]+" ",
-			tcell.StyleDefault.Foreground(tcell.ColorGrey).Reverse(true))
+			ui.highlights.Style(highlight.CompletionSel))
+	}
+}
+
+// SetClipboard copies the bytes to the system clipboard
+// using OSC 52 escape sequence, which also works over SSH.
+// The sequence is written on the next redraw.
+func (ui *Tui) SetClipboard(bs []byte) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.clipboard = bs
+}
+
+// writeClipboard writes the OSC 52 escape sequence with the lock of
+// the screen, so that it is not interleaved with the screen output.
+func (ui *Tui) writeClipboard(bs []byte) {
+	if screen, ok := ui.screen.(interface {
+		Lock()
+		Unlock()
+		TPuts(string)
+	}); ok {
+		screen.Lock()
+		defer screen.Unlock()
+		screen.TPuts("\x1b]52;c;" + base64.StdEncoding.EncodeToString(bs) + "\x07")
 	}
 }
 
@@ -2,8 +2,11 @@ package tui
 
 import (
 	"errors"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gdamore/tcell"
 
@@ -64,12 +67,18 @@ func TestTuiRun(t *testing.T) {
 	go ui.Run(mockKeyManager())
 
 	screen.InjectKey(tcell.KeyRune, 'Z', tcell.ModNone)
-	screen.InjectKey(tcell.KeyRune, 'Q', tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'x', tcell.ModNone)
 	e := <-eventCh
-	if e.Type != event.Rune {
+	if e.Type != event.Rune || e.Rune != 'Z' {
 		t.Errorf("pressing Z should emit event.Rune but got: %+v", e)
 	}
 	e = <-eventCh
+	if e.Type != event.Rune || e.Rune != 'x' {
+		t.Errorf("pressing x should emit event.Rune but got: %+v", e)
+	}
+	screen.InjectKey(tcell.KeyRune, 'Z', tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'Q', tcell.ModNone)
+	e = <-eventCh
 	if e.Type != event.Quit {
 		t.Errorf("pressing ZQ should emit event.Quit but got: %+v", e)
 	}
@@ -78,9 +87,6 @@ func TestTuiRun(t *testing.T) {
 	screen.InjectKey(tcell.KeyRune, '9', tcell.ModNone)
 	screen.InjectKey(tcell.KeyRune, 'j', tcell.ModNone)
 	e = <-eventCh
-	e = <-eventCh
-	e = <-eventCh
-	e = <-eventCh
 	if e.Type != event.CursorDown {
 		t.Errorf("pressing 709j should emit event.CursorDown but got: %+v", e)
 	}
@@ -92,6 +98,33 @@ func TestTuiRun(t *testing.T) {
 	}
 }
 
+func TestTuiRunPending(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	go ui.Run(mockKeyManager())
+
+	for _, r := range "709Z" {
+		screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	select {
+	case e := <-eventCh:
+		t.Errorf("pressing 709Z should not emit any event while pending but got: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+	screen.InjectKey(tcell.KeyRune, 'Q', tcell.ModNone)
+	if e := <-eventCh; e.Type != event.Quit || e.Count != 709 {
+		t.Errorf("pressing 709ZQ should emit event.Quit with count 709 but got: %+v", e)
+	}
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
 func TestTuiEmpty(t *testing.T) {
 	ui := NewTui()
 	eventCh := make(chan event.Event)
@@ -144,6 +177,54 @@ func TestTuiEmpty(t *testing.T) {
 	}
 }
 
+type clipboardScreen struct {
+	tcell.SimulationScreen
+	sync.Mutex
+	output strings.Builder
+}
+
+func (screen *clipboardScreen) TPuts(str string) {
+	if screen.TryLock() {
+		screen.Unlock()
+		panic("TPuts should be called with the lock of the screen")
+	}
+	screen.output.WriteString(str)
+}
+
+func TestTuiSetClipboard(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := &clipboardScreen{SimulationScreen: tcell.NewSimulationScreen("")}
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	ui.SetClipboard([]byte("Hello, world!"))
+	if got := screen.output.String(); got != "" {
+		t.Errorf("output should be empty before redraw but got %q", got)
+	}
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: {Width: 16, Bytes: make([]byte, 16*(height-1)), Size: 16 * (height - 1), Mode: mode.Normal},
+		},
+		Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+	}
+	for range 2 {
+		if err := ui.Redraw(s); err != nil {
+			t.Errorf("ui.Redraw should return nil but got: %v", err)
+		}
+	}
+	if expected, got := "\x1b]52;c;SGVsbG8sIHdvcmxkIQ==\x07", screen.output.String(); got != expected {
+		t.Errorf("output should be %q but got %q", expected, got)
+	}
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
 func TestTuiScrollBar(t *testing.T) {
 	ui := NewTui()
 	eventCh := make(chan event.Event)
@@ -196,6 +277,333 @@ func TestTuiScrollBar(t *testing.T) {
 	}
 }
 
+func TestTuiColorBytes(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: {
+				Width:         16,
+				Cursor:        4,
+				Bytes:         []byte("\x00a \x01\x80\xff"),
+				Size:          6,
+				Length:        6,
+				Mode:          mode.Visual,
+				VisualStart:   2,
+				EditedIndices: []int64{1, 2},
+			},
+		},
+		Layout:     layout.NewLayout(0).Resize(0, 0, width, height-1),
+		Mode:       mode.Visual,
+		ColorBytes: true,
+	}
+	checkStyle := func(x int, fg string, attrs tcell.AttrMask) {
+		t.Helper()
+		_, _, style, _ := screen.GetContent(x, 1)
+		if gotFg, _, gotAttrs := style.Decompose(); gotFg != tcell.GetColor(fg) || gotAttrs != attrs {
+			t.Errorf("style at %d should be %s with %v but got %v with %v", x, fg, attrs, gotFg, gotAttrs)
+		}
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+	checkStyle(10, "grey", tcell.AttrNone)
+	checkStyle(13, "lightseagreen", tcell.AttrNone)
+	checkStyle(16, "green", tcell.AttrUnderline)
+	checkStyle(19, "purple", tcell.AttrUnderline)
+	checkStyle(22, "olive", tcell.AttrUnderline|tcell.AttrReverse)
+	checkStyle(64, "olive", tcell.AttrUnderline|tcell.AttrBold)
+	checkStyle(25, "maroon", tcell.AttrNone)
+	checkStyle(65, "maroon", tcell.AttrNone)
+
+	s.ColorBytes = false
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+	checkStyle(10, "", tcell.AttrNone)
+	checkStyle(13, "lightseagreen", tcell.AttrNone)
+	checkStyle(25, "", tcell.AttrNone)
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
+func TestTuiTextEncoding(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	str := "\x00あいうえおか\xe3\x81\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00あ"
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: {
+				Width:        16,
+				Bytes:        []byte(str),
+				Size:         len(str),
+				Length:       int64(len(str)),
+				Mode:         mode.Normal,
+				TextEncoding: "utf-8",
+			},
+		},
+		Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+
+	shouldContain(t, screen, []string{
+		" 000000 | 00 e3 81 82 e3 81 84 e3 81 86 e3 81 88 e3 81 8a | .あ-い-う-え-お- ",
+		" 000010 | e3 81 8b e3 81 00 00 00 00 00 00 00 00 00 00 e3 | か-............> ",
+		" 000020 | 81 82                                           | -- ",
+	})
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
+func TestTuiOffsetBase(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: {
+				Width:        8,
+				Cursor:       9,
+				Bytes:        []byte(strings.Repeat("a", 20)),
+				Size:         20,
+				Length:       20,
+				Mode:         mode.Normal,
+				OffsetBase:   "oct",
+				AddressDelta: 0o100,
+			},
+		},
+		Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+
+	shouldContain(t, screen, []string{
+		"        |  0  1  2  3  4  5  6  7 |",
+		" 000100 | 61 61 61 61 61 61 61 61 | aaaaaaaa ",
+		" 000110 | 61 61 61 61 61 61 61 61 | aaaaaaaa ",
+		" 000120 | 61 61 61 61             | aaaa ",
+		"9/20 : 0o000111/0o000124 : 45.00%",
+	})
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
+func TestTuiReading(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: {
+				Name:       "test",
+				Reading:    true,
+				ReadSize:   12345,
+				Width:      16,
+				Bytes:      []byte(strings.Repeat("a", 32)),
+				Size:       32,
+				Length:     32,
+				Mode:       mode.Normal,
+				OffsetBase: "hex",
+			},
+		},
+		Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+
+	shouldContain(t, screen, []string{
+		" test : reading... 12345 bytes : 0x61 : 'a'",
+	})
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
+func TestTuiStatusLine(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: {
+				Name:        "test",
+				Width:       16,
+				Cursor:      2,
+				Bytes:       []byte("\x01\x02\x03\x04\x05\x06"),
+				Size:        6,
+				Length:      6,
+				Mode:        mode.Visual,
+				VisualStart: 5,
+			},
+		},
+		Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+	shouldContain(t, screen, []string{
+		" [VISUAL] test : 0x03 : '\\x03'",
+		"4 bytes selected : 2/6 : 0x000002/0x000006 : 33.33% ",
+	})
+
+	s.WindowStates[0].StatusLine = "%f%m [%s]%=%B %{u16be}"
+	s.WindowStates[0].CursorBytes = []byte("\x03\x04\x05\x06")
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+	shouldContain(t, screen, []string{
+		" test [4]" + strings.Repeat(" ", 72) + "0x03 772 ",
+	})
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
+func TestTuiTabline(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			1: {
+				Name:   "test",
+				Width:  16,
+				Bytes:  []byte("\x01\x02\x03"),
+				Size:   3,
+				Length: 3,
+			},
+		},
+		Layout: layout.NewLayout(1).Resize(0, 1, width, height-2),
+		Tabs: []state.TabState{
+			{Name: "foo", Windows: 2, Modified: true},
+			{Name: "test", Windows: 1, Active: true},
+			{Name: "[No Name]", Windows: 1},
+		},
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+	got := strings.Split(getContents(screen), "\n")
+	if expected := " 2+ foo  test  [No Name] "; strings.TrimRight(got[0], " ") != strings.TrimRight(expected, " ") {
+		t.Errorf("tabline should be %q but got %q", expected, got[0])
+	}
+	if _, _, style, _ := screen.GetContent(10, 0); style != tcell.StyleDefault.Bold(true) {
+		t.Errorf("active tab label should be bold")
+	}
+	shouldContain(t, screen, []string{
+		"  |  0  1  2  3  4  5  6  7  8  9  a  b  c  d  e  f |",
+		" 000000 | 01 02 03",
+	})
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
+func TestTuiMouse(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: {
+				Width:  16,
+				Bytes:  []byte(strings.Repeat("a", 100)),
+				Size:   100,
+				Length: 100,
+				Mode:   mode.Normal,
+			},
+		},
+		Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+
+	for _, tc := range []struct {
+		x, y     int
+		buttons  tcell.ButtonMask
+		expected event.Event
+	}{
+		{13, 2, tcell.Button1, event.Event{Type: event.MousePress,
+			Mouse: &event.Mouse{X: 13, Y: 2, Window: 0, Offset: 17}}},
+		{62, 3, tcell.Button1, event.Event{Type: event.MouseDrag,
+			Mouse: &event.Mouse{X: 62, Y: 3, Window: 0, Offset: 34, FocusText: true}}},
+		{62, 19, tcell.ButtonNone, event.Event{Type: event.MouseRelease,
+			Mouse: &event.Mouse{X: 62, Y: 19, Window: 0, Offset: 274, FocusText: true}}},
+		{5, 5, tcell.WheelDown, event.Event{Type: event.MouseWheelDown,
+			Mouse: &event.Mouse{X: 5, Y: 5, Window: 0, Offset: -1}}},
+		{5, 18, tcell.Button1, event.Event{Type: event.MousePress,
+			Mouse: &event.Mouse{X: 5, Y: 18, Window: -1, Offset: -1}}},
+	} {
+		screen.InjectMouse(tc.x, tc.y, tc.buttons, tcell.ModNone)
+		if e := <-eventCh; !reflect.DeepEqual(e, tc.expected) {
+			t.Errorf("mouse event should be %+v, %+v but got %+v, %+v",
+				tc.expected, tc.expected.Mouse, e, e.Mouse)
+		}
+	}
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
 func TestTuiHorizontalSplit(t *testing.T) {
 	ui := NewTui()
 	eventCh := make(chan event.Event)
@@ -368,6 +776,20 @@ func TestTuiCmdline(t *testing.T) {
 	if !strings.HasPrefix(got, expected) {
 		t.Errorf("cmdline should start with %q but got %q", expected, got)
 	}
+
+	s = state.State{
+		Mode:  mode.Normal,
+		Error: errors.New("info1\ninfo2"),
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+
+	got, expected = getCmdline(), "info2 "
+	if !strings.HasPrefix(got, expected) {
+		t.Errorf("cmdline should start with %q but got %q", expected, got)
+	}
+	shouldContain(t, screen, []string{"info1               \ninfo2"})
 	if err := ui.Close(); err != nil {
 		t.Errorf("ui.Close should return nil but got %v", err)
 	}
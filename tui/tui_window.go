@@ -5,14 +5,20 @@ import (
 	"fmt"
 
 	"github.com/gdamore/tcell"
+	"github.com/mattn/go-runewidth"
 
+	"github.com/itchyny/bed/charset"
+	"github.com/itchyny/bed/highlight"
 	"github.com/itchyny/bed/mode"
 	"github.com/itchyny/bed/state"
+	"github.com/itchyny/bed/statusline"
 )
 
 type tuiWindow struct {
-	region region
-	screen tcell.Screen
+	region     region
+	screen     tcell.Screen
+	highlights highlight.Highlights
+	colorBytes bool
 }
 
 func (ui *tuiWindow) getTextDrawer() *textDrawer {
@@ -24,14 +30,36 @@ func (ui *tuiWindow) setCursor(line, offset int) {
 }
 
 func offsetStyleWidth(s *state.WindowState) int {
-	threshold := int64(0xfffff)
-	for i := range 10 {
-		if s.Length <= threshold {
-			return 6 + i
-		}
-		threshold = (threshold << 4) | 0x0f
+	return max(len(formatAddress(s.Length+s.AddressDelta, s.OffsetBase, 0))+1,
+		len(formatAddress(s.AddressDelta, s.OffsetBase, 0))+1, 6)
+}
+
+// formatAddress formats the address in the base of the offsetbase option.
+func formatAddress(address int64, base string, width int) string {
+	switch base {
+	case "dec":
+		return fmt.Sprintf("%0*d", width, address)
+	case "oct":
+		return fmt.Sprintf("%0*o", width, address)
+	default:
+		return fmt.Sprintf("%0*x", width, address)
+	}
+}
+
+// formatPrefixedAddress formats the address with the prefix of the base.
+func formatPrefixedAddress(address int64, base string, width int) string {
+	var sign, prefix string
+	if address < 0 {
+		sign, address = "-", -address
+	}
+	switch base {
+	case "dec":
+	case "oct":
+		prefix = "0o"
+	default:
+		prefix = "0x"
 	}
-	return 16
+	return sign + prefix + formatAddress(address, base, width)
 }
 
 func (ui *tuiWindow) drawWindow(s *state.WindowState, active bool) {
@@ -43,54 +71,109 @@ func (ui *tuiWindow) drawWindow(s *state.WindowState, active bool) {
 	for 0 < len(eis) && eis[1] <= s.Offset {
 		eis = eis[2:]
 	}
-	editedColor := tcell.ColorLightSeaGreen
+	uis := s.Unmapped
+	hs := ui.highlights
+	runes := charset.Render(s.TextEncoding, s.Bytes[:s.Size], s.Offset)
 	d := ui.getTextDrawer()
 	var k int
 	for i := range height {
+		var covered bool
 		d.addTop(1).setLeft(0).setOffset(0)
-		d.setString(
-			fmt.Sprintf(" %0*x", offsetStyleWidth, s.Offset+int64(i*width)),
-			tcell.StyleDefault.Bold(i == cursorLine),
-		)
+		h := hs[highlight.Offset]
+		if i == cursorLine {
+			h = h.Merge(hs[highlight.CursorOffset])
+		}
+		d.setString(" "+formatAddress(s.Offset+int64(i*width)+s.AddressDelta, s.OffsetBase, offsetStyleWidth), h.Style())
 		d.setLeft(offsetStyleWidth + 3)
 		for j := range width {
-			b, style := byte(0), tcell.StyleDefault
+			cover := covered
+			covered = false
+			b, r, h, unmapped := byte(0), rune(0), highlight.Highlight{}, false
 			if s.Pending && i*width+j == cursorPos {
-				b, style = s.PendingByte, tcell.StyleDefault.Foreground(editedColor)
+				b, h = s.PendingByte, hs[highlight.EditedByte]
+				r = charset.Render(s.TextEncoding, []byte{b}, 0)[0]
 				if s.Mode != mode.Replace {
 					k--
 				}
 			} else if k >= s.Size {
 				if k == cursorPos {
-					d.setOffset(3*j+1).setByte(' ', tcell.StyleDefault.Underline(!active || s.FocusText))
-					d.setOffset(3*width+j+3).setByte(' ', tcell.StyleDefault.Underline(!active || !s.FocusText))
+					var h1, h2 highlight.Highlight
+					if !active || s.FocusText {
+						h1 = hs[highlight.CursorInactive]
+					}
+					if !active || !s.FocusText {
+						h2 = hs[highlight.CursorInactive]
+					}
+					d.setOffset(3*j+1).setByte(' ', h1.Style())
+					d.setOffset(3*width+j+3).setByte(' ', h2.Style())
 				}
 				k++
 				continue
 			} else {
-				b = s.Bytes[k]
+				b, r = s.Bytes[k], runes[k]
 				pos := int64(k) + s.Offset
+				if ui.colorBytes {
+					h = hs[highlight.ByteClass(b)]
+				}
 				if 0 < len(eis) && eis[0] <= pos && pos < eis[1] {
-					style = tcell.StyleDefault.Foreground(editedColor)
+					h = h.Merge(hs[highlight.EditedByte])
 				} else if 0 < len(eis) && eis[1] <= pos {
 					eis = eis[2:]
 				}
+				for 0 < len(uis) && uis[1] <= pos {
+					uis = uis[2:]
+				}
+				if unmapped = 0 < len(uis) && uis[0] <= pos; unmapped {
+					h = hs[highlight.Unmapped]
+				}
+				if s.MatchStart <= pos && pos < s.MatchEnd {
+					h = h.Merge(hs[highlight.Search])
+				}
 				if s.VisualStart >= 0 && s.Cursor < s.Length &&
 					(s.VisualStart <= pos && pos <= s.Cursor ||
 						s.Cursor <= pos && pos <= s.VisualStart) {
-					style = style.Underline(true)
+					h = h.Merge(hs[highlight.Visual])
 				}
 			}
-			style1, style2 := style, style
+			h1, h2 := h, h
 			if i*width+j == cursorPos {
-				style1 = style1.Reverse(active && !s.FocusText).Bold(
-					!active || s.FocusText).Underline(!active || s.FocusText)
-				style2 = style2.Reverse(active && s.FocusText).Bold(
-					!active || !s.FocusText).Underline(!active || !s.FocusText)
+				if active && !s.FocusText {
+					h1 = h1.Merge(hs[highlight.Cursor])
+				} else {
+					h1 = h1.Merge(hs[highlight.CursorInactive])
+				}
+				if active && s.FocusText {
+					h2 = h2.Merge(hs[highlight.Cursor])
+				} else {
+					h2 = h2.Merge(hs[highlight.CursorInactive])
+				}
+			}
+			style1, style2 := h1.Style(), h2.Style()
+			if unmapped {
+				// The unmapped bytes of the process memory.
+				d.setOffset(3*j+1).setByte('-', style1)
+				d.setOffset(3*j+2).setByte('-', style1)
+				d.setOffset(3*width+j+3).setByte(' ', style2)
+			} else {
+				d.setOffset(3*j+1).setByte(hex[b>>4], style1)
+				d.setOffset(3*j+2).setByte(hex[b&0x0f], style1)
+				switch {
+				case cover:
+					// The cell is covered by the wide character.
+				case r == charset.Continuation:
+					d.setOffset(3*width+j+3).setByte('-', style2)
+				case runewidth.RuneWidth(r) == 2:
+					if covered = j+1 < width && k+1 < s.Size && runes[k+1] == charset.Continuation &&
+						(!s.Pending || i*width+j+1 != cursorPos); !covered {
+						r = '>'
+					}
+					d.setOffset(3*width+j+3).setRune(r, style2)
+				case runewidth.RuneWidth(r) == 0:
+					d.setOffset(3*width+j+3).setByte('.', style2)
+				default:
+					d.setOffset(3*width+j+3).setRune(r, style2)
+				}
 			}
-			d.setOffset(3*j+1).setByte(hex[b>>4], style1)
-			d.setOffset(3*j+2).setByte(hex[b&0x0f], style1)
-			d.setOffset(3*width+j+3).setByte(prettyByte(b), style2)
 			k++
 		}
 		d.setOffset(-2).setByte(' ', tcell.StyleDefault)
@@ -118,7 +201,8 @@ func (ui *tuiWindow) drawWindow(s *state.WindowState, active bool) {
 const hex = "0123456789abcdef"
 
 func (ui *tuiWindow) drawHeader(s *state.WindowState, offsetStyleWidth int) {
-	style := tcell.StyleDefault.Underline(true)
+	h := ui.highlights[highlight.Header]
+	style, cursorStyle := h.Style(), h.Merge(ui.highlights[highlight.CursorHeader]).Style()
 	d := ui.getTextDrawer().setLeft(-1)
 	cursor := int(s.Cursor % int64(s.Width))
 	for range offsetStyleWidth + 2 {
@@ -126,9 +210,17 @@ func (ui *tuiWindow) drawHeader(s *state.WindowState, offsetStyleWidth int) {
 	}
 	d.addLeft(1).setByte('|', style)
 	for i := range s.Width {
+		style := style
 		d.addLeft(1).setByte(' ', style)
-		d.addLeft(1).setByte(" 123456789abcdef"[i>>4], style.Bold(cursor == i))
-		d.addLeft(1).setByte(hex[i&0x0f], style.Bold(cursor == i))
+		if cursor == i {
+			style = cursorStyle
+		}
+		column := formatAddress(int64(i), s.OffsetBase, 0)
+		if len(column) < 2 {
+			column = " " + column
+		}
+		d.addLeft(1).setByte(column[len(column)-2], style)
+		d.addLeft(1).setByte(column[len(column)-1], style)
 	}
 	d.addLeft(1).setByte(' ', style)
 	d.addLeft(1).setByte('|', style)
@@ -147,6 +239,7 @@ func (ui *tuiWindow) drawScrollBar(s *state.WindowState, height, left int) {
 	size := max(total*total/length, 1)
 	pad := (total*total + length - length*size - 1) / max(total-size+1, 1)
 	top := (s.Offset / int64(s.Width) * total) / (length - pad)
+	style := ui.highlights.Style(highlight.ScrollBar)
 	d := ui.getTextDrawer().setLeft(left)
 	for i := range height {
 		var b byte
@@ -155,31 +248,46 @@ func (ui *tuiWindow) drawScrollBar(s *state.WindowState, height, left int) {
 		} else {
 			b = '|'
 		}
-		d.addTop(1).setByte(b, tcell.StyleDefault)
+		d.addTop(1).setByte(b, style)
 	}
 }
 
 func (ui *tuiWindow) drawFooter(s *state.WindowState, offsetStyleWidth int) {
+	if s.StatusLine != "" {
+		if f, err := statusline.Parse(s.StatusLine); err == nil {
+			line := " " + f.Render(s, ui.region.width-2) + " "
+			ui.getTextDrawer().setTop(ui.region.height-1).setString(line, ui.highlights.Style(highlight.StatusLine))
+			return
+		}
+	}
 	var modified string
+	if s.Codec != "" {
+		modified = " [" + s.Codec + "]"
+	}
+	if s.Readonly {
+		modified += " : RO"
+	}
 	if s.Modified {
-		modified = " : +"
+		modified += " : +"
+	}
+	if s.Reading {
+		modified += fmt.Sprintf(" : reading... %d bytes", s.ReadSize)
 	}
 	b := s.Bytes[int(s.Cursor-s.Offset)]
 	left := fmt.Sprintf(" %s%s%s : 0x%02x : '%s'",
 		prettyMode(s.Mode), cmp.Or(s.Name, "[No name]"), modified, b, prettyRune(b))
-	right := fmt.Sprintf("%[1]d/%[2]d : 0x%0[3]*[1]x/0x%0[3]*[2]x : %.2[4]f%% ",
-		s.Cursor, s.Length, offsetStyleWidth, float64(s.Cursor*100)/float64(max(s.Length, 1)))
-	line := fmt.Sprintf("%s  %*s", left, max(ui.region.width-len(left)-2, 0), right)
-	ui.getTextDrawer().setTop(ui.region.height-1).setString(line, tcell.StyleDefault.Reverse(true))
-}
-
-func prettyByte(b byte) byte {
-	switch {
-	case 0x20 <= b && b < 0x7f:
-		return b
-	default:
-		return 0x2e
+	var selection string
+	if size := statusline.SelectionSize(s); size == 1 {
+		selection = "1 byte selected : "
+	} else if size > 1 {
+		selection = fmt.Sprintf("%d bytes selected : ", size)
 	}
+	right := fmt.Sprintf("%s%d/%d : %s/%s : %.2f%% ", selection, s.Cursor, s.Length,
+		formatPrefixedAddress(s.Cursor+s.AddressDelta, s.OffsetBase, offsetStyleWidth),
+		formatPrefixedAddress(s.Length+s.AddressDelta, s.OffsetBase, offsetStyleWidth),
+		float64(s.Cursor*100)/float64(max(s.Length, 1)))
+	line := fmt.Sprintf("%s  %*s", left, max(ui.region.width-len(left)-2, 0), right)
+	ui.getTextDrawer().setTop(ui.region.height-1).setString(line, ui.highlights.Style(highlight.StatusLine))
 }
 
 func prettyRune(b byte) string {
@@ -0,0 +1,142 @@
+package window
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/itchyny/bed/archive"
+	"github.com/itchyny/bed/event"
+)
+
+// expandMemberPath expands the path of the archive in the path of the member
+// of the archive, like archive.zip//path/in/zip. The path is treated as an
+// ordinary path unless the archive is an existing file.
+func expandMemberPath(name string) (string, error) {
+	if archivePath, member, ok := archive.Split(name); ok {
+		archivePath, err := expandPath(archivePath)
+		if err != nil {
+			return "", err
+		}
+		if fi, err := os.Stat(archivePath); err == nil && !fi.IsDir() {
+			return archive.Join(archivePath, member), nil
+		}
+	}
+	return expandPath(name)
+}
+
+// openedArchive is an archive opened to read the members.
+type openedArchive struct {
+	archive *archive.Archive
+	codec   *codec
+	info    os.FileInfo
+}
+
+// openArchive reads the entries of the archive. The compressed archive
+// is read to the end unless interrupted, and the codec is returned to
+// recompress on writing.
+// The opened archive is reused until the file is changed.
+func (m *Manager) openArchive(path string) (*archive.Archive, *codec, error) {
+	fi, err := os.Stat(path)
+	if a, ok := m.archives[path]; ok && err == nil && os.SameFile(a.info, fi) &&
+		a.info.Size() == fi.Size() && a.info.ModTime().Equal(fi.ModTime()) {
+		return a.archive, a.codec, nil
+	}
+	r, err := m.openFile(path, filepath.Base(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	var c *codec
+	if s, ok := r.(*spoolReader); ok {
+		// The decompression of a large archive is aborted by the interrupt.
+		abort := make(chan os.Signal, 1)
+		signal.Notify(abort, os.Interrupt)
+		defer signal.Stop(abort)
+		if err = s.waitDone(abort); err != nil {
+			return nil, nil, err
+		}
+		c = s.codec
+	}
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := archive.Open(r, size)
+	if err != nil {
+		return nil, nil, errors.New(filepath.Base(path) + " is not a zip or tar archive")
+	}
+	if fi != nil {
+		m.archives[path] = openedArchive{a, c, fi}
+	}
+	return a, c, nil
+}
+
+// openMember opens the member of the archive. The listing of the archive
+// is opened when the member name is empty.
+func (m *Manager) openMember(path string) (*window, error) {
+	archivePath, member, _ := archive.Split(path)
+	a, _, err := m.openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	name := archive.Join(filepath.Base(archivePath), member)
+	if member == "" {
+		window, err := newWindow(bytes.NewReader([]byte(a.List())), path, name, m.eventCh, m.redrawCh)
+		if err != nil {
+			return nil, err
+		}
+		window.readonly, window.modifiable = true, false
+		return window, nil
+	}
+	var r readAtSeeker
+	if rd, err := a.Open(member); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		r = bytes.NewReader(nil)
+	} else if sr, ok := rd.(*io.SectionReader); ok {
+		r = sr
+	} else {
+		s := newSpoolReader(rd, nil)
+		s.wait(spoolWait)
+		m.spools = append(m.spools, s)
+		r = s
+	}
+	window, err := newWindow(r, path, name, m.eventCh, m.redrawCh)
+	if err != nil {
+		return nil, err
+	}
+	window.readonly = m.readonly
+	return window, nil
+}
+
+// writeMember rebuilds the archive with the contents of the window as the
+// member, and renames it to the archive.
+func (m *Manager) writeMember(window *window, path string, r *event.Range) (int64, error) {
+	archivePath, member, _ := archive.Split(path)
+	if member == "" {
+		return 0, errors.New("cannot write the listing of " + filepath.Base(archivePath))
+	}
+	a, c, err := m.openArchive(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	var buf bytes.Buffer
+	n, err := window.writeTo(r, &buf)
+	if err != nil {
+		return 0, err
+	}
+	if err = m.replaceFile(archivePath, c, func(w io.Writer) error {
+		return a.Write(w, member, buf.Bytes())
+	}); err != nil {
+		return 0, err
+	}
+	if window.path == path {
+		window.savedChangedTick = window.changedTick
+	}
+	return n, nil
+}
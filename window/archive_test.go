@@ -0,0 +1,139 @@
+package window
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/itchyny/bed/event"
+)
+
+func TestManagerOpenArchive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot overwrite the original file on Windows")
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"README.md", "src/main.go"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		if _, err = w.Write([]byte("Hello, " + name + "!")); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	f, err := createTemp(t.TempDir(), buf.String())
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := newTestManager(t)
+	if err = wm.Open(f.Name() + "//src/main.go"); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	windowStates, _, windowIndex, _ := wm.State()
+	ws := windowStates[windowIndex]
+	if expected := filepath.Base(f.Name()) + "//src/main.go"; ws.Name != expected {
+		t.Errorf("name should be %q but got %q", expected, ws.Name)
+	}
+	if expected := "Hello, src/main.go!"; string(ws.Bytes[:ws.Size]) != expected {
+		t.Errorf("bytes should be %q but got %q", expected, string(ws.Bytes[:ws.Size]))
+	}
+
+	wm.Emit(event.Event{Type: event.DeleteByte})
+	for wm.windows[windowIndex].isReading() {
+		runtime.Gosched()
+	}
+	if _, n, err := wm.write(event.Event{Type: event.Write}); err != nil || n != 18 {
+		t.Errorf("write should write 18 bytes but got: %d, %v", n, err)
+	}
+	if _, n, err := wm.write(event.Event{Type: event.Write, Arg: f.Name() + "//src/lib.go"}); err != nil || n != 18 {
+		t.Errorf("write should write 18 bytes but got: %d, %v", n, err)
+	}
+	bs, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(bs), int64(len(bs)))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	for i, expected := range []string{"Hello, README.md!", "ello, src/main.go!", "ello, src/main.go!"} {
+		r, err := zr.File[i].Open()
+		if err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		if bs, err = io.ReadAll(r); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		if string(bs) != expected {
+			t.Errorf("member %s should be %q but got %q", zr.File[i].Name, expected, string(bs))
+		}
+	}
+
+	if err = wm.edit(event.Event{Type: event.Edit, Arg: f.Name() + "//"}); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	windowStates, _, windowIndex, _ = wm.State()
+	ws = windowStates[windowIndex]
+	if expected := "src/lib.go\n"; !bytes.HasSuffix(ws.Bytes[:ws.Size], []byte(expected)) {
+		t.Errorf("bytes should end with %q but got %q", expected, string(ws.Bytes[:ws.Size]))
+	}
+	if _, _, err := wm.write(event.Event{Type: event.Write, Bang: true}); err == nil {
+		t.Errorf("err should not be nil")
+	}
+}
+
+func TestManagerOpenArchiveReuse(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, name := range []string{"README.md", "src/main.go"} {
+		contents := "Hello, " + name + "!"
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(len(contents)),
+		}); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	f, err := createTemp(t.TempDir(), buf.String())
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := newTestManager(t)
+	if err = wm.Open(f.Name() + "//README.md"); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	for _, name := range []string{"src/main.go", ""} {
+		if err = wm.edit(event.Event{Type: event.Edit, Arg: f.Name() + "//" + name}); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+	}
+	windowStates, _, windowIndex, _ := wm.State()
+	ws := windowStates[windowIndex]
+	if expected := "src/main.go\n"; !bytes.HasSuffix(ws.Bytes[:ws.Size], []byte(expected)) {
+		t.Errorf("bytes should end with %q but got %q", expected, string(ws.Bytes[:ws.Size]))
+	}
+	if len(wm.prevFiles) != 0 || len(wm.spools) != 1 {
+		t.Errorf("archive should be opened once but got %d previous files and %d spools",
+			len(wm.prevFiles), len(wm.spools))
+	}
+}
@@ -0,0 +1,180 @@
+package window
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/itchyny/bed/buffer"
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/history"
+	"github.com/itchyny/bed/searcher"
+)
+
+// fileChanged reports whether the file has been changed since the file
+// information was recorded, comparing the inode, the size and the mtime.
+func fileChanged(fi, newFi os.FileInfo) bool {
+	return !os.SameFile(fi, newFi) || fi.Size() != newFi.Size() ||
+		!fi.ModTime().Equal(newFi.ModTime())
+}
+
+// emitCheckTime emits the result of checking the files, and the confirmations
+// to reload the changed files. It emits nothing when no file has been changed,
+// unless the check is requested explicitly.
+func (m *Manager) emitCheckTime(all bool) {
+	info, confirms, err := m.checkTime(all)
+	if err != nil {
+		m.eventCh <- event.Event{Type: event.Error, Error: err}
+	} else if info != "" {
+		m.eventCh <- event.Event{Type: event.Info, Error: errors.New(info)}
+	} else if all && len(confirms) == 0 {
+		m.eventCh <- event.Event{Type: event.Redraw}
+	}
+	for _, e := range confirms {
+		m.eventCh <- e
+	}
+}
+
+// checkTime checks whether the files have been changed outside the editor.
+// It checks all the opened files, or the file of the current window. The
+// windows of the changed file are reloaded when the autoread option is
+// enabled and none of them has unsaved changes. Otherwise, it returns the
+// confirmations to reload the file. Each change is reported only once.
+func (m *Manager) checkTime(all bool) (string, []event.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var paths []string
+	if all {
+		for path := range m.files {
+			paths = append(paths, path)
+		}
+		slices.Sort(paths)
+	} else if path := m.windows[m.windowIndex].path; m.opened(path) {
+		paths = append(paths, path)
+	}
+	var infos []string
+	var confirms []event.Event
+	var errs []error
+	for _, path := range paths {
+		f := m.files[path]
+		fi, err := os.Stat(path)
+		if err != nil {
+			if f.info != nil {
+				f.info = nil
+				m.files[path] = f
+				errs = append(errs, errors.New(filepath.Base(path)+" is no longer available"))
+			}
+			continue
+		}
+		if _, ok := processID(path); ok {
+			// The memory of a process changes while running.
+			continue
+		}
+		if f.info != nil && !fileChanged(f.info, fi) {
+			continue
+		}
+		f.info = fi
+		m.files[path] = f
+		var windows []*window
+		var modified, follow bool
+		for _, window := range m.windows {
+			if window.path == path {
+				windows = append(windows, window)
+				modified = modified || window.changedTick != window.savedChangedTick
+				follow = follow || window.follow
+			}
+		}
+		if follow {
+			// The growth of the file is expected in follow mode.
+			continue
+		}
+		if !m.options.autoread || modified {
+			question := filepath.Base(path) + " has been changed since editing started, reload"
+			if modified {
+				question += " and discard the unsaved changes"
+			}
+			confirms = append(confirms, event.Event{Type: event.ConfirmReload,
+				Arg: path, Error: errors.New(question + "? (y/n)")})
+			continue
+		}
+		if err := m.reload(path, windows...); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		infos = append(infos, filepath.Base(path)+" has been reloaded")
+	}
+	if len(errs) > 0 {
+		return "", confirms, errors.Join(errs...)
+	}
+	return strings.Join(infos, "\n"), confirms, nil
+}
+
+// reloadFile reloads the windows of the file, or the file of the current
+// window, discarding the changes. The editor emits the event when the user
+// confirms to reload the file changed outside.
+func (m *Manager) reloadFile(e event.Event) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path := e.Arg
+	if path == "" {
+		path = m.windows[m.windowIndex].path
+	}
+	if !m.opened(path) {
+		return "", errors.New("no file to reload")
+	}
+	var windows []*window
+	for _, window := range m.windows {
+		if window.path == path {
+			windows = append(windows, window)
+		}
+	}
+	if err := m.reload(path, windows...); err != nil {
+		return "", err
+	}
+	return filepath.Base(path) + " has been reloaded", nil
+}
+
+// reload the windows from the file, discarding the changes.
+func (m *Manager) reload(path string, windows ...*window) error {
+	r, err := m.openFile(path, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	for _, window := range windows {
+		if err = window.reload(r, m.options.undofile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reload the buffer from the reader, keeping the cursor position.
+// The history is restored from the undo file when the undofile option is
+// enabled and the undo file matches the file, otherwise it is cleared.
+func (w *window) reload(r readAtSeeker, undofile bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	buffer := buffer.NewBuffer(r)
+	length, err := buffer.Len()
+	if err != nil {
+		return err
+	}
+	offset, cursor := w.offset, min(w.cursor, max(length-1, 0))
+	history := history.NewHistory()
+	history.Push(buffer, offset, cursor, 0)
+	w.buffer, w.history, w.searcher, w.length = buffer, history, searcher.NewSearcher(r), length
+	w.changedTick, w.prevChanged, w.maxChangedTick, w.savedChangedTick = 0, false, 0, 0
+	w.searchTick, w.visualStart = 0, -1
+	w.append, w.replaceByte, w.extending, w.pending = false, false, false, false
+	w.stream, w.codec, w.process = nil, nil, processOf(r)
+	if s, ok := r.(*spoolReader); ok {
+		w.readStream(s)
+	}
+	if undofile && w.codec == nil && w.process == nil {
+		w.loadUndoFile(r)
+	}
+	w.offset, w.cursor = offset, cursor
+	return nil
+}
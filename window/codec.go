@@ -0,0 +1,153 @@
+package window
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os/exec"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// codec represents the compression format of a file. The level is the
+// compression level of gzip and zstd, the block size of bzip2, and the
+// dictionary size of xz. The header of zstd does not tell the level,
+// so the zstd files are written with the default level.
+type codec struct {
+	name      string
+	level     int
+	newReader func(io.Reader) (io.Reader, error)
+	newWriter func(io.Writer, int) (io.WriteCloser, error)
+}
+
+// detectCodec detects the compression format from the magic bytes.
+func detectCodec(r io.ReaderAt) *codec {
+	var bs [32]byte
+	n, _ := r.ReadAt(bs[:], 0)
+	switch b := bs[:n]; {
+	case bytes.HasPrefix(b, []byte{0x1f, 0x8b}):
+		// The extra flags of the header tell the compression level.
+		level := gzip.DefaultCompression
+		if n > 8 {
+			switch b[8] {
+			case 2:
+				level = gzip.BestCompression
+			case 4:
+				level = gzip.BestSpeed
+			}
+		}
+		return &codec{"gz", level, newGzipReader, newGzipWriter}
+	case bytes.HasPrefix(b, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return &codec{"zst", 0, newZstdReader, newZstdWriter}
+	case bytes.HasPrefix(b, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return &codec{"xz", xzDictSize(b), newXzReader, newXzWriter}
+	case n >= 4 && bytes.HasPrefix(b, []byte("BZh")) && '1' <= b[3] && b[3] <= '9':
+		// The header of bzip2 tells the block size, which is the level.
+		return &codec{"bz2", int(b[3] - '0'), newBzip2Reader, newBzip2Writer}
+	default:
+		return nil
+	}
+}
+
+// xzDictSize returns the dictionary size of the LZMA2 filter in the header
+// of the first block. It returns zero for the other filters.
+func xzDictSize(b []byte) int {
+	const streamHeaderSize = 12
+	if len(b) <= streamHeaderSize+1 || b[streamHeaderSize] == 0 {
+		return 0
+	}
+	flags, b := b[streamHeaderSize+1], b[streamHeaderSize+2:]
+	if flags&0x03 != 0 {
+		return 0
+	}
+	// Skip the compressed size and the uncompressed size.
+	for _, bit := range []byte{0x40, 0x80} {
+		if flags&bit != 0 {
+			_, n := binary.Uvarint(b)
+			if n <= 0 {
+				return 0
+			}
+			b = b[n:]
+		}
+	}
+	// The filter ID of LZMA2 is 0x21, and the property size is 1.
+	if len(b) < 3 || b[0] != 0x21 || b[1] != 0x01 || b[2] > 40 {
+		return 0
+	}
+	if b[2] == 40 {
+		return 1<<32 - 1
+	}
+	return (2 | int(b[2]&1)) << (b[2]/2 + 11)
+}
+
+func newGzipReader(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func newGzipWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, level)
+}
+
+func newZstdReader(r io.Reader) (io.Reader, error) {
+	return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+}
+
+func newZstdWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if level == 0 {
+		return zstd.NewWriter(w)
+	}
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+}
+
+func newXzReader(r io.Reader) (io.Reader, error) {
+	return xz.NewReader(r)
+}
+
+func newXzWriter(w io.Writer, dictSize int) (io.WriteCloser, error) {
+	return xz.WriterConfig{DictCap: dictSize}.NewWriter(w)
+}
+
+func newBzip2Reader(r io.Reader) (io.Reader, error) {
+	return bzip2.NewReader(r), nil
+}
+
+// newBzip2Writer compresses with the bzip2 command,
+// since the standard library does not support bzip2 compression.
+func newBzip2Writer(w io.Writer, level int) (io.WriteCloser, error) {
+	cmd := exec.Command("bzip2", "-c", "-"+strconv.Itoa(level))
+	var stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = w, &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &commandWriter{stdin, cmd, &stderr}, nil
+}
+
+type commandWriter struct {
+	io.WriteCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+}
+
+// Close closes the standard input and waits for the command.
+func (w *commandWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+	if err := w.cmd.Wait(); err != nil {
+		if w.stderr.Len() > 0 {
+			return errors.New(string(bytes.TrimSpace(w.stderr.Bytes())))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package window
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/option"
+)
+
+func TestCodec(t *testing.T) {
+	for _, name := range []string{"gz", "zst", "xz", "bz2"} {
+		t.Run(name, func(t *testing.T) {
+			if name == "bz2" {
+				if _, err := exec.LookPath("bzip2"); err != nil {
+					t.Skip("bzip2 command is not found")
+				}
+			}
+			c := &codec{name: name}
+			switch name {
+			case "gz":
+				c.level, c.newReader, c.newWriter = gzip.BestCompression, newGzipReader, newGzipWriter
+			case "zst":
+				c.newReader, c.newWriter = newZstdReader, newZstdWriter
+			case "xz":
+				c.level, c.newReader, c.newWriter = 1<<20, newXzReader, newXzWriter
+			case "bz2":
+				c.level, c.newReader, c.newWriter = 9, newBzip2Reader, newBzip2Writer
+			}
+			var buf bytes.Buffer
+			w, err := c.newWriter(&buf, c.level)
+			if err != nil {
+				t.Fatalf("err should be nil but got: %v", err)
+			}
+			if _, err = w.Write([]byte("Hello, world!")); err != nil {
+				t.Fatalf("err should be nil but got: %v", err)
+			}
+			if err = w.Close(); err != nil {
+				t.Fatalf("err should be nil but got: %v", err)
+			}
+			d := detectCodec(bytes.NewReader(buf.Bytes()))
+			if d == nil || d.name != c.name || d.level != c.level {
+				t.Fatalf("detectCodec should detect %s (level: %d) but got: %+v", c.name, c.level, d)
+			}
+			r, err := d.newReader(&buf)
+			if err != nil {
+				t.Fatalf("err should be nil but got: %v", err)
+			}
+			bs, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("err should be nil but got: %v", err)
+			}
+			if expected := "Hello, world!"; string(bs) != expected {
+				t.Errorf("decompressed bytes should be %q but got %q", expected, string(bs))
+			}
+		})
+	}
+	if c := detectCodec(bytes.NewReader([]byte("Hello, world!"))); c != nil {
+		t.Errorf("detectCodec should return nil but got: %+v", c)
+	}
+	// The header of xz -9, which has the sizes in the block header.
+	xz9 := []byte("\xfd7zXZ\x00\x00\x04\xe6\xd6\xb4\x46\x04\xc0\x0a\x06\x21\x01\x1c\x00")
+	if c := detectCodec(bytes.NewReader(xz9)); c == nil || c.name != "xz" || c.level != 64<<20 {
+		t.Errorf("detectCodec should detect xz (level: %d) but got: %+v", 64<<20, c)
+	}
+}
+
+func TestManagerOpenCompressed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot overwrite the original file on Windows")
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte("Hello, world!")); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	f, err := createTemp(t.TempDir(), buf.String())
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := newTestManager(t)
+	if err = wm.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	windowStates, _, windowIndex, _ := wm.State()
+	ws := windowStates[windowIndex]
+	if expected := "Hello, world!"; string(ws.Bytes[:ws.Size]) != expected {
+		t.Errorf("bytes should be %q but got %q", expected, string(ws.Bytes[:ws.Size]))
+	}
+	if expected := "gz"; ws.Codec != expected {
+		t.Errorf("codec should be %q but got %q", expected, ws.Codec)
+	}
+
+	wm.Emit(event.Event{Type: event.DeleteByte})
+	for wm.windows[windowIndex].isReading() {
+		runtime.Gosched()
+	}
+	if _, n, err := wm.write(event.Event{Type: event.Write}); err != nil || n != 12 {
+		t.Errorf("write should write 12 bytes but got: %d, %v", n, err)
+	}
+	bs, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(bs))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if bs, err = io.ReadAll(r); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if expected := "ello, world!"; string(bs) != expected {
+		t.Errorf("file should be %q but got %q", expected, string(bs))
+	}
+
+	opts, err := option.Parse("binary")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if _, err = wm.SetOption(opts[0], false); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err = wm.edit(event.Event{Type: event.Edit}); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	windowStates, _, windowIndex, _ = wm.State()
+	ws = windowStates[windowIndex]
+	if expected := "\x1f\x8b"; !bytes.HasPrefix(ws.Bytes, []byte(expected)) {
+		t.Errorf("bytes should start with %q but got %q", expected, string(ws.Bytes[:ws.Size]))
+	}
+	if ws.Codec != "" {
+		t.Errorf("codec should be empty but got %q", ws.Codec)
+	}
+}
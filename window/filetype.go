@@ -0,0 +1,51 @@
+package window
+
+// fileTypes lists the magic bytes of the file types at the offsets.
+var fileTypes = []struct {
+	name   string
+	offset int
+	magic  string
+}{
+	{"elf", 0, "\x7fELF"},
+	{"macho", 0, "\xfe\xed\xfa\xce"},
+	{"macho", 0, "\xfe\xed\xfa\xcf"},
+	{"macho", 0, "\xce\xfa\xed\xfe"},
+	{"macho", 0, "\xcf\xfa\xed\xfe"},
+	{"class", 0, "\xca\xfe\xba\xbe"},
+	{"wasm", 0, "\x00asm"},
+	{"pe", 0, "MZ"},
+	{"png", 0, "\x89PNG\r\n\x1a\n"},
+	{"jpeg", 0, "\xff\xd8\xff"},
+	{"gif", 0, "GIF87a"},
+	{"gif", 0, "GIF89a"},
+	{"webp", 8, "WEBP"},
+	{"wav", 8, "WAVE"},
+	{"ogg", 0, "OggS"},
+	{"flac", 0, "fLaC"},
+	{"mp3", 0, "ID3"},
+	{"pdf", 0, "%PDF-"},
+	{"sqlite", 0, "SQLite format 3\x00"},
+	{"zip", 0, "PK\x03\x04"},
+	{"zip", 0, "PK\x05\x06"},
+	{"gz", 0, "\x1f\x8b"},
+	{"zst", 0, "\x28\xb5\x2f\xfd"},
+	{"xz", 0, "\xfd7zXZ\x00"},
+	{"bz2", 0, "BZh"},
+	{"7z", 0, "7z\xbc\xaf\x27\x1c"},
+	{"rar", 0, "Rar!\x1a\x07"},
+	{"tar", 257, "ustar"},
+}
+
+// fileTypeHeadSize is the size of the head of the file to detect the file type.
+const fileTypeHeadSize = 262
+
+// detectFileType detects the file type from the magic bytes at the head.
+func detectFileType(head []byte) string {
+	for _, t := range fileTypes {
+		if len(head) >= t.offset+len(t.magic) &&
+			string(head[t.offset:t.offset+len(t.magic)]) == t.magic {
+			return t.name
+		}
+	}
+	return ""
+}
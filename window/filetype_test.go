@@ -0,0 +1,30 @@
+package window
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectFileType(t *testing.T) {
+	for _, tc := range []struct {
+		head     string
+		expected string
+	}{
+		{"\x7fELF\x02\x01\x01", "elf"},
+		{"\xcf\xfa\xed\xfe\x0c\x00\x00\x01", "macho"},
+		{"MZ\x90\x00", "pe"},
+		{"\x89PNG\r\n\x1a\n\x00\x00", "png"},
+		{"RIFF\x24\x00\x00\x00WAVEfmt ", "wav"},
+		{"SQLite format 3\x00", "sqlite"},
+		{"PK\x03\x04\x14\x00", "zip"},
+		{"\x1f\x8b\x08\x00", "gz"},
+		{strings.Repeat("\x00", 257) + "ustar\x0000", "tar"},
+		{"RIFF", ""},
+		{"Hello, world!", ""},
+		{"", ""},
+	} {
+		if got := detectFileType([]byte(tc.head)); got != tc.expected {
+			t.Errorf("detectFileType(%q) should be %q but got %q", tc.head, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package window
+
+import "time"
+
+// followInterval is the interval of polling the length of the buffer.
+var followInterval = 500 * time.Millisecond
+
+// setFollow starts or stops polling the length of the buffer.
+// The buffer reads the rest of the file, so it grows along with the file.
+func (w *window) setFollow(follow bool) {
+	w.follow = follow
+	if follow && w.followStop == nil {
+		w.followStop = make(chan struct{})
+		go w.followLoop(time.NewTicker(followInterval), w.followStop)
+	} else if !follow && w.followStop != nil {
+		close(w.followStop)
+		w.followStop = nil
+	}
+}
+
+func (w *window) followLoop(ticker *time.Ticker, stop <-chan struct{}) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if w.updateLength(true) {
+				select {
+				case w.redrawCh <- struct{}{}:
+				case <-stop:
+					return
+				}
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// updateLength updates the length of the window when the buffer has grown
+// or shrunk. The cursor at the last byte stays at the end of the buffer when
+// pinned to the end.
+func (w *window) updateLength(pin bool) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.append {
+		return false
+	}
+	length, err := w.buffer.Len()
+	if err != nil || length == w.length {
+		return false
+	}
+	if pin && w.cursor >= w.length-1 {
+		w.cursor = max(length-1, 0)
+	} else {
+		w.cursor = min(w.cursor, max(length-1, 0))
+	}
+	w.length = length
+	return true
+}
+
+// close stops the background goroutines of the window.
+func (w *window) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.setFollow(false)
+	close(w.closeCh)
+}
@@ -0,0 +1,119 @@
+package window
+
+import (
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/itchyny/bed/event"
+)
+
+// originalReader reads the file as of opening. The original bytes of the
+// regions overwritten in place are kept, so that the buffers and the history
+// referring to the file keep reading the same contents.
+type originalReader struct {
+	readAtSeekCloser
+	mu      sync.RWMutex
+	regions []savedRegion
+}
+
+type savedRegion struct {
+	offset int64
+	bytes  []byte
+}
+
+func (r savedRegion) end() int64 {
+	return r.offset + int64(len(r.bytes))
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (r *originalReader) ReadAt(b []byte, offset int64) (int, error) {
+	n, err := r.readAtSeekCloser.ReadAt(b, offset)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, region := range r.regions {
+		if from, to := max(region.offset, offset), min(region.end(), offset+int64(n)); from < to {
+			copy(b[from-offset:to-offset], region.bytes[from-region.offset:])
+		}
+	}
+	return n, err
+}
+
+// save the original bytes of the region before overwriting the file.
+func (r *originalReader) save(offset, length int64) error {
+	bs := make([]byte, length)
+	if _, err := r.ReadAt(bs, offset); err != nil && err != io.EOF {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	start, end := offset, offset+length
+	i := slices.IndexFunc(r.regions, func(r savedRegion) bool { return r.end() >= start })
+	if i < 0 {
+		i = len(r.regions)
+	}
+	j := i
+	for ; j < len(r.regions) && r.regions[j].offset <= end; j++ {
+		start, end = min(start, r.regions[j].offset), max(end, r.regions[j].end())
+	}
+	region := savedRegion{start, make([]byte, end-start)}
+	for _, r := range r.regions[i:j] {
+		copy(region.bytes[r.offset-start:], r.bytes)
+	}
+	copy(region.bytes[offset-start:], bs)
+	r.regions = slices.Replace(r.regions, i, j, region)
+	return nil
+}
+
+// writeInPlace writes the changed regions of the window to the opened file,
+// when the inplace option is enabled or the file is not a regular file, like
+// block devices. It reports false if the file should be written as a new file.
+func (m *Manager) writeInPlace(window *window, path string, r *event.Range, hash io.Writer) (int64, bool, error) {
+	f, ok := m.files[path]
+	// The memory of a process is regular but cannot be written as a new file.
+	_, process := processID(path)
+	regular := f.mode.IsRegular() && !process
+	if !ok || window.path != path || r != nil || window.codec != nil ||
+		!m.options.inplace && regular {
+		return 0, false, nil
+	}
+	size, err := f.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, false, err
+	}
+	if size != window.length {
+		if regular {
+			return 0, false, nil
+		}
+		return 0, false, errors.New("cannot change the size of " + window.name)
+	}
+	dst, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return 0, false, err
+	}
+	var w io.WriterAt = dst
+	if process {
+		w = &processWriter{dst, window.process.regions, window.process.base}
+	}
+	n, err := window.writeChangedTo(w, f.file)
+	if err == nil && !process {
+		err = dst.Sync()
+	}
+	if err != nil {
+		_ = dst.Close()
+		return 0, false, err
+	}
+	if err = dst.Close(); err != nil {
+		return 0, false, err
+	}
+	window.savedChangedTick = window.changedTick
+	m.updateFileInfo(path)
+	if m.options.undofile {
+		if _, err = window.writeTo(nil, hash); err != nil {
+			return 0, false, err
+		}
+	}
+	return n, true, nil
+}
@@ -2,6 +2,7 @@ package window
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
@@ -16,8 +17,8 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 
+	"github.com/itchyny/bed/archive"
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/layout"
 	"github.com/itchyny/bed/state"
@@ -29,19 +30,30 @@ type Manager struct {
 	height          int
 	windows         []*window
 	layout          layout.Layout
+	tabs            []tab
+	tabIndex        int
+	dragLayout      layout.Layout
+	dragX, dragY    int
 	mu              *sync.Mutex
 	windowIndex     int
 	prevWindowIndex int
 	prevDir         string
 	files           map[string]file
+	archives        map[string]openedArchive
+	prevFiles       []*originalReader
+	spools          []*spoolReader
+	options         options
+	windowOptions   windowOptions
+	readonly        bool
 	eventCh         chan<- event.Event
 	redrawCh        chan<- struct{}
 }
 
 type file struct {
 	path string
-	file *os.File
-	perm os.FileMode
+	file *originalReader
+	mode os.FileMode
+	info os.FileInfo
 }
 
 // NewManager creates a new Manager.
@@ -49,10 +61,17 @@ func NewManager() *Manager {
 	return &Manager{}
 }
 
+// SetReadonly sets whether the windows are opened in readonly mode.
+func (m *Manager) SetReadonly(readonly bool) {
+	m.readonly = readonly
+}
+
 // Init initializes the Manager.
 func (m *Manager) Init(eventCh chan<- event.Event, redrawCh chan<- struct{}) {
 	m.eventCh, m.redrawCh = eventCh, redrawCh
 	m.mu, m.files = new(sync.Mutex), make(map[string]file)
+	m.archives = make(map[string]openedArchive)
+	m.windowOptions = defaultWindowOptions()
 }
 
 // Open a new window.
@@ -75,6 +94,7 @@ func (m *Manager) Read(r io.Reader) error {
 
 func (m *Manager) init(window *window) error {
 	m.addWindow(window)
+	m.tabs = []tab{{}}
 	m.layout = layout.NewLayout(m.windowIndex).Resize(0, 0, m.width, m.height)
 	return nil
 }
@@ -86,6 +106,11 @@ func (m *Manager) addWindow(window *window) {
 			return
 		}
 	}
+	window.windowOptions = m.windowOptions
+	if window.process != nil {
+		// The offsets of the process memory are from the start of the first region.
+		window.baseAddress = window.process.base
+	}
 	m.windows = append(m.windows, window)
 	m.windowIndex, m.prevWindowIndex = len(m.windows)-1, m.windowIndex
 }
@@ -96,6 +121,7 @@ func (m *Manager) open(name string) (*window, error) {
 		if err != nil {
 			return nil, err
 		}
+		window.readonly = m.readonly
 		return window, nil
 	}
 	if name == "#" {
@@ -112,18 +138,37 @@ func (m *Manager) open(name string) (*window, error) {
 	if err != nil {
 		return nil, err
 	}
-	path, err := expandPath(name)
+	path, err := expandMemberPath(name)
 	if err != nil {
 		return nil, err
 	}
+	if _, _, ok := archive.Split(path); ok {
+		return m.openMember(path)
+	}
 	r, err := m.openFile(path, name)
 	if err != nil {
 		return nil, err
 	}
-	return newWindow(r, path, filepath.Base(path), m.eventCh, m.redrawCh)
+	if _, ok := processID(path); ok {
+		name = path
+	} else {
+		name = filepath.Base(path)
+	}
+	window, err := newWindow(r, path, name, m.eventCh, m.redrawCh)
+	if err != nil {
+		return nil, err
+	}
+	window.readonly = m.readonly
+	if m.options.undofile && window.codec == nil && window.process == nil {
+		window.loadUndoFile(r)
+	}
+	return window, nil
 }
 
 func (m *Manager) openFile(path, name string) (readAtSeeker, error) {
+	if pid, ok := processID(path); ok {
+		return m.openProcess(path, pid)
+	}
 	fi, err := os.Stat(path)
 	if err != nil {
 		if !os.IsNotExist(err) {
@@ -137,8 +182,20 @@ func (m *Manager) openFile(path, name string) (readAtSeeker, error) {
 	if err != nil {
 		return nil, err
 	}
-	m.addFile(path, f, fi)
-	return f, nil
+	r := &originalReader{readAtSeekCloser: newFileReader(f, fi)}
+	m.addFile(path, r, fi)
+	if !m.options.binary {
+		if c := detectCodec(r); c != nil {
+			// Open the file as is if the header is broken.
+			if d, err := c.newReader(io.NewSectionReader(r, 0, fi.Size())); err == nil {
+				s := newSpoolReader(d, c)
+				s.wait(spoolWait)
+				m.spools = append(m.spools, s)
+				return s, nil
+			}
+		}
+	}
+	return r, nil
 }
 
 func expandBacktick(name string) (string, error) {
@@ -185,18 +242,14 @@ func expandPath(path string) (string, error) {
 }
 
 func (m *Manager) read(r io.Reader) error {
-	bs, err := func() ([]byte, error) {
-		r, stop := newReader(r)
-		defer stop()
-		return io.ReadAll(r)
-	}()
-	if err != nil {
-		return err
-	}
-	window, err := newWindow(bytes.NewReader(bs), "", "", m.eventCh, m.redrawCh)
+	s := newSpoolReader(r, nil)
+	s.wait(spoolWait)
+	m.spools = append(m.spools, s)
+	window, err := newWindow(s, "", "", m.eventCh, m.redrawCh)
 	if err != nil {
 		return err
 	}
+	window.readonly = m.readonly
 	return m.init(window)
 }
 
@@ -206,20 +259,18 @@ type reader struct {
 }
 
 func newReader(r io.Reader) (*reader, func()) {
-	done := make(chan struct{})
 	abort := make(chan os.Signal, 1)
 	signal.Notify(abort, os.Interrupt)
-	go func() {
-		select {
-		case <-time.After(time.Second):
-			fmt.Fprint(os.Stderr, "Reading stdin took more than 1 second, press <C-c> to abort...")
-		case <-done:
-		}
-	}()
 	return &reader{r, abort}, func() {
 		signal.Stop(abort)
-		close(abort)
-		close(done)
+	}
+}
+
+// stop reading at the next read.
+func (r *reader) stop() {
+	select {
+	case r.abort <- os.Interrupt:
+	default:
 	}
 }
 
@@ -243,19 +294,38 @@ func (m *Manager) Resize(width, height int) {
 		m.mu.Lock()
 		defer m.mu.Unlock()
 		m.width, m.height = width, height
-		m.layout = m.layout.Resize(0, 0, width, height)
+		m.layout = m.fit(m.layout)
 	}
 }
 
 // Emit an event to the current window.
 func (m *Manager) Emit(e event.Event) {
+	_, windowIndex := m.current()
 	switch e.Type {
-	case event.Edit:
+	case event.Edit, event.View:
 		if err := m.edit(e); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		} else {
 			m.eventCh <- event.Event{Type: event.Redraw}
 		}
+	case event.CheckTime:
+		if e.Arg != "" {
+			m.eventCh <- event.Event{Type: event.Error, Error: errors.New("too many arguments for " + e.CmdName)}
+		} else {
+			m.emitCheckTime(true)
+		}
+	case event.Reload:
+		if info, err := m.reloadFile(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Info, Error: errors.New(info)}
+		}
+	case event.Attach:
+		if err := m.attach(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
 	case event.Enew:
 		if err := m.enew(e); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
@@ -358,6 +428,54 @@ func (m *Manager) Emit(e event.Event) {
 		} else {
 			m.eventCh <- event.Event{Type: event.Redraw}
 		}
+	case event.IncreaseWindowHeight:
+		m.resizeWindow(int(max(e.Count, 1)), false)
+		m.eventCh <- event.Event{Type: event.Redraw}
+	case event.DecreaseWindowHeight:
+		m.resizeWindow(-int(max(e.Count, 1)), false)
+		m.eventCh <- event.Event{Type: event.Redraw}
+	case event.IncreaseWindowWidth:
+		m.resizeWindow(int(max(e.Count, 1)), true)
+		m.eventCh <- event.Event{Type: event.Redraw}
+	case event.DecreaseWindowWidth:
+		m.resizeWindow(-int(max(e.Count, 1)), true)
+		m.eventCh <- event.Event{Type: event.Redraw}
+	case event.EqualizeWindows:
+		if err := m.wincmd("="); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.Resize, event.VerticalResize:
+		if err := m.resize(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.TabNew:
+		if err := m.tabnew(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.TabNext, event.TabPrevious:
+		if err := m.tabnext(e, e.Type == event.TabPrevious); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.TabClose:
+		if err := m.tabclose(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.Tabs:
+		if str, err := m.tabList(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Info, Error: errors.New(str)}
+		}
 	case event.Pwd:
 		if e.Arg != "" {
 			m.eventCh <- event.Event{Type: event.Error, Error: errors.New("too many arguments for " + e.CmdName)}
@@ -387,22 +505,57 @@ func (m *Manager) Emit(e event.Event) {
 		} else if err := m.quit(event.Event{Bang: e.Bang}); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		}
+	case event.MousePress, event.MouseDrag, event.MouseRelease,
+		event.MouseWheelUp, event.MouseWheelDown:
+		if e.Mouse != nil {
+			m.mouse(e)
+		}
 	default:
-		m.windows[m.windowIndex].emit(e)
+		window, _ := m.current()
+		window.emit(e)
+	}
+	if _, index := m.current(); index != windowIndex {
+		// Check the file of the window on focus.
+		m.emitCheckTime(false)
+	}
+}
+
+// current returns the current window and the index,
+// or nil when no window has been opened yet.
+func (m *Manager) current() (*window, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.windowIndex >= len(m.windows) {
+		return nil, m.windowIndex
 	}
+	return m.windows[m.windowIndex], m.windowIndex
 }
 
 func (m *Manager) edit(e event.Event) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	name := e.Arg
-	if name == "" {
-		name = m.windows[m.windowIndex].path
+	if current := m.windows[m.windowIndex]; name == "" && m.opened(current.path) {
+		if current.changedTick != current.savedChangedTick && !e.Bang {
+			return errors.New("you have unsaved changes in " + current.getName() + ", add ! to force :edit")
+		}
+		if err := m.reload(current.path, current); err != nil {
+			return err
+		}
+		if e.Type == event.View {
+			current.setReadonly(true)
+		}
+		return nil
+	} else if name == "" {
+		name = current.path
 	}
 	window, err := m.open(name)
 	if err != nil {
 		return err
 	}
+	if e.Type == event.View {
+		window.setReadonly(true)
+	}
 	m.addWindow(window)
 	m.layout = m.layout.Replace(m.windowIndex)
 	return nil
@@ -432,9 +585,9 @@ func (m *Manager) newWindow(e event.Event, vertical bool) error {
 	}
 	m.addWindow(window)
 	if vertical {
-		m.layout = m.layout.SplitLeft(m.windowIndex).Resize(0, 0, m.width, m.height)
+		m.layout = m.fit(m.layout.SplitLeft(m.windowIndex))
 	} else {
-		m.layout = m.layout.SplitTop(m.windowIndex).Resize(0, 0, m.width, m.height)
+		m.layout = m.fit(m.layout.SplitTop(m.windowIndex))
 	}
 	return nil
 }
@@ -452,7 +605,7 @@ func (m *Manager) only(e event.Event) error {
 			}
 		}
 	}
-	m.layout = layout.NewLayout(m.windowIndex).Resize(0, 0, m.width, m.height)
+	m.layout = m.fit(layout.NewLayout(m.windowIndex))
 	return nil
 }
 
@@ -499,7 +652,7 @@ func (m *Manager) wincmd(arg string) error {
 		})
 	case "t":
 		m.focus(func(_, y layout.Window) bool {
-			return y.LeftMargin() == 0 && y.TopMargin() == 0
+			return y.LeftMargin() == 0 && y.TopMargin() == m.layout.TopMargin()
 		})
 	case "b":
 		m.focus(func(_, y layout.Window) bool {
@@ -526,6 +679,18 @@ func (m *Manager) wincmd(arg string) error {
 		m.move(func(x layout.Window, y layout.Layout) layout.Layout {
 			return layout.Vertical{Left: y, Right: x}
 		})
+	case "+":
+		m.resizeWindow(1, false)
+	case "-":
+		m.resizeWindow(-1, false)
+	case ">":
+		m.resizeWindow(1, true)
+	case "<":
+		m.resizeWindow(-1, true)
+	case "=":
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.layout = m.layout.Equalize()
 	default:
 		return errors.New("Invalid argument for wincmd: " + arg)
 	}
@@ -551,9 +716,41 @@ func (m *Manager) move(modifier func(layout.Window, layout.Layout) layout.Layout
 	w, h := m.layout.Count()
 	if w != 1 || h != 1 {
 		activeWindow := m.layout.ActiveWindow()
-		m.layout = modifier(activeWindow, m.layout.Close()).Activate(
-			activeWindow.Index).Resize(0, 0, m.width, m.height)
+		m.layout = m.fit(modifier(activeWindow, m.layout.Close()).Activate(
+			activeWindow.Index))
+	}
+}
+
+func (m *Manager) resizeWindow(delta int, vertical bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.layout = m.layout.ResizeActive(delta, vertical)
+}
+
+// resize sets the height, which is the number of the rows of the bytes, or
+// the width of the active window. The size is relative with a sign, and the
+// window is maximized without the argument.
+func (m *Manager) resize(e event.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	vertical := e.Type == event.VerticalResize
+	activeWindow := m.layout.ActiveWindow()
+	size, maxSize := activeWindow.Height()-2, m.height
+	if vertical {
+		size, maxSize = activeWindow.Width(), m.width
+	}
+	delta := maxSize
+	if e.Arg != "" {
+		n, err := strconv.Atoi(e.Arg)
+		if err != nil {
+			return errors.New("invalid argument for " + e.CmdName + ": " + e.Arg)
+		}
+		if delta = n; e.Arg[0] != '+' && e.Arg[0] != '-' {
+			delta -= size
+		}
 	}
+	m.layout = m.layout.ResizeActive(delta, vertical)
+	return nil
 }
 
 func (m *Manager) chdir(e event.Event) (string, error) {
@@ -595,9 +792,14 @@ func (m *Manager) quit(e event.Event) error {
 	}
 	w, h := m.layout.Count()
 	if w == 1 && h == 1 {
-		m.eventCh <- event.Event{Type: event.QuitAll}
+		if len(m.tabs) == 1 {
+			m.eventCh <- event.Event{Type: event.QuitAll}
+		} else {
+			m.closeTab()
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
 	} else {
-		m.layout = m.layout.Close().Resize(0, 0, m.width, m.height)
+		m.layout = m.fit(m.layout.Close())
 		m.windowIndex, m.prevWindowIndex = m.layout.ActiveWindow().Index, m.windowIndex
 		m.eventCh <- event.Event{Type: event.Redraw}
 	}
@@ -620,41 +822,122 @@ func (m *Manager) write(e event.Event) (string, int64, error) {
 		path, name = window.path, window.name
 	} else {
 		var err error
-		path, err = expandPath(name)
+		path, err = expandMemberPath(name)
 		if err != nil {
 			return "", 0, err
 		}
 	}
-	if runtime.GOOS == "windows" && m.opened(path) {
-		return "", 0, errors.New("cannot overwrite the original file on Windows")
+	if window.readonly && window.path == path && !e.Bang {
+		return "", 0, errors.New("readonly option is set (add ! to override)")
+	}
+	if window.path == path && window.isReading() {
+		return "", 0, errors.New("cannot write while reading " + window.name)
 	}
 	if window.path == "" && window.name == "" {
 		window.setPathName(path, filepath.Base(path))
 	}
+	if _, _, ok := archive.Split(path); ok {
+		n, err := m.writeMember(window, path, e.Range)
+		if err != nil {
+			return "", 0, err
+		}
+		return name, n, nil
+	}
+	hash := sha256.New()
+	n, ok, err := m.writeInPlace(window, path, e.Range, hash)
+	if err != nil {
+		return "", 0, err
+	}
+	if !ok {
+		if n, err = m.writeFile(window, path, e.Range, hash); err != nil {
+			return "", 0, err
+		}
+	}
+	if m.options.undofile && window.path == path && e.Range == nil &&
+		window.codec == nil && window.process == nil {
+		if err = window.saveUndoFile(hash.Sum(nil)); err != nil {
+			return "", 0, err
+		}
+	}
+	return name, n, nil
+}
+
+// writeFile writes the window to a temporary file and renames it to the path.
+func (m *Manager) writeFile(window *window, path string, r *event.Range, hash io.Writer) (int64, error) {
+	var c *codec
+	if window.path == path {
+		// Compress with the same codec and level as the original file.
+		c = window.codec
+	}
+	var n int64
+	if err := m.replaceFile(path, c, func(w io.Writer) (err error) {
+		n, err = window.writeTo(r, io.MultiWriter(w, hash))
+		return
+	}); err != nil {
+		return 0, err
+	}
+	if window.path == path {
+		window.savedChangedTick = window.changedTick
+	}
+	return n, nil
+}
+
+// replaceFile writes to a temporary file, compressing with the codec if any,
+// and renames it to the path.
+func (m *Manager) replaceFile(path string, c *codec, write func(io.Writer) error) error {
+	if runtime.GOOS == "windows" && m.opened(path) {
+		return errors.New("cannot overwrite the original file on Windows")
+	}
 	tmpf, err := os.OpenFile(
 		path+"-"+strconv.FormatUint(rand.Uint64(), 36),
 		os.O_RDWR|os.O_CREATE|os.O_EXCL, m.filePerm(path),
 	) //#nosec G404
 	if err != nil {
-		return "", 0, err
+		return err
 	}
 	defer os.Remove(tmpf.Name())
-	n, err := window.writeTo(e.Range, tmpf)
+	var w io.WriteCloser = tmpf
+	if c != nil {
+		if w, err = c.newWriter(tmpf, c.level); err != nil {
+			_ = tmpf.Close()
+			return err
+		}
+	}
+	err = write(w)
+	if err == nil && w != tmpf {
+		err = w.Close()
+	}
 	if err != nil {
 		_ = tmpf.Close()
-		return "", 0, err
+		return err
 	}
 	if err = tmpf.Close(); err != nil {
-		return "", 0, err
+		return err
 	}
-	if window.path == path {
-		window.savedChangedTick = window.changedTick
+	if err = os.Rename(tmpf.Name(), path); err != nil {
+		return err
+	}
+	m.updateFileInfo(path)
+	return nil
+}
+
+func (m *Manager) addFile(path string, f *originalReader, fi os.FileInfo) {
+	// The previous file may still be referred by the buffers and the history.
+	if prev, ok := m.files[path]; ok {
+		m.prevFiles = append(m.prevFiles, prev.file)
 	}
-	return name, n, os.Rename(tmpf.Name(), path)
+	m.files[path] = file{path: path, file: f, mode: fi.Mode(), info: fi}
 }
 
-func (m *Manager) addFile(path string, f *os.File, fi os.FileInfo) {
-	m.files[path] = file{path: path, file: f, perm: fi.Mode().Perm()}
+// updateFileInfo records the file information after writing the file,
+// so that the change is not reported as an external change.
+func (m *Manager) updateFileInfo(path string) {
+	if f, ok := m.files[path]; ok {
+		if fi, err := os.Stat(path); err == nil {
+			f.info = fi
+			m.files[path] = f
+		}
+	}
 }
 
 func (m *Manager) opened(path string) bool {
@@ -664,7 +947,7 @@ func (m *Manager) opened(path string) bool {
 
 func (m *Manager) filePerm(path string) os.FileMode {
 	if f, ok := m.files[path]; ok {
-		return f.perm
+		return f.mode.Perm()
 	}
 	return os.FileMode(0o644)
 }
@@ -695,7 +978,16 @@ func hexWindowWidth(width int) int {
 
 // Close the Manager.
 func (m *Manager) Close() {
+	for _, window := range m.windows {
+		window.close()
+	}
 	for _, f := range m.files {
 		_ = f.file.Close()
 	}
+	for _, f := range m.prevFiles {
+		_ = f.Close()
+	}
+	for _, s := range m.spools {
+		_ = s.Close()
+	}
 }
@@ -7,11 +7,14 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/itchyny/bed/buffer"
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/layout"
 	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/option"
+	"github.com/itchyny/bed/state"
 )
 
 func createTemp(dir, contents string) (*os.File, error) {
@@ -28,6 +31,28 @@ func createTemp(dir, contents string) (*os.File, error) {
 	return f, nil
 }
 
+// newTestManager creates a manager which discards the events and the redraws,
+// and closes it at the end of the test.
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	wm := NewManager()
+	eventCh, redrawCh, doneCh := make(chan event.Event), make(chan struct{}), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			select {
+			case <-eventCh:
+			case <-redrawCh:
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() { close(doneCh); wm.Close() })
+	wm.SetSize(110, 20)
+	return wm
+}
+
 func TestManagerOpenEmpty(t *testing.T) {
 	wm := NewManager()
 	eventCh, redrawCh, waitCh := make(chan event.Event), make(chan struct{}), make(chan struct{})
@@ -600,6 +625,188 @@ func TestManagerWincmd(t *testing.T) {
 	wm.Close()
 }
 
+func TestManagerMouse(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event, 10), make(chan struct{}, 10)
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	if err := wm.Read(strings.NewReader("Hello, world!")); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm.Emit(event.Event{Type: event.Vnew})
+	<-eventCh
+	if _, _, _, err := wm.State(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+
+	wm.Emit(event.Event{Type: event.MousePress, Mode: mode.Normal,
+		Mouse: &event.Mouse{X: 70, Y: 1, Window: 0, Offset: 4, FocusText: true}})
+	wm.Emit(event.Event{Type: event.MouseDrag, Mode: mode.Visual,
+		Mouse: &event.Mouse{X: 75, Y: 1, Window: 0, Offset: 8, FocusText: true}})
+	wm.Emit(event.Event{Type: event.MouseRelease, Mode: mode.Visual,
+		Mouse: &event.Mouse{X: 75, Y: 1, Window: 0, Offset: 8, FocusText: true}})
+	<-redrawCh
+	<-redrawCh
+	windowStates, _, windowIndex, _ := wm.State()
+	if expected := 0; windowIndex != expected {
+		t.Errorf("windowIndex should be %d but got %d", expected, windowIndex)
+	}
+	if ws := windowStates[0]; ws.Cursor != 8 || ws.VisualStart != 4 || !ws.FocusText {
+		t.Errorf("cursor, visual start and focus should be 8, 4 and true but got %d, %d and %v",
+			ws.Cursor, ws.VisualStart, ws.FocusText)
+	}
+
+	wm.Emit(event.Event{Type: event.MousePress, Mode: mode.Normal,
+		Mouse: &event.Mouse{X: 55, Y: 3, Window: -1, Offset: -1}})
+	wm.Emit(event.Event{Type: event.MouseDrag, Mode: mode.Normal,
+		Mouse: &event.Mouse{X: 40, Y: 8, Window: -1, Offset: -1}})
+	wm.Emit(event.Event{Type: event.MouseDrag, Mode: mode.Normal,
+		Mouse: &event.Mouse{X: 30, Y: 9, Window: -1, Offset: -1}})
+	wm.Emit(event.Event{Type: event.MouseRelease, Mode: mode.Normal,
+		Mouse: &event.Mouse{X: 30, Y: 9, Window: -1, Offset: -1}})
+	wm.Emit(event.Event{Type: event.MouseDrag, Mode: mode.Normal,
+		Mouse: &event.Mouse{X: 20, Y: 9, Window: -1, Offset: -1}})
+	wm.Emit(event.Event{Type: event.MouseWheelDown})
+	<-eventCh
+	<-eventCh
+	_, got, _, _ := wm.State()
+	if l := got.Lookup(func(l layout.Window) bool { return l.Index == 1 }); l.Width() != 30 {
+		t.Errorf("width of the window should be %d but got %d", 30, l.Width())
+	}
+	select {
+	case e := <-eventCh:
+		t.Errorf("no event should be emitted but got: %+v", e)
+	case <-redrawCh:
+		t.Errorf("no redraw should be requested")
+	default:
+	}
+	wm.Close()
+}
+
+func TestManagerResize(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event, 10), make(chan struct{}, 10)
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	if err := wm.Read(strings.NewReader("Hello, world!")); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm.Emit(event.Event{Type: event.New})
+	wm.Emit(event.Event{Type: event.Vnew})
+	for range 2 {
+		<-eventCh
+	}
+
+	wm.Emit(event.Event{Type: event.IncreaseWindowHeight, Count: 3})
+	wm.Emit(event.Event{Type: event.DecreaseWindowWidth, Count: 5})
+	wm.Emit(event.Event{Type: event.Resize, CmdName: "res[ize]", Arg: "-2"})
+	wm.Emit(event.Event{Type: event.VerticalResize, CmdName: "res[ize]", Arg: "40"})
+	wm.Emit(event.Event{Type: event.Resize, CmdName: "res[ize]", Arg: "x"})
+	for range 4 {
+		if e := <-eventCh; e.Type != event.Redraw {
+			t.Errorf("event type should be %s but got: %s", event.Redraw, e.Type)
+		}
+	}
+	if e := <-eventCh; e.Type != event.Error || e.Error.Error() != "invalid argument for res[ize]: x" {
+		t.Errorf("resize should emit an error but got: %+v", e)
+	}
+	wm.Resize(220, 40)
+	_, got, _, _ := wm.State()
+	expected := map[int][2]int{0: {220, 18}, 1: {139, 22}, 2: {80, 22}}
+	for i, l := range got.Collect() {
+		if size := [2]int{l.Width(), l.Height()}; size != expected[i] {
+			t.Errorf("size of the window %d should be %v but got %v", i, expected[i], size)
+		}
+	}
+
+	wm.Emit(event.Event{Type: event.EqualizeWindows})
+	<-eventCh
+	_, got, _, _ = wm.State()
+	expected = map[int][2]int{0: {220, 20}, 1: {109, 20}, 2: {110, 20}}
+	for i, l := range got.Collect() {
+		if size := [2]int{l.Width(), l.Height()}; size != expected[i] {
+			t.Errorf("size of the window %d should be %v but got %v", i, expected[i], size)
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerTabs(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event, 10), make(chan struct{}, 10)
+	wm.Init(eventCh, redrawCh)
+	wm.SetSize(110, 20)
+	if err := wm.Read(strings.NewReader("Hello, world!")); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm.Emit(event.Event{Type: event.TabNew})
+	wm.Emit(event.Event{Type: event.Vnew})
+	wm.Emit(event.Event{Type: event.TabNew})
+	for range 3 {
+		if e := <-eventCh; e.Type != event.Redraw {
+			t.Errorf("event type should be %s but got: %s", event.Redraw, e.Type)
+		}
+	}
+	_, got, windowIndex, _ := wm.State()
+	if expected := layout.NewLayout(3).Resize(0, 1, 110, 19); !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+	if windowIndex != 3 {
+		t.Errorf("windowIndex should be %d but got %d", 3, windowIndex)
+	}
+
+	wm.Emit(event.Event{Type: event.TabPrevious})
+	<-eventCh
+	_, got, windowIndex, _ = wm.State()
+	if expected := layout.NewLayout(1).SplitLeft(2).Resize(0, 1, 110, 19); !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+	if windowIndex != 2 {
+		t.Errorf("windowIndex should be %d but got %d", 2, windowIndex)
+	}
+
+	wm.Emit(event.Event{Type: event.Tabs, CmdName: "tabs"})
+	if e := <-eventCh; e.Type != event.Info || e.Error.Error() !=
+		"Tab page 1\n    [No Name]\nTab page 2\n>   [No Name]\n    [No Name]\nTab page 3\n    [No Name]" {
+		t.Errorf("tabs should list the windows but got: %+v", e)
+	}
+	expected := []state.TabState{
+		{Name: "[No Name]", Windows: 1},
+		{Name: "[No Name]", Windows: 2, Active: true},
+		{Name: "[No Name]", Windows: 1},
+	}
+	if tabs := wm.Tabs(); !reflect.DeepEqual(tabs, expected) {
+		t.Errorf("tabs should be %+v but got %+v", expected, tabs)
+	}
+
+	wm.Emit(event.Event{Type: event.TabNext, CmdName: "tabn[ext]", Arg: "4"})
+	wm.Emit(event.Event{Type: event.TabNext, Count: 1})
+	wm.Emit(event.Event{Type: event.TabClose, CmdName: "tabc[lose]"})
+	wm.Emit(event.Event{Type: event.TabPrevious, Count: 3})
+	wm.Emit(event.Event{Type: event.Quit})
+	if e := <-eventCh; e.Type != event.Error || e.Error.Error() != "tab page 4 does not exist" {
+		t.Errorf("tabnext should emit an error but got: %+v", e)
+	}
+	for range 4 {
+		if e := <-eventCh; e.Type != event.Redraw {
+			t.Errorf("event type should be %s but got: %s", event.Redraw, e.Type)
+		}
+	}
+	_, got, windowIndex, _ = wm.State()
+	if expected := layout.NewLayout(1).SplitLeft(2).Resize(0, 0, 110, 20); !reflect.DeepEqual(got, expected) {
+		t.Errorf("layout should be %#v but got %#v", expected, got)
+	}
+	if windowIndex != 2 {
+		t.Errorf("windowIndex should be %d but got %d", 2, windowIndex)
+	}
+
+	wm.Emit(event.Event{Type: event.TabClose, CmdName: "tabc[lose]"})
+	if e := <-eventCh; e.Type != event.Error || e.Error.Error() != "cannot close last tab page" {
+		t.Errorf("tabclose should emit an error but got: %+v", e)
+	}
+	wm.Close()
+}
+
 func TestManagerCopyCutPaste(t *testing.T) {
 	wm := NewManager()
 	eventCh, redrawCh, waitCh := make(chan event.Event), make(chan struct{}), make(chan struct{})
@@ -706,3 +913,398 @@ func TestManagerCopyCutPaste(t *testing.T) {
 	<-waitCh
 	wm.Close()
 }
+
+func TestManagerUndoFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot overwrite the original file on Windows")
+	}
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	f, err := createTemp(t.TempDir(), "Hello, world!")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	open := func() *Manager {
+		wm := newTestManager(t)
+		opts, err := option.Parse("undofile")
+		if err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		if _, err = wm.SetOption(opts[0], false); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		if err = wm.Open(f.Name()); err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		return wm
+	}
+	bytes := func(wm *Manager) string {
+		windowStates, _, windowIndex, _ := wm.State()
+		ws := windowStates[windowIndex]
+		return string(ws.Bytes[:ws.Size])
+	}
+
+	wm := open()
+	wm.Emit(event.Event{Type: event.DeleteByte})
+	wm.Emit(event.Event{Type: event.Write})
+
+	wm = open()
+	if expected := "ello, world!"; bytes(wm) != expected {
+		t.Errorf("bytes should be %q but got %q", expected, bytes(wm))
+	}
+	wm.Emit(event.Event{Type: event.Undo})
+	if expected := "Hello, world!"; bytes(wm) != expected {
+		t.Errorf("bytes should be %q but got %q", expected, bytes(wm))
+	}
+	wm.Emit(event.Event{Type: event.Redo})
+	wm.Emit(event.Event{Type: event.DeleteByte})
+	wm.Emit(event.Event{Type: event.Write})
+
+	if err = os.WriteFile(f.Name(), []byte("Hello, bed!"), 0o644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm = open()
+	wm.Emit(event.Event{Type: event.Undo})
+	if expected := "Hello, bed!"; bytes(wm) != expected {
+		t.Errorf("bytes should be %q but got %q", expected, bytes(wm))
+	}
+}
+
+func TestManagerWriteInPlace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot overwrite the original file on Windows")
+	}
+	f, err := createTemp(t.TempDir(), "Hello, world!")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := newTestManager(t)
+	opts, err := option.Parse("inplace")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if _, err = wm.SetOption(opts[0], false); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err = wm.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	bytes := func() string {
+		windowStates, _, windowIndex, _ := wm.State()
+		ws := windowStates[windowIndex]
+		return string(ws.Bytes[:ws.Size])
+	}
+
+	wm.Emit(event.Event{Type: event.CursorNext, Count: 7})
+	wm.Emit(event.Event{Type: event.Increment, Count: 1})
+	if _, n, err := wm.write(event.Event{Type: event.Write}); err != nil || n != 1 {
+		t.Errorf("write should write 1 byte but got: %d, %v", n, err)
+	}
+	bs, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if expected := "Hello, xorld!"; string(bs) != expected {
+		t.Errorf("file should be %q but got %q", expected, string(bs))
+	}
+	if gi, err := os.Stat(f.Name()); err != nil || !os.SameFile(fi, gi) {
+		t.Errorf("file should be written in place")
+	}
+
+	wm.Emit(event.Event{Type: event.Undo})
+	if expected := "Hello, world!"; bytes() != expected {
+		t.Errorf("bytes should be %q but got %q", expected, bytes())
+	}
+	wm.Emit(event.Event{Type: event.DeleteByte})
+	if _, n, err := wm.write(event.Event{Type: event.Write}); err != nil || n != 12 {
+		t.Errorf("write should write 12 bytes but got: %d, %v", n, err)
+	}
+	if bs, err = os.ReadFile(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if expected := "ello, world!"; string(bs) != expected {
+		t.Errorf("file should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestManagerWindowOptions(t *testing.T) {
+	wm := newTestManager(t)
+	set := func(src string, local bool) error {
+		opts, err := option.Parse(src)
+		if err != nil {
+			t.Fatalf("err should be nil but got: %v", err)
+		}
+		_, err = wm.SetOption(opts[0], local)
+		return err
+	}
+	state := func() *state.WindowState {
+		windowStates, _, windowIndex, _ := wm.State()
+		return windowStates[windowIndex]
+	}
+	// The options set before opening the first window.
+	if err := set("textencoding=utf-8", false); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := set("offsetbase=dec", true); err == nil {
+		t.Errorf("err should not be nil")
+	}
+	if err := wm.Open(""); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if ws := state(); ws.TextEncoding != "utf-8" || ws.OffsetBase != "hex" {
+		t.Errorf("options should be utf-8 and hex but got %s and %s", ws.TextEncoding, ws.OffsetBase)
+	}
+	if err := set("offsetbase=dec", true); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := set("relativeoffset!", false); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm.Emit(event.Event{Type: event.New})
+	if ws := state(); ws.TextEncoding != "utf-8" || ws.OffsetBase != "hex" || ws.AddressDelta != 0 {
+		t.Errorf("options should be inherited but got %s, %s and %d", ws.TextEncoding, ws.OffsetBase, ws.AddressDelta)
+	}
+	if !wm.windows[1].relativeOffset || wm.windows[0].offsetBase != "dec" {
+		t.Errorf("options should be set to the windows")
+	}
+	if err := set("textencoding=latin1", true); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm.Emit(event.Event{Type: event.Vnew})
+	if ws := state(); ws.TextEncoding != "utf-8" {
+		t.Errorf("textencoding should be utf-8 but got %s", ws.TextEncoding)
+	}
+}
+
+func TestManagerWriteInPlaceMoved(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot overwrite the original file on Windows")
+	}
+	f, err := createTemp(t.TempDir(), "Hello, world!")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := newTestManager(t)
+	opts, err := option.Parse("inplace")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if _, err = wm.SetOption(opts[0], false); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err = wm.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+
+	if _, _, _, err = wm.State(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+
+	// Delete a byte and insert a byte, keeping the length of the file.
+	wm.Emit(event.Event{Type: event.DeleteByte})
+	wm.Emit(event.Event{Type: event.StartAppendEnd})
+	wm.Emit(event.Event{Type: event.SwitchFocus})
+	wm.Emit(event.Event{Type: event.Rune, Rune: 'X', Mode: mode.Insert})
+	wm.Emit(event.Event{Type: event.ExitInsert})
+	if _, n, err := wm.write(event.Event{Type: event.Write}); err != nil || n != 13 {
+		t.Errorf("write should write 13 bytes but got: %d, %v", n, err)
+	}
+	bs, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if expected := "ello, world!X"; string(bs) != expected {
+		t.Errorf("file should be %q but got %q", expected, string(bs))
+	}
+	if gi, err := os.Stat(f.Name()); err != nil || !os.SameFile(fi, gi) {
+		t.Errorf("file should be written in place")
+	}
+
+	// The buffer still reads the original bytes moved by the edits.
+	windowStates, _, windowIndex, _ := wm.State()
+	if ws := windowStates[windowIndex]; string(ws.Bytes[:ws.Size]) != "ello, world!X" {
+		t.Errorf("bytes should be %q but got %q", "ello, world!X", string(ws.Bytes[:ws.Size]))
+	}
+}
+
+func TestManagerWriteReadonly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot overwrite the original file on Windows")
+	}
+	f, err := createTemp(t.TempDir(), "Hello, world!")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := NewManager()
+	wm.Init(nil, nil)
+	wm.SetSize(110, 20)
+	wm.SetReadonly(true)
+	if err = wm.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer wm.Close()
+
+	_, _, err = wm.write(event.Event{Type: event.Write})
+	if expected := "readonly option is set (add ! to override)"; err == nil || err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, err)
+	}
+	if _, n, err := wm.write(event.Event{Type: event.Write, Bang: true}); err != nil || n != 13 {
+		t.Errorf("write should write 13 bytes but got: %d, %v", n, err)
+	}
+}
+
+func TestManagerCheckTime(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot overwrite the original file on Windows")
+	}
+	f, err := createTemp(t.TempDir(), "Hello, world!")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := newTestManager(t)
+	if err = wm.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	state := func() (string, int64) {
+		windowStates, _, windowIndex, _ := wm.State()
+		ws := windowStates[windowIndex]
+		return string(ws.Bytes[:ws.Size]), ws.Cursor
+	}
+	wm.Emit(event.Event{Type: event.CursorNext, Count: 7})
+
+	if _, confirms, err := wm.checkTime(true); err != nil || len(confirms) != 0 {
+		t.Errorf("err and confirms should be nil but got: %v, %v", err, confirms)
+	}
+	if err = os.WriteFile(f.Name(), []byte("Hello, bed editor!"), 0o644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	expected := filepath.Base(f.Name()) + " has been changed since editing started, reload? (y/n)"
+	if _, confirms, err := wm.checkTime(true); err != nil || len(confirms) != 1 ||
+		confirms[0].Type != event.ConfirmReload || confirms[0].Arg != f.Name() ||
+		confirms[0].Error.Error() != expected {
+		t.Errorf("confirms should be %q but got: %v, %v", expected, confirms, err)
+	}
+	if _, confirms, err := wm.checkTime(true); err != nil || len(confirms) != 0 {
+		t.Errorf("err and confirms should be nil but got: %v, %v", err, confirms)
+	}
+
+	wm.Emit(event.Event{Type: event.Increment, Count: 1})
+	if err = wm.edit(event.Event{Type: event.Edit}); err == nil {
+		t.Errorf("err should not be nil")
+	}
+	if err = wm.edit(event.Event{Type: event.Edit, Bang: true}); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if str, cursor := state(); str != "Hello, bed editor!" || cursor != 7 {
+		t.Errorf("bytes and cursor should be %q and %d but got %q and %d",
+			"Hello, bed editor!", 7, str, cursor)
+	}
+
+	opts, err := option.Parse("autoread")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if _, err = wm.SetOption(opts[0], false); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err = os.WriteFile(f.Name(), []byte("Hello!"), 0o644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if info, _, err := wm.checkTime(false); err != nil ||
+		info != filepath.Base(f.Name())+" has been reloaded" {
+		t.Errorf("file should be reloaded but got: %q, %v", info, err)
+	}
+	if str, cursor := state(); str != "Hello!" || cursor != 5 {
+		t.Errorf("bytes and cursor should be %q and %d but got %q and %d",
+			"Hello!", 5, str, cursor)
+	}
+
+	wm.Emit(event.Event{Type: event.Increment, Count: 1})
+	if _, _, err = wm.write(event.Event{Type: event.Write}); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if _, confirms, err := wm.checkTime(true); err != nil || len(confirms) != 0 {
+		t.Errorf("err and confirms should be nil but got: %v, %v", err, confirms)
+	}
+	wm.Emit(event.Event{Type: event.Increment, Count: 1})
+	if err = os.WriteFile(f.Name(), []byte("Hello, world!"), 0o644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	expected = filepath.Base(f.Name()) + " has been changed since editing started," +
+		" reload and discard the unsaved changes? (y/n)"
+	if _, confirms, err := wm.checkTime(true); err != nil || len(confirms) != 1 ||
+		confirms[0].Error.Error() != expected {
+		t.Errorf("confirms should be %q but got: %v, %v", expected, confirms, err)
+	}
+	if info, err := wm.reloadFile(event.Event{Type: event.Reload, Arg: f.Name()}); err != nil ||
+		info != filepath.Base(f.Name())+" has been reloaded" {
+		t.Errorf("file should be reloaded but got: %q, %v", info, err)
+	}
+	if str, cursor := state(); str != "Hello, world!" || cursor != 5 {
+		t.Errorf("bytes and cursor should be %q and %d but got %q and %d",
+			"Hello, world!", 5, str, cursor)
+	}
+	if _, err := wm.reloadFile(event.Event{Type: event.Reload, Arg: "none"}); err == nil {
+		t.Errorf("err should not be nil")
+	}
+}
+
+func TestManagerFollow(t *testing.T) {
+	f, err := createTemp(t.TempDir(), "Hello, world!")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer func(interval time.Duration) { followInterval = interval }(followInterval)
+	followInterval = 10 * time.Millisecond
+	wm := newTestManager(t)
+	if err = wm.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	waitLength := func(length int64) int64 {
+		for range 100 {
+			windowStates, _, windowIndex, _ := wm.State()
+			if ws := windowStates[windowIndex]; ws.Length == length {
+				return ws.Cursor
+			}
+			time.Sleep(followInterval)
+		}
+		t.Fatalf("length should be %d", length)
+		return 0
+	}
+	waitLength(13)
+	wm.Emit(event.Event{Type: event.CursorEnd})
+	opts, err := option.Parse("follow")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if _, err = wm.SetOption(opts[0], false); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+
+	g, err := os.OpenFile(f.Name(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer g.Close()
+	if _, err = g.WriteString(" Hello, bed!"); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if cursor := waitLength(25); cursor != 24 {
+		t.Errorf("cursor should be %d but got %d", 24, cursor)
+	}
+
+	wm.Emit(event.Event{Type: event.CursorHead})
+	if _, err = g.WriteString("!!!"); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if cursor := waitLength(28); cursor != 16 {
+		t.Errorf("cursor should be %d but got %d", 16, cursor)
+	}
+}
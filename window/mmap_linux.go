@@ -0,0 +1,78 @@
+package window
+
+import (
+	"errors"
+	"os"
+	"runtime/debug"
+	"sync/atomic"
+	"syscall"
+)
+
+// mmapThreshold is the minimum size of the file to be memory-mapped.
+const mmapThreshold = 1 << 20
+
+// mmapReader reads a file through the memory-mapped bytes. When the file
+// is truncated after mapping, accessing the pages beyond the end of the
+// file raises SIGBUS, so the reader recovers from the fault and falls
+// back to reading the file.
+type mmapReader struct {
+	file    *os.File
+	data    []byte
+	faulted atomic.Bool
+}
+
+// newFileReader returns a memory-mapped reader for a large regular file,
+// or the file itself when the file cannot be mapped.
+func newFileReader(f *os.File, fi os.FileInfo) readAtSeekCloser {
+	if !fi.Mode().IsRegular() || fi.Size() < mmapThreshold || int64(int(fi.Size())) != fi.Size() {
+		return f
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return f
+	}
+	return &mmapReader{file: f, data: data}
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (r *mmapReader) ReadAt(b []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, errors.New("window.mmapReader.ReadAt: negative offset")
+	}
+	if !r.faulted.Load() && offset+int64(len(b)) <= int64(len(r.data)) {
+		if n, ok := r.copy(b, offset); ok {
+			return n, nil
+		}
+		r.faulted.Store(true)
+	}
+	return r.file.ReadAt(b, offset)
+}
+
+// copy the mapped bytes, and reports whether the pages are accessible.
+func (r *mmapReader) copy(b []byte, offset int64) (n int, ok bool) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if err := recover(); err != nil {
+			if _, fault := err.(interface{ Addr() uintptr }); !fault {
+				panic(err)
+			}
+			n, ok = 0, false
+		}
+	}()
+	return copy(b, r.data[offset:]), true
+}
+
+// Seek implements the io.Seeker interface.
+// The file is used to reflect the current size of the file.
+func (r *mmapReader) Seek(offset int64, whence int) (int64, error) {
+	return r.file.Seek(offset, whence)
+}
+
+// Close unmaps the bytes and closes the file.
+func (r *mmapReader) Close() error {
+	if err := syscall.Munmap(r.data); err != nil {
+		_ = r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,58 @@
+package window
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMmapReader(t *testing.T) {
+	str := strings.Repeat("0123456789abcdef", mmapThreshold/8)
+	f, err := createTemp(t.TempDir(), str)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if f, err = os.Open(f.Name()); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	r, ok := newFileReader(f, fi).(*mmapReader)
+	if !ok {
+		t.Fatalf("newFileReader should return *mmapReader")
+	}
+	defer r.Close()
+
+	p := make([]byte, 8)
+	if n, err := r.ReadAt(p, mmapThreshold+4); err != nil || n != 8 {
+		t.Errorf("ReadAt should read 8 bytes but got: %d, %v", n, err)
+	}
+	if expected := "456789ab"; string(p) != expected {
+		t.Errorf("p should be %q but got: %q", expected, string(p))
+	}
+	if n, err := r.ReadAt(p, int64(len(str))-4); err != io.EOF || n != 4 {
+		t.Errorf("ReadAt should read 4 bytes with io.EOF but got: %d, %v", n, err)
+	}
+	if l, err := r.Seek(0, io.SeekEnd); err != nil || l != int64(len(str)) {
+		t.Errorf("Seek should return %d but got: %d, %v", len(str), l, err)
+	}
+
+	if err := os.Truncate(f.Name(), 16); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if n, err := r.ReadAt(p, mmapThreshold+4); err != io.EOF || n != 0 {
+		t.Errorf("ReadAt should return io.EOF but got: %d, %v", n, err)
+	}
+	if n, err := r.ReadAt(p, 4); err != nil || n != 8 {
+		t.Errorf("ReadAt should read 8 bytes but got: %d, %v", n, err)
+	}
+	if expected := "456789ab"; string(p) != expected {
+		t.Errorf("p should be %q but got: %q", expected, string(p))
+	}
+	if l, err := r.Seek(0, io.SeekEnd); err != nil || l != 16 {
+		t.Errorf("Seek should return %d but got: %d, %v", 16, l, err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package window
+
+import "os"
+
+// newFileReader returns the file itself on the platforms
+// where the memory-mapped reader is not supported.
+func newFileReader(f *os.File, _ os.FileInfo) readAtSeekCloser {
+	return f
+}
@@ -0,0 +1,74 @@
+package window
+
+import "github.com/itchyny/bed/event"
+
+// mouse handles the mouse events. A press focuses the window, or starts
+// dragging the split border. A drag moves the border, or selects the bytes.
+func (m *Manager) mouse(e event.Event) {
+	switch e.Type {
+	case event.MousePress:
+		if m.mousePress(e.Mouse) {
+			window, _ := m.current()
+			window.emit(e)
+		}
+	case event.MouseDrag:
+		if e.Mouse.Window < 0 {
+			if m.dragBorder(e.Mouse) {
+				m.eventCh <- event.Event{Type: event.Redraw}
+			}
+		} else {
+			window, _ := m.current()
+			window.emit(e)
+		}
+	case event.MouseRelease:
+		m.mu.Lock()
+		m.dragLayout = nil
+		m.mu.Unlock()
+	case event.MouseWheelUp, event.MouseWheelDown:
+		m.mu.Lock()
+		var window *window
+		if i := e.Mouse.Window; 0 <= i && i < len(m.windows) {
+			window = m.windows[i]
+		}
+		m.mu.Unlock()
+		if window != nil {
+			typ := event.ScrollUp
+			if e.Type == event.MouseWheelDown {
+				typ = event.ScrollDown
+			}
+			window.emit(event.Event{Type: typ, Count: 3, Mode: e.Mode})
+		}
+	}
+}
+
+func (m *Manager) mousePress(mouse *event.Mouse) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dragLayout = nil
+	if mouse.Window < 0 || len(m.windows) <= mouse.Window {
+		if _, ok := m.layout.MoveBorder(mouse.X, mouse.Y, mouse.X, mouse.Y); ok {
+			m.dragLayout, m.dragX, m.dragY = m.layout, mouse.X, mouse.Y
+		}
+		return false
+	}
+	if mouse.Window != m.windowIndex {
+		m.windowIndex, m.prevWindowIndex = mouse.Window, m.windowIndex
+		m.layout = m.layout.Activate(m.windowIndex)
+	}
+	return true
+}
+
+func (m *Manager) dragBorder(mouse *event.Mouse) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dragLayout == nil {
+		return false
+	}
+	// Move the border of the layout on the press, so that
+	// the border is found at the same position while dragging.
+	l, ok := m.dragLayout.MoveBorder(m.dragX, m.dragY, mouse.X, mouse.Y)
+	if ok {
+		m.layout = m.fit(l)
+	}
+	return ok
+}
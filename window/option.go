@@ -0,0 +1,142 @@
+package window
+
+import (
+	"errors"
+
+	"github.com/itchyny/bed/charset"
+	"github.com/itchyny/bed/option"
+	"github.com/itchyny/bed/statusline"
+)
+
+type options struct {
+	undofile bool
+	inplace  bool
+	autoread bool
+	binary   bool
+}
+
+// windowOptions holds the window-local options. The manager holds the
+// options set by :set, which the new windows inherit.
+type windowOptions struct {
+	textEncoding   string
+	offsetBase     string
+	baseAddress    int64
+	relativeOffset bool
+	statusLine     string
+}
+
+func defaultWindowOptions() windowOptions {
+	return windowOptions{textEncoding: charset.ASCII, offsetBase: "hex"}
+}
+
+// SetOption applies the option setting. The window-local option is applied
+// to the current window, and also to the new windows unless local is set.
+func (m *Manager) SetOption(opt option.Option, local bool) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch opt.Name {
+	case "undofile":
+		return opt.SetBool(&m.options.undofile)
+	case "inplace":
+		return opt.SetBool(&m.options.inplace)
+	case "autoread":
+		return opt.SetBool(&m.options.autoread)
+	case "binary":
+		return opt.SetBool(&m.options.binary)
+	case "readonly", "modifiable", "follow":
+		if len(m.windows) == 0 {
+			if opt.Name == "readonly" && !local {
+				// Open the windows in readonly mode, like the -R flag.
+				return opt.SetBool(&m.readonly)
+			}
+			return "", errors.New("no window for " + opt.Name)
+		}
+		return m.windows[m.windowIndex].setOption(opt)
+	case "textencoding", "offsetbase", "baseaddress", "relativeoffset", "statusline":
+		if len(m.windows) == 0 {
+			if local {
+				return "", errors.New("no window for " + opt.Name)
+			}
+			return m.windowOptions.set(opt)
+		}
+		window := m.windows[m.windowIndex]
+		info, err := window.setOption(opt)
+		if err == nil && !local && opt.Type != option.Query {
+			window.mu.Lock()
+			m.windowOptions.copy(window.windowOptions, opt.Name)
+			window.mu.Unlock()
+		}
+		return info, err
+	default:
+		return "", opt.Unknown()
+	}
+}
+
+// setOption applies the window-local option setting.
+func (w *window) setOption(opt option.Option) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch opt.Name {
+	case "readonly":
+		return opt.SetBool(&w.readonly)
+	case "modifiable":
+		return opt.SetBool(&w.modifiable)
+	case "follow":
+		follow := w.follow
+		info, err := opt.SetBool(&follow)
+		if err == nil {
+			w.setFollow(follow)
+		}
+		return info, err
+	default:
+		return w.windowOptions.set(opt)
+	}
+}
+
+// set applies the window-local option setting.
+func (o *windowOptions) set(opt option.Option) (string, error) {
+	switch opt.Name {
+	case "textencoding":
+		return opt.SetString(&o.textEncoding, charset.Encodings...)
+	case "offsetbase":
+		return opt.SetString(&o.offsetBase, "hex", "dec", "oct")
+	case "baseaddress":
+		baseAddress := o.baseAddress
+		info, err := opt.SetInt(&baseAddress)
+		if err == nil && baseAddress < 0 {
+			return "", errors.New("baseaddress should not be negative")
+		}
+		o.baseAddress = baseAddress
+		return info, err
+	case "relativeoffset":
+		return opt.SetBool(&o.relativeOffset)
+	case "statusline":
+		statusLine := o.statusLine
+		info, err := opt.SetString(&statusLine)
+		if err == nil {
+			if _, err = statusline.Parse(statusLine); err != nil {
+				return "", err
+			}
+		}
+		o.statusLine = statusLine
+		return info, err
+	default:
+		return "", opt.Unknown()
+	}
+}
+
+// copy copies the window-local option of the name.
+func (o *windowOptions) copy(p windowOptions, name string) {
+	switch name {
+	case "textencoding":
+		o.textEncoding = p.textEncoding
+	case "offsetbase":
+		o.offsetBase = p.offsetBase
+	case "baseaddress":
+		o.baseAddress = p.baseAddress
+	case "relativeoffset":
+		o.relativeOffset = p.relativeOffset
+	case "statusline":
+		o.statusLine = p.statusLine
+	}
+}
@@ -0,0 +1,344 @@
+package window
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/itchyny/bed/event"
+)
+
+// processID returns the process id if the path is the memory of a process,
+// like /proc/1234/mem.
+func processID(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "/proc/")
+	if !ok {
+		return "", false
+	}
+	pid, ok := strings.CutSuffix(rest, "/mem")
+	if !ok || pid == "" || strings.Trim(pid, "0123456789") != "" {
+		return "", false
+	}
+	return pid, true
+}
+
+// memRegion is a mapped region of the virtual memory of a process.
+type memRegion struct {
+	start, end int64
+	perms      string
+	name       string
+}
+
+// readable reports whether the region can be read.
+func (r memRegion) readable() bool {
+	return strings.HasPrefix(r.perms, "r")
+}
+
+// readMaps reads the mapped regions from /proc/PID/maps.
+func readMaps(path string) ([]memRegion, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var regions []memRegion
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		xs := strings.Fields(s.Text())
+		if len(xs) < 5 {
+			continue
+		}
+		from, to, ok := strings.Cut(xs[0], "-")
+		if !ok {
+			continue
+		}
+		start, err := strconv.ParseUint(from, 16, 64)
+		if err != nil {
+			continue
+		}
+		end, err := strconv.ParseUint(to, 16, 64)
+		// The regions beyond the range of the offsets, like [vsyscall], are skipped.
+		if err != nil || start >= end || end > math.MaxInt64 {
+			continue
+		}
+		regions = append(regions, memRegion{
+			start: int64(start), end: int64(end),
+			perms: xs[1], name: strings.Join(xs[5:], " "),
+		})
+	}
+	if err = s.Err(); err != nil {
+		return nil, err
+	}
+	return regions, nil
+}
+
+// processReader reads the memory of a process at the virtual addresses from
+// the start of the first region. The unmapped gaps and the regions which
+// cannot be read are read as zeros.
+type processReader struct {
+	file    *os.File
+	regions []memRegion
+	base    int64
+	mu      sync.Mutex
+	index   int64
+}
+
+func newProcessReader(f *os.File, regions []memRegion) *processReader {
+	var base int64
+	if len(regions) > 0 {
+		base = regions[0].start
+	}
+	return &processReader{file: f, regions: regions, base: base}
+}
+
+func (r *processReader) size() int64 {
+	if len(r.regions) == 0 {
+		return 0
+	}
+	return r.regions[len(r.regions)-1].end - r.base
+}
+
+// regionIndex returns the index of the first region which ends after the address.
+func (r *processReader) regionIndex(address int64) int {
+	i, _ := slices.BinarySearchFunc(r.regions, address, func(region memRegion, address int64) int {
+		return cmp.Compare(region.end, address+1)
+	})
+	return i
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (r *processReader) ReadAt(b []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, errors.New("window.processReader.ReadAt: negative offset")
+	}
+	size := r.size()
+	if offset >= size {
+		return 0, io.EOF
+	}
+	n := int(min(int64(len(b)), size-offset))
+	clear(b[:n])
+	address := r.base + offset
+	for _, region := range r.regions[r.regionIndex(address):] {
+		if region.start >= address+int64(n) {
+			break
+		}
+		if from, to := max(region.start, address), min(region.end, address+int64(n)); from < to && region.readable() {
+			_, _ = r.file.ReadAt(b[from-address:to-address], from)
+		}
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// processWriter writes to the memory of a process at the virtual addresses
+// from the base. The unmapped gaps and the regions which cannot be read are
+// not written.
+type processWriter struct {
+	file    io.WriterAt
+	regions []memRegion
+	base    int64
+}
+
+// WriteAt implements the io.WriterAt interface.
+func (w *processWriter) WriteAt(b []byte, offset int64) (int, error) {
+	var n int
+	address := w.base + offset
+	for _, region := range w.regions {
+		from, to := max(region.start, address), min(region.end, address+int64(len(b)))
+		if from >= to || !region.readable() {
+			continue
+		}
+		m, err := w.file.WriteAt(b[from-address:to-address], from)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Seek implements the io.Seeker interface.
+func (r *processReader) Seek(offset int64, whence int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.index
+	case io.SeekEnd:
+		offset += r.size()
+	default:
+		return 0, errors.New("window.processReader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("window.processReader.Seek: negative position")
+	}
+	r.index = offset
+	return offset, nil
+}
+
+// Close closes the memory file.
+func (r *processReader) Close() error {
+	return r.file.Close()
+}
+
+// unmapped returns the ranges of the unmapped gaps and the regions which
+// cannot be read between the offsets, as pairs of the start and end offsets.
+func (r *processReader) unmapped(from, to int64) []int64 {
+	var indices []int64
+	prev := from
+	for _, region := range r.regions[r.regionIndex(r.base+from):] {
+		start, end := region.start-r.base, region.end-r.base
+		if start >= to {
+			break
+		}
+		if region.readable() {
+			if prev < start {
+				indices = append(indices, prev, start)
+			}
+			prev = max(prev, end)
+		}
+	}
+	if prev < to {
+		indices = append(indices, prev, to)
+	}
+	return indices
+}
+
+// mapped returns the range of the offsets of the contiguous regions which can
+// be read, after the offset if forward, or before the offset if backward.
+// The range is empty if there is no such region.
+func (r *processReader) mapped(offset int64, forward bool) (int64, int64) {
+	address := r.base + offset
+	if forward {
+		for i := r.regionIndex(address); i < len(r.regions); i++ {
+			if !r.regions[i].readable() {
+				continue
+			}
+			start, end := r.regions[i].start, r.regions[i].end
+			for i++; i < len(r.regions) && r.regions[i].start == end && r.regions[i].readable(); i++ {
+				end = r.regions[i].end
+			}
+			return start - r.base, end - r.base
+		}
+		return offset, offset
+	}
+	for i := min(r.regionIndex(address), len(r.regions)-1); i >= 0; i-- {
+		if r.regions[i].start >= address || !r.regions[i].readable() {
+			continue
+		}
+		start, end := r.regions[i].start, r.regions[i].end
+		for i--; i >= 0 && r.regions[i].end == start && r.regions[i].readable(); i-- {
+			start = r.regions[i].start
+		}
+		return start - r.base, end - r.base
+	}
+	return offset, offset
+}
+
+// mappedReader reads the buffer of the process memory for the searcher,
+// which searches only in the regions which can be read.
+type mappedReader struct {
+	io.ReaderAt
+	process *processReader
+}
+
+// Range implements the searcher.Ranger interface.
+func (r mappedReader) Range(offset int64, forward bool) (int64, int64) {
+	return r.process.mapped(offset, forward)
+}
+
+// list returns the listing of the regions, marking the region of the cursor.
+func (r *processReader) list(cursor int64) string {
+	if len(r.regions) == 0 {
+		return "No mapped region"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, " %6s  %-25s  %-5s  %s", "number", "address", "perms", "name")
+	address := r.base + cursor
+	for i, region := range r.regions {
+		mark := ' '
+		if region.start <= address && address < region.end {
+			mark = '>'
+		}
+		fmt.Fprintf(&sb, "\n%c%6d  %012x-%012x  %-5s  %s",
+			mark, i+1, region.start, region.end, region.perms, region.name)
+	}
+	return sb.String()
+}
+
+// processOf returns the reader of the process memory of the buffer, if any.
+func processOf(r readAtSeeker) *processReader {
+	if r, ok := r.(*originalReader); ok {
+		if p, ok := r.readAtSeekCloser.(*processReader); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// openProcess opens the memory of the process with the mapped regions.
+func (m *Manager) openProcess(path, pid string) (readAtSeeker, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("attaching to a process is not supported on " + runtime.GOOS)
+	}
+	regions, err := readMaps(filepath.Join(filepath.Dir(path), "maps"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("no such process: " + pid)
+		}
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	r := &originalReader{readAtSeekCloser: newProcessReader(f, regions)}
+	m.addFile(path, r, fi)
+	return r, nil
+}
+
+// attach opens the memory of the process.
+func (m *Manager) attach(e event.Event) error {
+	if e.Arg == "" {
+		return errors.New("an argument is required for " + e.CmdName)
+	}
+	if _, ok := processID("/proc/" + e.Arg + "/mem"); !ok {
+		return errors.New("invalid process id: " + e.Arg)
+	}
+	return m.edit(event.Event{Type: event.Edit, Arg: "/proc/" + e.Arg + "/mem"})
+}
+
+// maps lists the mapped regions of the process,
+// or moves the cursor to the start of the region of the number.
+func (w *window) maps(arg string) (string, error) {
+	if w.process == nil {
+		return "", errors.New("not attached to a process")
+	}
+	if arg == "" {
+		return w.process.list(w.cursor), nil
+	}
+	i, err := strconv.Atoi(arg)
+	if err != nil || i <= 0 || len(w.process.regions) < i {
+		return "", errors.New("invalid region number: " + arg)
+	}
+	w.cursorGotoOffset(w.process.regions[i-1].start - w.process.base)
+	return "", nil
+}
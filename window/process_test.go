@@ -0,0 +1,197 @@
+package window
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/mode"
+)
+
+func TestProcessReaderUnmapped(t *testing.T) {
+	r := newProcessReader(nil, []memRegion{
+		{start: 0x1000, end: 0x3000, perms: "r--p", name: "/bin/sleep"},
+		{start: 0x3000, end: 0x4000, perms: "---p", name: "/bin/sleep"},
+		{start: 0x5000, end: 0x6000, perms: "rw-p", name: "[heap]"},
+		{start: 0x8000, end: 0x9000, perms: "---p"},
+	})
+	testCases := []struct {
+		from, to int64
+		expected []int64
+	}{
+		{0x0000, 0x1000, nil},
+		{0x1800, 0x2800, []int64{0x2000, 0x2800}},
+		{0x2000, 0x3000, []int64{0x2000, 0x3000}},
+		{0x4800, 0x6000, []int64{0x5000, 0x6000}},
+		{0x0000, 0x8000, []int64{0x2000, 0x4000, 0x5000, 0x8000}},
+	}
+	for _, tc := range testCases {
+		if got := r.unmapped(tc.from, tc.to); !slices.Equal(got, tc.expected) {
+			t.Errorf("unmapped(%#x, %#x) should be %#x but got %#x", tc.from, tc.to, tc.expected, got)
+		}
+	}
+	if l, err := r.Seek(0, 2); err != nil || l != 0x8000 {
+		t.Errorf("Seek should return %#x but got: %#x, %v", 0x8000, l, err)
+	}
+}
+
+func TestProcessReaderMapped(t *testing.T) {
+	r := newProcessReader(nil, []memRegion{
+		{start: 0x1000, end: 0x2000, perms: "r-xp", name: "/bin/sleep"},
+		{start: 0x2000, end: 0x3000, perms: "r--p", name: "/bin/sleep"},
+		{start: 0x3000, end: 0x4000, perms: "---p", name: "/bin/sleep"},
+		{start: 0x5000, end: 0x6000, perms: "rw-p", name: "[heap]"},
+	})
+	for _, tc := range []struct {
+		offset     int64
+		forward    bool
+		start, end int64
+	}{
+		{0x0000, true, 0x0000, 0x2000},
+		{0x1fff, true, 0x1000, 0x2000},
+		{0x2000, true, 0x4000, 0x5000},
+		{0x4800, true, 0x4000, 0x5000},
+		{0x5000, true, 0x5000, 0x5000},
+		{0x5000, false, 0x4000, 0x5000},
+		{0x4001, false, 0x4000, 0x5000},
+		{0x4000, false, 0x0000, 0x2000},
+		{0x0001, false, 0x0000, 0x1000},
+		{0x0000, false, 0x0000, 0x0000},
+	} {
+		if start, end := r.mapped(tc.offset, tc.forward); start != tc.start || end != tc.end {
+			t.Errorf("mapped(%#x, %t) should be %#x-%#x but got %#x-%#x",
+				tc.offset, tc.forward, tc.start, tc.end, start, end)
+		}
+	}
+}
+
+func TestProcessWriter(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "mem")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer f.Close()
+	r := newProcessReader(nil, []memRegion{
+		{start: 0x02, end: 0x04, perms: "r--p"},
+		{start: 0x04, end: 0x06, perms: "---p"},
+		{start: 0x08, end: 0x0a, perms: "rw-p"},
+	})
+	w := &processWriter{f, r.regions, r.base}
+	if n, err := w.WriteAt([]byte("abcdefgh"), 0); err != nil || n != 4 {
+		t.Fatalf("WriteAt should write 4 bytes but got: %d, %v", n, err)
+	}
+	bs, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if expected := "\x00\x00ab\x00\x00\x00\x00gh"; string(bs) != expected {
+		t.Errorf("file should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestManagerAttach(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process memory is supported only on Linux")
+	}
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start a process: %v", err)
+	}
+	defer func() { _ = cmd.Process.Kill(); _ = cmd.Wait() }()
+	pid := strconv.Itoa(cmd.Process.Pid)
+	if f, err := os.Open("/proc/" + pid + "/mem"); err != nil {
+		t.Skipf("cannot read the process memory: %v", err)
+	} else {
+		_ = f.Close()
+	}
+	wm := newTestManager(t)
+	if err := wm.Open(""); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := wm.attach(event.Event{Arg: "x"}); err == nil {
+		t.Errorf("err should not be nil")
+	}
+	if err := wm.attach(event.Event{Arg: pid}); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	window := wm.windows[wm.windowIndex]
+	regions := window.process.regions
+	if len(regions) == 0 {
+		t.Fatalf("regions should not be empty")
+	}
+	windowStates, _, windowIndex, _ := wm.State()
+	ws := windowStates[windowIndex]
+	if expected := "/proc/" + pid + "/mem"; ws.Name != expected {
+		t.Errorf("name should be %q but got %q", expected, ws.Name)
+	}
+	if expected := window.process.size(); ws.Length != expected {
+		t.Errorf("length should be %#x but got %#x", expected, ws.Length)
+	}
+	if ws.Cursor != 0 {
+		t.Errorf("cursor should be 0 but got %#x", ws.Cursor)
+	}
+	if expected := regions[len(regions)-1].end - regions[0].start; ws.Length != expected {
+		t.Errorf("length should be %#x but got %#x", expected, ws.Length)
+	}
+	if ws.AddressDelta != regions[0].start {
+		t.Errorf("address delta should be %#x but got %#x", regions[0].start, ws.AddressDelta)
+	}
+	if expected := "\x7fELF"; !strings.HasPrefix(string(ws.Bytes), expected) {
+		t.Errorf("bytes should start with %q but got %q", expected, string(ws.Bytes[:16]))
+	}
+
+	i := len(regions) - 1
+	for ; i >= 0; i-- {
+		if strings.HasPrefix(regions[i].perms, "rw") {
+			break
+		}
+	}
+	if i < 0 {
+		t.Fatalf("writable region should exist")
+	}
+	wm.Emit(event.Event{Type: event.Maps, Arg: strconv.Itoa(i + 1)})
+	offset := regions[i].start - regions[0].start
+	if window.cursor != offset {
+		t.Errorf("cursor should be %#x but got %#x", offset, window.cursor)
+	}
+	if pos := window.fromAddress(event.Absolute{Offset: regions[i].start}); pos != (event.Absolute{Offset: offset}) {
+		t.Errorf("position should be %#x but got %v", offset, pos)
+	}
+	if info, err := window.maps(""); err != nil ||
+		!strings.Contains(info, fmt.Sprintf("\n>%6d  %012x-", i+1, regions[i].start)) {
+		t.Errorf("maps should mark the region of the cursor but got: %s, %v", info, err)
+	}
+
+	b, err := window.readByte(window.cursor)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm.Emit(event.Event{Type: event.Increment, Count: 1, Mode: mode.Normal})
+	if _, n, err := wm.write(event.Event{Type: event.Write}); err != nil || n != 1 {
+		t.Fatalf("write should write 1 byte but got: %d, %v", n, err)
+	}
+	f, err := os.Open("/proc/" + pid + "/mem")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer f.Close()
+	bs := make([]byte, 1)
+	if _, err = f.ReadAt(bs, regions[i].start); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if bs[0] != b+1 {
+		t.Errorf("byte should be %#x but got %#x", b+1, bs[0])
+	}
+
+	wm.Emit(event.Event{Type: event.DeleteByte})
+	if _, _, err := wm.write(event.Event{Type: event.Write}); err == nil ||
+		!strings.Contains(err.Error(), "cannot change the size") {
+		t.Errorf("write should fail to change the size but got: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package window
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/itchyny/bed/event"
+)
+
+// spoolThreshold is the size of the bytes kept in memory. The bytes beyond
+// the threshold are spilled to a temporary file.
+var spoolThreshold int64 = 64 << 20
+
+// spoolWait is the duration to wait for the first bytes of the stream.
+var spoolWait = 100 * time.Millisecond
+
+// spoolReader reads the stream in the background, and serves the bytes read
+// so far. The length of the reader grows along with the stream, like a file
+// which is still being written.
+type spoolReader struct {
+	mu      sync.RWMutex
+	bytes   []byte
+	file    *os.File
+	size    int64
+	index   int64
+	err     error
+	closed  bool
+	codec   *codec
+	abort   func()
+	ready   func()
+	readyCh chan struct{}
+	doneCh  chan struct{}
+}
+
+// newSpoolReader starts spooling the stream. The codec is the compression
+// format of the file, if the stream is decompressing the file.
+func newSpoolReader(r io.Reader, c *codec) *spoolReader {
+	rd, stop := newReader(r)
+	s := &spoolReader{codec: c, abort: rd.stop,
+		readyCh: make(chan struct{}), doneCh: make(chan struct{})}
+	s.ready = sync.OnceFunc(func() { close(s.readyCh) })
+	go func() {
+		defer close(s.doneCh)
+		defer stop()
+		s.spool(rd)
+	}()
+	return s
+}
+
+func (s *spoolReader) spool(r io.Reader) {
+	defer s.ready()
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if err := s.write(buf[:n]); err != nil {
+				s.setErr(err)
+				return
+			}
+			s.ready()
+		}
+		if err != nil {
+			if err != io.EOF {
+				s.setErr(err)
+			}
+			return
+		}
+	}
+}
+
+func (s *spoolReader) write(p []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	if s.file == nil && s.size+int64(len(p)) > spoolThreshold {
+		f, err := os.CreateTemp("", "bed-")
+		if err != nil {
+			return err
+		}
+		if _, err = f.Write(s.bytes); err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return err
+		}
+		s.file, s.bytes = f, nil
+	}
+	if s.file != nil {
+		if _, err := s.file.WriteAt(p, s.size); err != nil {
+			return err
+		}
+	} else {
+		s.bytes = append(s.bytes, p...)
+	}
+	s.size += int64(len(p))
+	return nil
+}
+
+func (s *spoolReader) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// wait for the first bytes of the stream, or the end of the stream.
+func (s *spoolReader) wait(d time.Duration) {
+	select {
+	case <-s.readyCh:
+	case <-time.After(d):
+	}
+}
+
+// waitDone waits for the end of the stream. The reading is aborted when the
+// abort channel receives a signal.
+func (s *spoolReader) waitDone(abort <-chan os.Signal) error {
+	select {
+	case <-s.doneCh:
+		return s.Err()
+	case <-abort:
+		s.abort()
+		return errors.New("reading is aborted")
+	}
+}
+
+// readSize returns the size of the bytes read so far.
+func (s *spoolReader) readSize() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.size
+}
+
+// Err returns the error occurred on reading the stream.
+func (s *spoolReader) Err() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.err
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (s *spoolReader) ReadAt(p []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, errors.New("window.spoolReader.ReadAt: negative offset")
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if offset >= s.size {
+		return 0, io.EOF
+	}
+	n := int(min(int64(len(p)), s.size-offset))
+	if s.file != nil {
+		var err error
+		if n, err = s.file.ReadAt(p[:n], offset); err != nil {
+			return n, err
+		}
+	} else {
+		copy(p, s.bytes[offset:offset+int64(n)])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Seek implements the io.Seeker interface.
+func (s *spoolReader) Seek(offset int64, whence int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.index
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("window.spoolReader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("window.spoolReader.Seek: negative position")
+	}
+	s.index = offset
+	return offset, nil
+}
+
+// Close stops spooling and removes the temporary file.
+func (s *spoolReader) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed, s.bytes, s.size = true, nil, 0
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	if e := os.Remove(s.file.Name()); err == nil {
+		err = e
+	}
+	s.file = nil
+	return err
+}
+
+func (w *window) isReading() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stream != nil
+}
+
+// readStream updates the length of the window while reading the stream.
+func (w *window) readStream(s *spoolReader) {
+	w.stream, w.codec = s, s.codec
+	ticker := time.NewTicker(followInterval)
+	go func() {
+		defer ticker.Stop()
+		for done := false; !done; {
+			select {
+			case <-ticker.C:
+			case <-s.doneCh:
+				w.mu.Lock()
+				if w.stream == s {
+					w.stream = nil
+				}
+				w.mu.Unlock()
+				done = true
+			case <-w.closeCh:
+				return
+			}
+			if w.updateLength(false) || done {
+				select {
+				case w.redrawCh <- struct{}{}:
+				case <-w.closeCh:
+					return
+				}
+			}
+		}
+		if err := s.Err(); err != nil {
+			select {
+			case w.eventCh <- event.Event{Type: event.Error, Error: err}:
+			case <-w.closeCh:
+			}
+		}
+	}()
+}
@@ -0,0 +1,116 @@
+package window
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/itchyny/bed/event"
+)
+
+func TestSpoolReader(t *testing.T) {
+	defer func(threshold int64) { spoolThreshold = threshold }(spoolThreshold)
+	spoolThreshold = 16
+	pr, pw := io.Pipe()
+	s := newSpoolReader(pr, nil)
+	defer s.Close()
+
+	if _, err := pw.Write([]byte("0123456789")); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	s.wait(time.Second)
+	if l, err := s.Seek(0, io.SeekEnd); err != nil || l != 10 {
+		t.Errorf("Seek should return %d but got: %d, %v", 10, l, err)
+	}
+	p := make([]byte, 8)
+	if n, err := s.ReadAt(p, 4); err != io.EOF || n != 6 {
+		t.Errorf("ReadAt should read 6 bytes with io.EOF but got: %d, %v", n, err)
+	}
+	if expected := "456789"; string(p[:6]) != expected {
+		t.Errorf("p should be %q but got: %q", expected, string(p[:6]))
+	}
+
+	if _, err := pw.Write([]byte("abcdefghij")); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	<-s.doneCh
+	if s.file == nil {
+		t.Errorf("the bytes should be spilled to a temporary file")
+	}
+	if l, err := s.Seek(0, io.SeekEnd); err != nil || l != 20 {
+		t.Errorf("Seek should return %d but got: %d, %v", 20, l, err)
+	}
+	if n, err := s.ReadAt(p, 6); err != nil || n != 8 {
+		t.Errorf("ReadAt should read 8 bytes but got: %d, %v", n, err)
+	}
+	if expected := "6789abcd"; string(p) != expected {
+		t.Errorf("p should be %q but got: %q", expected, string(p))
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+}
+
+func TestSpoolReaderWaitDone(t *testing.T) {
+	pr, pw := io.Pipe()
+	s := newSpoolReader(pr, nil)
+	defer s.Close()
+	abort := make(chan os.Signal, 1)
+	abort <- os.Interrupt
+	if err := s.waitDone(abort); err == nil || err.Error() != "reading is aborted" {
+		t.Errorf("waitDone should be aborted but got: %v", err)
+	}
+	go func(pw *io.PipeWriter) { _, _ = pw.Write([]byte("0123456789")) }(pw)
+	<-s.doneCh
+	if err := s.Err(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+
+	pr, pw = io.Pipe()
+	s = newSpoolReader(pr, nil)
+	defer s.Close()
+	go func(pw *io.PipeWriter) { _, _ = pw.Write([]byte("0123456789")); _ = pw.Close() }(pw)
+	if err := s.waitDone(make(chan os.Signal)); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if l, err := s.Seek(0, io.SeekEnd); err != nil || l != 10 {
+		t.Errorf("Seek should return %d but got: %d, %v", 10, l, err)
+	}
+}
+
+func TestManagerReadStream(t *testing.T) {
+	defer func(interval time.Duration) { followInterval = interval }(followInterval)
+	followInterval = 10 * time.Millisecond
+	wm := newTestManager(t)
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() { _, _ = pw.Write([]byte("Hello, world!")) }()
+	if err := wm.Read(pr); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	waitState := func(length int64, reading bool) {
+		for range 100 {
+			windowStates, _, windowIndex, _ := wm.State()
+			if ws := windowStates[windowIndex]; ws.Length == length && ws.Reading == reading &&
+				(!reading || ws.ReadSize == length) {
+				return
+			}
+			time.Sleep(followInterval)
+		}
+		t.Fatalf("length should be %d and reading should be %t", length, reading)
+	}
+	waitState(13, true)
+
+	if _, err := pw.Write([]byte(" Hello, bed!")); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	waitState(25, true)
+
+	wm.Emit(event.Event{Type: event.AbortSearch})
+	go func() { _, _ = pw.Write([]byte("!!!")) }()
+	waitState(28, false)
+}
@@ -0,0 +1,170 @@
+package window
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/layout"
+	"github.com/itchyny/bed/state"
+)
+
+// tab holds the layout and the active window of a tab page. The windows are
+// shared among the tab pages, and the layout refers to them by the indices.
+type tab struct {
+	layout          layout.Layout
+	windowIndex     int
+	prevWindowIndex int
+}
+
+// fit resizes the layout to the screen. The tabline takes
+// the top line of the screen when there are multiple tab pages.
+func (m *Manager) fit(l layout.Layout) layout.Layout {
+	if len(m.tabs) > 1 {
+		return l.Resize(0, 1, m.width, max(m.height-1, 0))
+	}
+	return l.Resize(0, 0, m.width, m.height)
+}
+
+// saveTab saves the layout and the window index to the current tab page.
+func (m *Manager) saveTab() {
+	m.tabs[m.tabIndex] = tab{m.layout, m.windowIndex, m.prevWindowIndex}
+}
+
+// loadTab switches to the tab page of the index.
+func (m *Manager) loadTab(index int) {
+	m.tabIndex = index
+	t := m.tabs[index]
+	m.layout, m.windowIndex, m.prevWindowIndex = m.fit(t.layout), t.windowIndex, t.prevWindowIndex
+}
+
+func (m *Manager) tabnew(e event.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	window, err := m.open(e.Arg)
+	if err != nil {
+		return err
+	}
+	m.saveTab()
+	m.addWindow(window)
+	m.tabs = slices.Insert(m.tabs, m.tabIndex+1,
+		tab{layout.NewLayout(m.windowIndex), m.windowIndex, m.prevWindowIndex})
+	m.loadTab(m.tabIndex + 1)
+	return nil
+}
+
+// tabnext switches to the tab page of the count, or the next tab page.
+// With the backward flag, it switches to the count-th previous tab page.
+func (m *Manager) tabnext(e event.Event, backward bool) error {
+	count := e.Count
+	if e.Arg != "" {
+		var err error
+		if count, err = strconv.ParseInt(e.Arg, 10, 64); err != nil || count <= 0 {
+			return errors.New("invalid argument for " + e.CmdName + ": " + e.Arg)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	index, size := int64(m.tabIndex), int64(len(m.tabs))
+	switch {
+	case backward:
+		index = ((index-max(count, 1))%size + size) % size
+	case count > 0:
+		if count > size {
+			return fmt.Errorf("tab page %d does not exist", count)
+		}
+		index = count - 1
+	default:
+		index = (index + 1) % size
+	}
+	m.saveTab()
+	m.loadTab(int(index))
+	return nil
+}
+
+func (m *Manager) tabclose(e event.Event) error {
+	if e.Arg != "" {
+		return errors.New("too many arguments for " + e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.tabs) == 1 {
+		return errors.New("cannot close last tab page")
+	}
+	m.closeTab()
+	return nil
+}
+
+// closeTab closes the current tab page. The windows are kept to be reopened.
+func (m *Manager) closeTab() {
+	m.tabs = slices.Delete(m.tabs, m.tabIndex, m.tabIndex+1)
+	m.loadTab(min(m.tabIndex, len(m.tabs)-1))
+}
+
+// tabList lists the windows in the tab pages. The active window is marked
+// with >, and the window with unsaved changes is marked with +.
+func (m *Manager) tabList(e event.Event) (string, error) {
+	if e.Arg != "" {
+		return "", errors.New("too many arguments for " + e.CmdName)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saveTab()
+	var sb strings.Builder
+	for i, t := range m.tabs {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		fmt.Fprintf(&sb, "Tab page %d", i+1)
+		for _, l := range windowsInOrder(t.layout) {
+			mark, modified := ' ', ' '
+			if i == m.tabIndex && l.Index == m.windowIndex {
+				mark = '>'
+			}
+			if window := m.windows[l.Index]; window.changedTick != window.savedChangedTick {
+				modified = '+'
+			}
+			fmt.Fprintf(&sb, "\n%c%c  %s", mark, modified, m.windows[l.Index].getName())
+		}
+	}
+	return sb.String(), nil
+}
+
+// Tabs returns the states of the tab pages to draw the tabline.
+func (m *Manager) Tabs() []state.TabState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saveTab()
+	tabs := make([]state.TabState, len(m.tabs))
+	for i, t := range m.tabs {
+		windows := windowsInOrder(t.layout)
+		window := m.windows[t.windowIndex]
+		tabs[i] = state.TabState{
+			Name:    window.getName(),
+			Windows: len(windows),
+			Active:  i == m.tabIndex,
+			Modified: slices.ContainsFunc(windows, func(l layout.Window) bool {
+				window := m.windows[l.Index]
+				return window.changedTick != window.savedChangedTick
+			}),
+		}
+	}
+	return tabs
+}
+
+// windowsInOrder returns the windows of the layout from the top left.
+func windowsInOrder(l layout.Layout) []layout.Window {
+	var windows []layout.Window
+	for _, l := range l.Collect() {
+		windows = append(windows, l)
+	}
+	slices.SortFunc(windows, func(x, y layout.Window) int {
+		return cmp.Or(cmp.Compare(x.TopMargin(), y.TopMargin()),
+			cmp.Compare(x.LeftMargin(), y.LeftMargin()))
+	})
+	return windows
+}
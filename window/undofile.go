@@ -0,0 +1,104 @@
+package window
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/itchyny/bed/history"
+)
+
+type undoFileHeader struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modtime"`
+	Hash    string    `json:"hash"`
+}
+
+// undoFilePath returns the path of the undo file for the file path.
+func undoFilePath(path string) (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(homeDir, ".local", "state")
+	}
+	name := strings.Map(func(r rune) rune {
+		if r == filepath.Separator || r == '/' || r == ':' {
+			return '%'
+		}
+		return r
+	}, path)
+	return filepath.Join(dir, "bed", "undo", name), nil
+}
+
+// saveUndoFile saves the history to the undo file.
+// The hash should be the checksum of the written file.
+func (w *window) saveUndoFile(hash []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return err
+	}
+	path, err := undoFilePath(w.path)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = json.NewEncoder(&buf).Encode(undoFileHeader{
+		fi.Size(), fi.ModTime(), hex.EncodeToString(hash),
+	}); err != nil {
+		return err
+	}
+	if err = w.history.Save(&buf, w.buffer); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+// loadUndoFile restores the history from the undo file. The undo file is
+// ignored if the file has been changed after the undo file was saved.
+func (w *window) loadUndoFile(r readAtSeeker) {
+	path, err := undoFilePath(w.path)
+	if err != nil {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	var header undoFileHeader
+	dec := json.NewDecoder(f)
+	if err = dec.Decode(&header); err != nil {
+		return
+	}
+	fi, err := os.Stat(w.path)
+	if err != nil || fi.Size() != header.Size || !fi.ModTime().Equal(header.ModTime) {
+		return
+	}
+	h := sha256.New()
+	if _, err = io.Copy(h, io.NewSectionReader(r, 0, fi.Size())); err != nil ||
+		hex.EncodeToString(h.Sum(nil)) != header.Hash {
+		return
+	}
+	history, err := history.Load(io.MultiReader(dec.Buffered(), f), r)
+	if err != nil {
+		return
+	}
+	buffer, offset, cursor, tick := history.Current()
+	w.history, w.buffer, w.offset, w.cursor = history, buffer, offset, cursor
+	w.changedTick, w.savedChangedTick, w.maxChangedTick = tick, tick, history.MaxTick()
+	w.length, _ = w.buffer.Len()
+}
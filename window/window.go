@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 	"unicode"
-	"unicode/utf8"
 
 	"github.com/itchyny/bed/buffer"
+	"github.com/itchyny/bed/charset"
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/history"
 	"github.com/itchyny/bed/mode"
@@ -24,29 +26,41 @@ type window struct {
 	prevChanged      bool
 	maxChangedTick   uint64
 	savedChangedTick uint64
-	history          *history.History
-	searcher         *searcher.Searcher
-	searchTick       uint64
-	path             string
-	name             string
-	height           int64
-	width            int64
-	offset           int64
-	cursor           int64
-	length           int64
-	stack            []position
-	append           bool
-	replaceByte      bool
-	extending        bool
-	pending          bool
-	pendingByte      byte
-	visualStart      int64
-	focusText        bool
-	buf              []byte
-	buf1             [1]byte
-	redrawCh         chan<- struct{}
-	eventCh          chan<- event.Event
-	mu               *sync.Mutex
+	readonly         bool
+	modifiable       bool
+	follow           bool
+	windowOptions
+	mark        int64
+	followStop  chan struct{}
+	stream      *spoolReader
+	codec       *codec
+	process     *processReader
+	closeCh     chan struct{}
+	history     *history.History
+	searcher    *searcher.Searcher
+	searchTick  uint64
+	matchStart  int64
+	matchEnd    int64
+	path        string
+	name        string
+	height      int64
+	width       int64
+	offset      int64
+	cursor      int64
+	length      int64
+	stack       []position
+	append      bool
+	replaceByte bool
+	extending   bool
+	pending     bool
+	pendingByte byte
+	visualStart int64
+	focusText   bool
+	buf         []byte
+	buf1        [1]byte
+	redrawCh    chan<- struct{}
+	eventCh     chan<- event.Event
+	mu          *sync.Mutex
 }
 
 type position struct {
@@ -59,6 +73,11 @@ type readAtSeeker interface {
 	io.Seeker
 }
 
+type readAtSeekCloser interface {
+	readAtSeeker
+	io.Closer
+}
+
 func newWindow(
 	r readAtSeeker, path, name string,
 	eventCh chan<- event.Event, redrawCh chan<- struct{},
@@ -70,18 +89,27 @@ func newWindow(
 	}
 	history := history.NewHistory()
 	history.Push(buffer, 0, 0, 0)
-	return &window{
-		buffer:      buffer,
-		history:     history,
-		searcher:    searcher.NewSearcher(r),
-		path:        path,
-		name:        name,
-		length:      length,
-		visualStart: -1,
-		redrawCh:    redrawCh,
-		eventCh:     eventCh,
-		mu:          new(sync.Mutex),
-	}, nil
+	process := processOf(r)
+	w := &window{
+		buffer:        buffer,
+		history:       history,
+		searcher:      newSearcher(r, process),
+		path:          path,
+		name:          name,
+		length:        length,
+		modifiable:    true,
+		windowOptions: defaultWindowOptions(),
+		process:       process,
+		visualStart:   -1,
+		closeCh:       make(chan struct{}),
+		redrawCh:      redrawCh,
+		eventCh:       eventCh,
+		mu:            new(sync.Mutex),
+	}
+	if s, ok := r.(*spoolReader); ok {
+		w.readStream(s)
+	}
+	return w, nil
 }
 
 func (w *window) setSize(width, height int) {
@@ -101,6 +129,11 @@ func (w *window) setSize(width, height int) {
 func (w *window) emit(e event.Event) {
 	var newEvent event.Event
 	w.mu.Lock()
+	if err := w.checkEditable(e); err != nil {
+		w.mu.Unlock()
+		w.eventCh <- event.Event{Type: event.Error, Error: err}
+		return
+	}
 	offset, cursor, changedTick := w.offset, w.cursor, w.changedTick
 	switch e.Type {
 	case event.CursorUp:
@@ -121,6 +154,10 @@ func (w *window) emit(e event.Event) {
 		w.cursorEnd(e.Count)
 	case event.CursorGoto:
 		w.cursorGoto(e)
+	case event.SetMark:
+		if err := w.setMark(e.Range); err != nil {
+			newEvent = event.Event{Type: event.Error, Error: err}
+		}
 	case event.ScrollUp:
 		w.scrollUp(e.Count)
 	case event.ScrollDown:
@@ -161,9 +198,11 @@ func (w *window) emit(e event.Event) {
 		w.jumpBack()
 
 	case event.DeleteByte:
-		newEvent = event.Event{Type: event.Copied, Buffer: w.deleteBytes(e.Count), Arg: "deleted"}
+		newEvent = event.Event{Type: event.Copied, Buffer: w.deleteBytes(e.Count),
+			Register: e.Register, Arg: "deleted"}
 	case event.DeletePrevByte:
-		newEvent = event.Event{Type: event.Copied, Buffer: w.deletePrevBytes(e.Count), Arg: "deleted"}
+		newEvent = event.Event{Type: event.Copied, Buffer: w.deletePrevBytes(e.Count),
+			Register: e.Register, Arg: "deleted"}
 	case event.Increment:
 		w.increment(e.Count)
 	case event.Decrement:
@@ -196,7 +235,9 @@ func (w *window) emit(e event.Event) {
 	case event.ExitInsert:
 		w.exitInsert()
 	case event.Rune:
-		if w.insertRune(e.Mode, e.Rune) {
+		if exitInsert, err := w.insertRune(e.Mode, e.Rune); err != nil {
+			newEvent = event.Event{Type: event.Error, Error: err}
+		} else if exitInsert {
 			newEvent = event.Event{Type: event.ExitInsert}
 		}
 	case event.Backspace:
@@ -215,6 +256,10 @@ func (w *window) emit(e event.Event) {
 			w.pending = false
 			w.pendingByte = '\x00'
 		}
+	case event.MousePress:
+		w.mousePress(e.Mouse)
+	case event.MouseDrag:
+		w.mouseDrag(e.Mouse)
 	case event.Undo:
 		if e.Mode != mode.Normal {
 			panic("event.Undo should be emitted under normal mode")
@@ -225,10 +270,24 @@ func (w *window) emit(e event.Event) {
 			panic("event.Undo should be emitted under normal mode")
 		}
 		w.redo(e.Count)
+	case event.Earlier, event.Later:
+		if err := w.undoTime(e); err != nil {
+			newEvent = event.Event{Type: event.Error, Error: err}
+		}
+	case event.UndoList:
+		newEvent = event.Event{Type: event.Info, Error: errors.New(w.undoList())}
+	case event.Maps:
+		if info, err := w.maps(e.Arg); err != nil {
+			newEvent = event.Event{Type: event.Error, Error: err}
+		} else if info != "" {
+			newEvent = event.Event{Type: event.Info, Error: errors.New(info)}
+		}
 	case event.Copy:
-		newEvent = event.Event{Type: event.Copied, Buffer: w.copy(), Arg: "yanked"}
+		newEvent = event.Event{Type: event.Copied, Buffer: w.copy(),
+			Register: e.Register, Arg: "yanked"}
 	case event.Cut:
-		newEvent = event.Event{Type: event.Copied, Buffer: w.cut(), Arg: "deleted"}
+		newEvent = event.Event{Type: event.Copied, Buffer: w.cut(),
+			Register: e.Register, Arg: "deleted"}
 	case event.Paste, event.PastePrev:
 		newEvent = event.Event{Type: event.Pasted, Count: w.paste(e)}
 	case event.ExecuteSearch:
@@ -244,7 +303,7 @@ func (w *window) emit(e event.Event) {
 		return
 	}
 	changed := changedTick != w.changedTick
-	if e.Type != event.Undo && e.Type != event.Redo {
+	if e.Type < event.Undo || event.UndoList < e.Type {
 		if (e.Mode == mode.Normal || e.Mode == mode.Visual) && changed || e.Type == event.ExitInsert && w.prevChanged {
 			w.history.Push(w.buffer, w.offset, w.cursor, w.changedTick)
 		} else if e.Mode != mode.Normal && e.Mode != mode.Visual && w.prevChanged && !changed &&
@@ -261,6 +320,39 @@ func (w *window) emit(e event.Event) {
 	}
 }
 
+// checkEditable returns an error if the event edits the buffer
+// in readonly mode, or changes the buffer when not modifiable.
+func (w *window) checkEditable(e event.Event) error {
+	switch e.Type {
+	case event.DeleteByte, event.DeletePrevByte, event.Increment, event.Decrement,
+		event.ShiftLeft, event.ShiftRight, event.Backspace, event.Delete,
+		event.Cut, event.Paste, event.PastePrev:
+	case event.Rune:
+		if e.Mode != mode.Insert && e.Mode != mode.Replace {
+			return nil
+		}
+	case event.Undo, event.Redo, event.Earlier, event.Later:
+		if w.modifiable {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if !w.modifiable {
+		return errors.New("cannot make changes, modifiable is off")
+	}
+	if w.readonly {
+		return errors.New("cannot make changes, readonly option is set")
+	}
+	return nil
+}
+
+func (w *window) setReadonly(readonly bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.readonly = readonly
+}
+
 func (w *window) readByte(offset int64) (byte, error) {
 	n, err := w.buffer.ReadAt(w.buf1[:], offset)
 	if err != nil && err != io.EOF {
@@ -299,10 +391,10 @@ func (w *window) writeTo(r *event.Range, dst io.Writer) (int64, error) {
 		from, to = 0, w.length-1
 	} else {
 		var err error
-		if from, err = w.positionToOffset(r.From); err != nil {
+		if from, err = w.positionToOffset(w.fromAddress(r.From)); err != nil {
 			return 0, err
 		}
-		if to, err = w.positionToOffset(r.To); err != nil {
+		if to, err = w.positionToOffset(w.fromAddress(r.To)); err != nil {
 			return 0, err
 		}
 		if from > to {
@@ -312,6 +404,50 @@ func (w *window) writeTo(r *event.Range, dst io.Writer) (int64, error) {
 	return io.Copy(dst, io.NewSectionReader(w.buffer, from, to-from+1))
 }
 
+// writeChangedTo writes the regions changed from the original reader to the
+// destination at the same offsets. The changed regions include the original
+// bytes moved by the insertions and the deletions. The original bytes of each
+// region are saved before overwriting, so that the moved bytes are still read.
+func (w *window) writeChangedTo(dst io.WriterAt, r *originalReader) (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var n int64
+	eis, bs := w.buffer.ChangedIndices(r), make([]byte, 1<<20)
+	for i := 0; i < len(eis); i += 2 {
+		for offset, end := eis[i], min(eis[i+1], w.length); offset < end; {
+			p := bs[:min(end-offset, int64(len(bs)))]
+			if err := r.save(offset, int64(len(p))); err != nil {
+				return n, err
+			}
+			if _, err := w.buffer.ReadAt(p, offset); err != nil && err != io.EOF {
+				return n, err
+			}
+			k, err := dst.WriteAt(p, offset)
+			if n, offset = n+int64(k), offset+int64(k); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// addressDelta returns the difference of the address displayed in the offset
+// column from the offset, by the baseaddress option or the mark.
+func (w *window) addressDelta() int64 {
+	if w.relativeOffset {
+		return -w.mark
+	}
+	return w.baseAddress
+}
+
+// fromAddress converts the absolute position of the address to the offset.
+func (w *window) fromAddress(pos event.Position) event.Position {
+	if p, ok := pos.(event.Absolute); ok {
+		return event.Absolute{Offset: p.Offset - w.addressDelta()}
+	}
+	return pos
+}
+
 func (w *window) positionToOffset(pos event.Position) (int64, error) {
 	var offset int64
 	switch pos := pos.(type) {
@@ -346,9 +482,41 @@ func (w *window) state(width, height int) (*state.WindowState, error) {
 	if err != nil {
 		return nil, err
 	}
+	var codec string
+	if w.codec != nil {
+		codec = w.codec.name
+	}
+	var matchStart, matchEnd int64
+	if w.searchTick == w.changedTick {
+		matchStart, matchEnd = w.matchStart, w.matchEnd
+	}
+	var readSize int64
+	if w.stream != nil {
+		readSize = w.stream.readSize()
+	}
+	var unmappedIndices []int64
+	if w.process != nil {
+		unmappedIndices = w.process.unmapped(w.offset, w.offset+int64(n))
+	}
+	var fileType string
+	var cursorBytes []byte
+	if w.statusLine != "" {
+		// The items of the status line read the head of the file, and the
+		// bytes at the cursor which may be out of the screen.
+		head := make([]byte, fileTypeHeadSize)
+		m, _ := w.buffer.ReadAt(head, 0)
+		fileType = detectFileType(head[:m])
+		cursorBytes = make([]byte, 8)
+		m, _ = w.buffer.ReadAt(cursorBytes, w.cursor)
+		cursorBytes = cursorBytes[:m]
+	}
 	return &state.WindowState{
 		Name:          w.name,
 		Modified:      w.changedTick != w.savedChangedTick,
+		Readonly:      w.readonly,
+		Reading:       w.stream != nil,
+		ReadSize:      readSize,
+		Codec:         codec,
 		Width:         int(w.width),
 		Offset:        w.offset,
 		Cursor:        w.cursor,
@@ -359,6 +527,15 @@ func (w *window) state(width, height int) (*state.WindowState, error) {
 		PendingByte:   w.pendingByte,
 		VisualStart:   w.visualStart,
 		EditedIndices: w.buffer.EditedIndices(),
+		Unmapped:      unmappedIndices,
+		MatchStart:    matchStart,
+		MatchEnd:      matchEnd,
+		TextEncoding:  w.textEncoding,
+		OffsetBase:    w.offsetBase,
+		AddressDelta:  w.addressDelta(),
+		StatusLine:    w.statusLine,
+		FileType:      fileType,
+		CursorBytes:   cursorBytes,
 		FocusText:     w.focusText,
 	}, nil
 }
@@ -415,6 +592,69 @@ func (w *window) redo(count int64) {
 	}
 }
 
+func (w *window) undoTime(e event.Event) error {
+	var buffer *buffer.Buffer
+	var offset, cursor int64
+	var tick uint64
+	if count, err := strconv.ParseInt(e.Arg, 10, 64); e.Arg == "" || err == nil && count > 0 {
+		count = max(count, e.Count, 1)
+		if e.Type == event.Earlier {
+			buffer, offset, cursor, tick = w.history.Earlier(count)
+		} else {
+			buffer, offset, cursor, tick = w.history.Later(count)
+		}
+	} else {
+		d, err := parseUndoDuration(e.Arg)
+		if err != nil {
+			return err
+		}
+		if e.Type == event.Earlier {
+			buffer, offset, cursor, tick = w.history.EarlierTime(d)
+		} else {
+			buffer, offset, cursor, tick = w.history.LaterTime(d)
+		}
+	}
+	if buffer != nil {
+		w.buffer, w.offset, w.cursor, w.changedTick = buffer, offset, cursor, tick
+		w.length, _ = w.buffer.Len()
+	}
+	return nil
+}
+
+func parseUndoDuration(arg string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(arg, "s"):
+		unit = time.Second
+	case strings.HasSuffix(arg, "m"):
+		unit = time.Minute
+	case strings.HasSuffix(arg, "h"):
+		unit = time.Hour
+	case strings.HasSuffix(arg, "d"):
+		unit = 24 * time.Hour
+	default:
+		return 0, errors.New("invalid argument: " + arg)
+	}
+	count, err := strconv.ParseInt(arg[:len(arg)-1], 10, 64)
+	if err != nil || count <= 0 {
+		return 0, errors.New("invalid argument: " + arg)
+	}
+	return time.Duration(count) * unit, nil
+}
+
+func (w *window) undoList() string {
+	leaves := w.history.Leaves()
+	if len(leaves) == 0 || leaves[0].Number == 0 {
+		return "Nothing to undo"
+	}
+	var sb strings.Builder
+	sb.WriteString("number changes  when")
+	for _, leaf := range leaves {
+		fmt.Fprintf(&sb, "\n%6d %7d  %s", leaf.Number, leaf.Changes, leaf.Time.Format(time.TimeOnly))
+	}
+	return sb.String()
+}
+
 func (w *window) cursorUp(count int64) {
 	w.cursor -= min(max(count, 1), w.cursor/w.width) * w.width
 	if w.append && w.extending && w.cursor < w.length-1 {
@@ -538,16 +778,36 @@ func (w *window) cursorGotoPos(pos event.Position, cmdName string) {
 		case event.VisualEnd:
 			pos = event.VisualEnd{Offset: p.Offset * w.length / 100}
 		}
+	default:
+		pos = w.fromAddress(pos)
 	}
 	if offset, err := w.positionToOffset(pos); err == nil {
-		w.cursor = offset
-		if w.cursor < w.offset {
-			w.offset = (max(w.cursor/w.width, w.height/2) - w.height/2) * w.width
-		} else if w.cursor >= w.offset+w.height*w.width {
-			h := (max(w.length, 1)+w.width-1)/w.width - w.height
-			w.offset = min((w.cursor-w.height*w.width+w.width)/w.width+w.height/2, h) * w.width
+		w.cursorGotoOffset(offset)
+	}
+}
+
+// setMark sets the mark at the cursor, or the address of the range,
+// which is the base of the offsets with the relativeoffset option.
+func (w *window) setMark(r *event.Range) error {
+	offset := w.cursor
+	if r != nil {
+		var err error
+		if offset, err = w.positionToOffset(w.fromAddress(r.From)); err != nil {
+			return err
 		}
 	}
+	w.mark = offset
+	return nil
+}
+
+func (w *window) cursorGotoOffset(offset int64) {
+	w.cursor = offset
+	if w.cursor < w.offset {
+		w.offset = (max(w.cursor/w.width, w.height/2) - w.height/2) * w.width
+	} else if w.cursor >= w.offset+w.height*w.width {
+		h := (max(w.length, 1)+w.width-1)/w.width - w.height
+		w.offset = min((w.cursor-w.height*w.width+w.width)/w.width+w.height/2, h) * w.width
+	}
 }
 
 func (w *window) scrollUp(count int64) {
@@ -882,14 +1142,16 @@ func (w *window) exitInsert() {
 	w.buffer.Flush()
 }
 
-func (w *window) insertRune(m mode.Mode, ch rune) (exitInsert bool) {
+func (w *window) insertRune(m mode.Mode, ch rune) (exitInsert bool, err error) {
 	if m == mode.Insert || m == mode.Replace {
 		if w.focusText {
-			var buf [4]byte
-			n := utf8.EncodeRune(buf[:], ch)
-			for i := range n {
-				exitInsert = exitInsert || w.insertByte(m, byte(buf[i]>>4))
-				exitInsert = exitInsert || w.insertByte(m, byte(buf[i]&0x0f))
+			bs, err := charset.Encode(w.textEncoding, ch)
+			if err != nil {
+				return false, err
+			}
+			for _, b := range bs {
+				exitInsert = exitInsert || w.insertByte(m, b>>4)
+				exitInsert = exitInsert || w.insertByte(m, b&0x0f)
 			}
 		} else if '0' <= ch && ch <= '9' {
 			exitInsert = w.insertByte(m, byte(ch-'0'))
@@ -983,6 +1245,21 @@ func (w *window) exitVisual() {
 	w.visualStart = -1
 }
 
+func (w *window) mousePress(m *event.Mouse) {
+	w.visualStart = -1
+	if m.Offset >= 0 {
+		w.focusText = m.FocusText
+		w.cursorGotoOffset(min(m.Offset, max(w.length-1, 0)))
+	}
+}
+
+func (w *window) mouseDrag(m *event.Mouse) {
+	if w.visualStart < 0 {
+		w.visualStart = w.cursor
+	}
+	w.cursorGotoOffset(min(max(m.Offset, 0), max(w.length-1, 0)))
+}
+
 func (w *window) copy() *buffer.Buffer {
 	if w.visualStart < 0 {
 		panic("window#copy should be called in visual mode")
@@ -1035,10 +1312,19 @@ func (w *window) paste(e event.Event) int64 {
 	return l * count
 }
 
+// newSearcher creates a searcher of the reader, which skips the unmapped
+// regions of the process memory.
+func newSearcher(r io.ReaderAt, process *processReader) *searcher.Searcher {
+	if process != nil {
+		r = mappedReader{r, process}
+	}
+	return searcher.NewSearcher(r)
+}
+
 func (w *window) search(str string, forward bool) {
 	if w.searchTick != w.changedTick {
 		w.searcher.Abort()
-		w.searcher = searcher.NewSearcher(w.buffer)
+		w.searcher = newSearcher(w.buffer, w.process)
 		w.searchTick = w.changedTick
 	}
 	ch := w.searcher.Search(w.cursor, str, forward)
@@ -1049,6 +1335,7 @@ func (w *window) search(str string, forward bool) {
 		case int64:
 			w.mu.Lock()
 			w.cursor = x
+			w.matchStart, w.matchEnd = x, x+int64(searcher.MatchLength(str))
 			w.mu.Unlock()
 			w.redrawCh <- struct{}{}
 		}
@@ -1058,6 +1345,9 @@ func (w *window) search(str string, forward bool) {
 func (w *window) abortSearch() {
 	if err := w.searcher.Abort(); err != nil {
 		w.eventCh <- event.Event{Type: event.Info, Error: err}
+	} else if w.stream != nil {
+		w.stream.abort()
+		w.eventCh <- event.Event{Type: event.Info, Error: errors.New("reading is aborted")}
 	}
 }
 
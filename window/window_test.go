@@ -2,13 +2,17 @@ package window
 
 import (
 	"bytes"
+	"io"
 	"math"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/itchyny/bed/buffer"
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/option"
 )
 
 func TestWindowState(t *testing.T) {
@@ -548,6 +552,80 @@ func TestWindowCursorMotions(t *testing.T) {
 	}
 }
 
+func TestWindowAddress(t *testing.T) {
+	r := strings.NewReader(strings.Repeat("Hello, world!", 100))
+	width, height := 16, 10
+	window, err := newWindow(r, "test", "test", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(width, height)
+	setOption := func(arg string) {
+		t.Helper()
+		opts, err := option.Parse(arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := window.setOption(opts[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkState := func(cursor, delta int64) {
+		t.Helper()
+		s, err := window.state(width, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Cursor != cursor {
+			t.Errorf("s.Cursor should be %d but got %d", cursor, s.Cursor)
+		}
+		if s.AddressDelta != delta {
+			t.Errorf("s.AddressDelta should be %d but got %d", delta, s.AddressDelta)
+		}
+	}
+
+	setOption("baseaddress=0x08000000")
+	window.cursorGotoPos(event.Absolute{Offset: 0x08000100}, "goto")
+	checkState(0x100, 0x08000000)
+	window.cursorGotoPos(event.Absolute{Offset: 10}, "go[to]")
+	checkState(160, 0x08000000)
+	window.cursorGotoPos(event.Absolute{Offset: 0x10}, "goto")
+	checkState(0, 0x08000000)
+	n, err := window.writeTo(&event.Range{
+		From: event.Absolute{Offset: 0x08000007},
+		To:   event.Absolute{Offset: 0x0800000b},
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("writeTo should write %d bytes but got %d", 5, n)
+	}
+
+	window.cursorGotoPos(event.Absolute{Offset: 0x08000200}, "goto")
+	if err := window.setMark(nil); err != nil {
+		t.Fatal(err)
+	}
+	setOption("relativeoffset")
+	checkState(0x200, -0x200)
+	window.cursorGotoPos(event.Absolute{Offset: 0x10}, "goto")
+	checkState(0x210, -0x200)
+	if err := window.setMark(&event.Range{From: event.Absolute{Offset: 0x20}}); err != nil {
+		t.Fatal(err)
+	}
+	checkState(0x210, -0x220)
+	setOption("norelativeoffset")
+	checkState(0x210, 0x08000000)
+
+	opts, err := option.Parse("baseaddress=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := window.setOption(opts[0]); err == nil {
+		t.Errorf("err should not be nil")
+	}
+}
+
 func TestWindowScreenMotions(t *testing.T) {
 	r := strings.NewReader(strings.Repeat("Hello, world!", 100))
 	width, height := 16, 10
@@ -1703,6 +1781,95 @@ func TestWindowEventRuneText(t *testing.T) {
 	<-redrawCh
 }
 
+func TestWindowEventRuneTextEncoding(t *testing.T) {
+	width, height := 16, 10
+	redrawCh := make(chan struct{})
+	eventCh := make(chan event.Event)
+	window, err := newWindow(strings.NewReader(""), "test", "test", eventCh, redrawCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(width, height)
+	opts, err := option.Parse("textencoding=utf-16le")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := window.setOption(opts[0]); err != nil {
+		t.Fatal(err)
+	}
+	opts, _ = option.Parse("textencoding=utf-32")
+	if _, err := window.setOption(opts[0]); err == nil {
+		t.Errorf("err should not be nil")
+	}
+
+	go func() {
+		window.emit(event.Event{Type: event.SwitchFocus})
+		window.emit(event.Event{Type: event.StartInsert})
+		for _, r := range "aあ" {
+			window.emit(event.Event{Type: event.Rune, Rune: r, Mode: mode.Insert})
+		}
+		opts, _ := option.Parse("textencoding=latin1")
+		_, _ = window.setOption(opts[0])
+		window.emit(event.Event{Type: event.Rune, Rune: 'あ', Mode: mode.Insert})
+		window.emit(event.Event{Type: event.Rune, Rune: 'é', Mode: mode.Insert})
+	}()
+	for range 4 {
+		<-redrawCh
+	}
+	if ev := <-eventCh; ev.Type != event.Error || ev.Error.Error() != "cannot encode U+3042 in latin1" {
+		t.Errorf("emit should send an error event but got: %+v", ev)
+	}
+	<-redrawCh
+	s, err := window.state(width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a\x00\x42\x30\xe9\x00"; !strings.HasPrefix(string(s.Bytes), expected) {
+		t.Errorf("s.Bytes should start with %q but got %q", expected, string(s.Bytes))
+	}
+	if expected := "latin1"; s.TextEncoding != expected {
+		t.Errorf("s.TextEncoding should be %q but got %q", expected, s.TextEncoding)
+	}
+}
+
+func TestWindowStatusLine(t *testing.T) {
+	width, height := 16, 10
+	window, err := newWindow(strings.NewReader("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), "test", "test", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(width, height)
+	window.cursor = 8
+	s, err := window.state(width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.StatusLine != "" || s.FileType != "" || s.CursorBytes != nil {
+		t.Errorf("status line items should not be read without the statusline option but got: %+v", s)
+	}
+
+	opts, _ := option.Parse(`statusline=%f\ %y%=%{u32be}`)
+	if _, err := window.setOption(opts[0]); err != nil {
+		t.Fatal(err)
+	}
+	opts, _ = option.Parse("statusline=%x")
+	if _, err := window.setOption(opts[0]); err == nil || err.Error() != "invalid item in statusline: %x" {
+		t.Errorf("err should be %q but got: %v", "invalid item in statusline: %x", err)
+	}
+	if s, err = window.state(width, height); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "%f %y%=%{u32be}"; s.StatusLine != expected {
+		t.Errorf("s.StatusLine should be %q but got %q", expected, s.StatusLine)
+	}
+	if expected := "png"; s.FileType != expected {
+		t.Errorf("s.FileType should be %q but got %q", expected, s.FileType)
+	}
+	if expected := "\x00\x00\x00\x0dIHDR"; string(s.CursorBytes) != expected {
+		t.Errorf("s.CursorBytes should be %q but got %q", expected, s.CursorBytes)
+	}
+}
+
 func TestWindowEventUndoRedo(t *testing.T) {
 	width, height := 16, 10
 	redrawCh, waitCh := make(chan struct{}), make(chan struct{})
@@ -1868,3 +2035,109 @@ func TestWindowWriteTo(t *testing.T) {
 		}
 	}
 }
+
+func TestWindowEventEarlierLater(t *testing.T) {
+	width, height := 16, 10
+	eventCh, redrawCh := make(chan event.Event, 16), make(chan struct{}, 16)
+	window, err := newWindow(strings.NewReader("Hello, world!"), "test", "test", eventCh, redrawCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(width, height)
+	window.emit(event.Event{Type: event.DeleteByte, Mode: mode.Normal})
+	window.emit(event.Event{Type: event.DeleteByte, Mode: mode.Normal})
+	window.emit(event.Event{Type: event.Undo, Mode: mode.Normal})
+	window.emit(event.Event{Type: event.DeleteByte, Mode: mode.Normal, Count: 3})
+	for range 3 {
+		<-eventCh
+	}
+
+	for _, testCase := range []struct {
+		event    event.Event
+		expected string
+	}{
+		{event.Event{Type: event.Undo}, "ello, world!"},
+		{event.Event{Type: event.Redo}, "o, world!"},
+		{event.Event{Type: event.Earlier}, "llo, world!"},
+		{event.Event{Type: event.Earlier, Count: 5}, "Hello, world!"},
+		{event.Event{Type: event.Later, Arg: "2"}, "llo, world!"},
+		{event.Event{Type: event.Later, Arg: "1m"}, "o, world!"},
+		{event.Event{Type: event.Earlier, Arg: "1h"}, "Hello, world!"},
+	} {
+		window.emit(testCase.event)
+		s, err := window.state(width, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(s.Bytes), testCase.expected+"\x00") {
+			t.Errorf("s.Bytes should start with %q but got %q", testCase.expected, string(s.Bytes))
+		}
+	}
+
+	window.emit(event.Event{Type: event.Earlier, Arg: "1x"})
+	if ev := <-eventCh; ev.Type != event.Error || ev.Error.Error() != "invalid argument: 1x" {
+		t.Errorf("event should be invalid argument error but got %v", ev)
+	}
+	window.emit(event.Event{Type: event.UndoList})
+	ev := <-eventCh
+	if expected := regexp.MustCompile(
+		`^number changes  when\n     2       2  \d\d:\d\d:\d\d\n     3       2  \d\d:\d\d:\d\d$`,
+	); ev.Type != event.Info || !expected.MatchString(ev.Error.Error()) {
+		t.Errorf("event should be undo list but got %v", ev)
+	}
+}
+
+func TestWindowEventReadonly(t *testing.T) {
+	width, height := 16, 10
+	redrawCh, eventCh := make(chan struct{}, 10), make(chan event.Event, 10)
+	window, err := newWindow(strings.NewReader("Hello, world!"), "test", "test", eventCh, redrawCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(width, height)
+	window.emit(event.Event{Type: event.Increment, Mode: mode.Normal})
+	<-redrawCh
+
+	window.setReadonly(true)
+	for _, e := range []event.Event{
+		{Type: event.DeleteByte, Mode: mode.Normal},
+		{Type: event.Increment, Mode: mode.Normal},
+		{Type: event.ShiftLeft, Mode: mode.Normal},
+		{Type: event.Rune, Rune: 'x', Mode: mode.Insert},
+		{Type: event.Paste, Buffer: buffer.NewBuffer(strings.NewReader("x")), Mode: mode.Normal},
+	} {
+		window.emit(e)
+		if ev := <-eventCh; ev.Type != event.Error ||
+			ev.Error.Error() != "cannot make changes, readonly option is set" {
+			t.Errorf("window should emit readonly error but got %+v", ev)
+		}
+	}
+	window.emit(event.Event{Type: event.Undo, Mode: mode.Normal})
+	<-redrawCh
+	window.emit(event.Event{Type: event.Redo, Mode: mode.Normal})
+	<-redrawCh
+
+	opts, err := option.Parse("nomodifiable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = window.setOption(opts[0]); err != nil {
+		t.Fatal(err)
+	}
+	window.emit(event.Event{Type: event.Undo, Mode: mode.Normal})
+	if ev := <-eventCh; ev.Type != event.Error ||
+		ev.Error.Error() != "cannot make changes, modifiable is off" {
+		t.Errorf("window should emit modifiable error but got %+v", ev)
+	}
+
+	s, err := window.state(width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "Iello, world!\x00"; !strings.HasPrefix(string(s.Bytes), expected) {
+		t.Errorf("s.Bytes should start with %q but got %q", expected, string(s.Bytes))
+	}
+	if !s.Readonly {
+		t.Errorf("s.Readonly should be true")
+	}
+}